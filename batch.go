@@ -0,0 +1,160 @@
+package cloudcms
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+const defaultBatchConcurrency = 4
+
+type BatchOp string
+
+const (
+	BatchCreate    BatchOp = "create"
+	BatchUpdate    BatchOp = "update"
+	BatchPatch     BatchOp = "patch"
+	BatchDelete    BatchOp = "delete"
+	BatchAssociate BatchOp = "associate"
+)
+
+// BatchItem is one operation of a batch.
+//
+//   - BatchCreate creates Object, with the CreateNode Options.
+//   - BatchUpdate replaces the node with Object, whose _doc is set from NodeId if missing.
+//   - BatchPatch applies the patch document Object to NodeId.
+//   - BatchDelete deletes NodeId.
+//   - BatchAssociate associates NodeId with OtherNodeId, with AssociationType (default
+//     a:linked), Direction and the association properties in Object.
+type BatchItem struct {
+	Op              BatchOp
+	NodeId          string
+	Object          JsonObject
+	Options         map[string]string
+	OtherNodeId     string
+	AssociationType string
+	Direction       string
+}
+
+// BatchResult is the outcome of the BatchItem at Index. NodeId is the node created, updated,
+// patched or deleted, and Result the response, if any.
+type BatchResult struct {
+	Index  int
+	Item   BatchItem
+	NodeId string
+	Result JsonObject
+	Err    error
+}
+
+// BatchError is returned by ExecuteBatch when some of the items failed.
+type BatchError struct {
+	Failed []BatchResult
+	Total  int
+}
+
+func (e *BatchError) Error() string {
+	messages := []string{}
+	for i, res := range e.Failed {
+		if i == 3 {
+			messages = append(messages, fmt.Sprintf("and %d more", len(e.Failed)-i))
+			break
+		}
+		messages = append(messages, fmt.Sprintf("item %d (%s): %v", res.Index, res.Item.Op, res.Err))
+	}
+
+	return fmt.Sprintf("%d of %d batch operations failed: %s", len(e.Failed), e.Total, strings.Join(messages, "; "))
+}
+
+// ExecuteBatch runs the items with at most concurrency requests at a time (4 if not
+// positive). Every item is run even if others fail. The results are in item order; if any
+// item failed, a *BatchError listing the failures is returned along with them.
+func (session *CloudCmsSession) ExecuteBatch(repositoryId string, branchId string, items []BatchItem, concurrency int) ([]BatchResult, error) {
+	session = session.named("ExecuteBatch")
+	if concurrency <= 0 {
+		concurrency = defaultBatchConcurrency
+	}
+
+	results := make([]BatchResult, len(items))
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, concurrency)
+	for i, item := range items {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, item BatchItem) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			results[i] = session.executeBatchItem(repositoryId, branchId, item)
+			results[i].Index = i
+		}(i, item)
+	}
+	wg.Wait()
+
+	failed := []BatchResult{}
+	for _, res := range results {
+		if res.Err != nil {
+			failed = append(failed, res)
+		}
+	}
+	if len(failed) > 0 {
+		return results, &BatchError{Failed: failed, Total: len(items)}
+	}
+
+	return results, nil
+}
+
+func (session *CloudCmsSession) executeBatchItem(repositoryId string, branchId string, item BatchItem) BatchResult {
+	res := BatchResult{Item: item, NodeId: item.NodeId}
+
+	switch item.Op {
+	case BatchCreate:
+		res.NodeId, res.Err = session.CreateNode(repositoryId, branchId, item.Object, item.Options)
+
+	case BatchUpdate:
+		// the item is copied so that it is not changed when the id is filled in
+		obj := JsonObject{}
+		for key, val := range item.Object {
+			obj[key] = val
+		}
+		if obj["_doc"] == nil && item.NodeId != "" {
+			obj["_doc"] = item.NodeId
+		}
+		res.NodeId = obj.GetString("_doc")
+		res.Result, res.Err = session.UpdateNode(repositoryId, branchId, obj)
+
+	case BatchPatch, BatchDelete, BatchAssociate:
+		if item.NodeId == "" {
+			res.Err = fmt.Errorf("missing node id")
+		} else if item.Op == BatchPatch {
+			res.Result, res.Err = session.PatchNode(repositoryId, branchId, item.NodeId, item.Object)
+		} else if item.Op == BatchDelete {
+			res.Err = session.DeleteNode(repositoryId, branchId, item.NodeId)
+		} else {
+			res.Result, res.Err = session.batchAssociate(repositoryId, branchId, item)
+		}
+
+	default:
+		res.Err = fmt.Errorf("unknown batch operation %q", item.Op)
+	}
+
+	return res
+}
+
+func (session *CloudCmsSession) batchAssociate(repositoryId string, branchId string, item BatchItem) (JsonObject, error) {
+	if item.OtherNodeId == "" {
+		return nil, fmt.Errorf("missing other node id")
+	}
+
+	associationType := item.AssociationType
+	if associationType == "" {
+		associationType = "a:linked"
+	}
+
+	obj := item.Object
+	if obj == nil {
+		obj = JsonObject{}
+	}
+
+	return session.Associate(repositoryId, branchId, item.NodeId, item.OtherNodeId, associationType, item.Direction, obj)
+}
@@ -0,0 +1,142 @@
+package cloudcms
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestExecuteBatch(t *testing.T) {
+	var mu sync.Mutex
+	active, maxActive := 0, 0
+	requests := map[string]bool{}
+
+	session := newTestSession(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		active++
+		if active > maxActive {
+			maxActive = active
+		}
+		requests[r.Method+" "+strings.TrimPrefix(r.URL.Path, "/repositories/r/branches/b/nodes")] = true
+		mu.Unlock()
+
+		time.Sleep(10 * time.Millisecond)
+
+		mu.Lock()
+		active--
+		mu.Unlock()
+
+		if strings.HasSuffix(r.URL.Path, "/missing") {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		if r.URL.Path == "/repositories/r/branches/b/nodes/n1/associate" && r.URL.Query().Get("type") != "a:linked" {
+			t.Errorf("expected the default association type, got %s", r.URL.Query().Get("type"))
+		}
+
+		json.NewEncoder(w).Encode(JsonObject{"_doc": "created"})
+	}))
+
+	items := []BatchItem{
+		{Op: BatchCreate, Object: JsonObject{"title": "a"}},
+		{Op: BatchUpdate, NodeId: "n1", Object: JsonObject{"title": "b"}},
+		{Op: BatchPatch, NodeId: "n2", Object: JsonObject{"op": "replace", "path": "/title", "value": "c"}},
+		{Op: BatchDelete, NodeId: "missing"},
+		{Op: BatchAssociate, NodeId: "n1", OtherNodeId: "n2"},
+		{Op: BatchDelete},
+		{Op: BatchOp("copy"), NodeId: "n1"},
+	}
+
+	results, err := session.ExecuteBatch("r", "b", items, 2)
+
+	var batchErr *BatchError
+	if !errors.As(err, &batchErr) {
+		t.Fatalf("expected a batch error, got %v", err)
+	}
+	if len(batchErr.Failed) != 3 || batchErr.Total != 7 {
+		t.Fatalf("unexpected batch error: %v", err)
+	}
+	if batchErr.Failed[0].Index != 3 || !strings.HasPrefix(batchErr.Failed[0].Err.Error(), "404") {
+		t.Fatalf("unexpected first failure: %+v", batchErr.Failed[0])
+	}
+	if !strings.Contains(err.Error(), "unknown batch operation") {
+		t.Fatalf("unexpected message: %v", err)
+	}
+
+	if len(results) != len(items) || results[0].NodeId != "created" || results[1].NodeId != "n1" || results[2].Err != nil {
+		t.Fatalf("unexpected results: %+v", results)
+	}
+	if items[1].Object["_doc"] != nil {
+		t.Fatal("update should not change the item")
+	}
+
+	for _, request := range []string{"POST ", "PUT /n1", "PATCH /n2", "DELETE /missing", "POST /n1/associate"} {
+		if !requests[request] {
+			t.Errorf("missing request %s", request)
+		}
+	}
+	if maxActive > 2 {
+		t.Fatalf("expected at most 2 concurrent requests, got %d", maxActive)
+	}
+}
+
+func TestDeleteNodesResponse(t *testing.T) {
+	session := newTestSession(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"_docs": ["a", "b"]}`))
+	}))
+
+	ids, err := session.DeleteNodes("r", "b", []string{"a", "b"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(ids, ",") != "a,b" {
+		t.Fatalf("unexpected ids %v", ids)
+	}
+}
+
+func TestBatch(t *testing.T) {
+	session, repository := setupTestRepository(t)
+
+	repositoryId := ExtractId(&repository)
+	branchId := "master"
+	defer session.DeleteRepository(repositoryId)
+
+	items := []BatchItem{}
+	for _, title := range []string{"one", "two", "three"} {
+		items = append(items, BatchItem{Op: BatchCreate, Object: JsonObject{"title": title}})
+	}
+
+	results, err := session.ExecuteBatch(repositoryId, branchId, items, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	items = []BatchItem{
+		{Op: BatchUpdate, NodeId: results[0].NodeId, Object: JsonObject{"title": "one updated"}},
+		{Op: BatchAssociate, NodeId: results[0].NodeId, OtherNodeId: results[1].NodeId},
+	}
+	_, err = session.ExecuteBatch(repositoryId, branchId, items, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	node, err := session.ReadNode(repositoryId, branchId, results[0].NodeId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if node.GetString("title") != "one updated" {
+		t.Fatalf("node not updated: %v", node)
+	}
+
+	deleted, err := session.DeleteNodes(repositoryId, branchId, []string{results[1].NodeId, results[2].NodeId})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(deleted) != 2 {
+		t.Fatalf("expected 2 deleted nodes, got %v", deleted)
+	}
+}
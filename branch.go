@@ -6,6 +6,7 @@ import (
 )
 
 func (session *CloudCmsSession) QueryBranches(repositoryId string, query JsonObject, pagination JsonObject) (*ResultMap, error) {
+	session = session.named("QueryBranches")
 	res, err := session.Post(fmt.Sprintf("/repositories/%s/branches/query", repositoryId), ToParams(pagination), MapToReader(query))
 	if err != nil {
 		return nil, err
@@ -15,10 +16,12 @@ func (session *CloudCmsSession) QueryBranches(repositoryId string, query JsonObj
 }
 
 func (session *CloudCmsSession) ReadBranch(repositoryId string, branchId string) (JsonObject, error) {
+	session = session.named("ReadBranch")
 	return session.Get(fmt.Sprintf("/repositories/%s/branches/%s", repositoryId, branchId), nil)
 }
 
 func (session *CloudCmsSession) CreateBranch(repositoryId string, parentBranchId string, changesetId string, obj JsonObject) (JsonObject, error) {
+	session = session.named("CreateBranch")
 	params := url.Values{}
 	if changesetId != "" {
 		params.Add("changeset", changesetId)
@@ -31,6 +34,7 @@ func (session *CloudCmsSession) CreateBranch(repositoryId string, parentBranchId
 }
 
 func (session *CloudCmsSession) ListBranches(repositoryId string, pagination JsonObject) (*ResultMap, error) {
+	session = session.named("ListBranches")
 	params := ToParams(pagination)
 	params.Add("full", "true")
 	res, err := session.Get(fmt.Sprintf("/repositories/%s/branches", repositoryId), params)
@@ -42,11 +46,13 @@ func (session *CloudCmsSession) ListBranches(repositoryId string, pagination Jso
 }
 
 func (session *CloudCmsSession) DeleteBranch(repositoryId string, branchId string) error {
+	session = session.named("DeleteBranch")
 	_, err := session.Delete(fmt.Sprintf("/repositories/%s/branches/%s", repositoryId, branchId), nil)
 	return err
 }
 
 func (session *CloudCmsSession) UpdateBranch(repositoryId string, branchObj JsonObject) (JsonObject, error) {
+	session = session.named("UpdateBranch")
 	doc := branchObj["_doc"]
 
 	// Ensure branch id is a string
@@ -61,6 +67,7 @@ func (session *CloudCmsSession) UpdateBranch(repositoryId string, branchObj Json
 }
 
 func (session *CloudCmsSession) StartResetBranch(repositoryId string, branchId string, changesetId string) (string, error) {
+	session = session.named("StartResetBranch")
 	params := url.Values{"id": []string{changesetId}}
 	res, err := session.Post(fmt.Sprintf("/repositories/%s/branches/%s/reset/start", repositoryId, branchId), params, nil)
 	if err != nil {
@@ -71,7 +78,12 @@ func (session *CloudCmsSession) StartResetBranch(repositoryId string, branchId s
 }
 
 func (session *CloudCmsSession) StartChangesetHistory(repositoryId string, branchId string, config JsonObject) (string, error) {
-	res, err := session.Post(fmt.Sprintf("/repositories/%s/branches/%s/history/start", repositoryId, branchId), ToParams(config), nil)
+	session = session.named("StartChangesetHistory")
+	return session.startChangesetHistory(repositoryId, branchId, ToParams(config))
+}
+
+func (session *CloudCmsSession) startChangesetHistory(repositoryId string, branchId string, params url.Values) (string, error) {
+	res, err := session.Post(fmt.Sprintf("/repositories/%s/branches/%s/history/start", repositoryId, branchId), params, nil)
 	if err != nil {
 		return "", err
 	}
@@ -0,0 +1,337 @@
+package bulk
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	cloudcms "github.com/gitana/cloudcms-go-driver"
+	"github.com/gitana/cloudcms-go-driver/internal/attachment"
+)
+
+const defaultPageSize = 100
+
+// defaultSort is a stable order, so the pages neither overlap nor miss nodes
+var defaultSort = json.RawMessage(`{"_doc":1}`)
+
+// ExportSession is the subset of *cloudcms.CloudCmsSession used by the exporter.
+type ExportSession interface {
+	QueryNodes(repositoryId string, branchId string, query cloudcms.JsonObject, pagination cloudcms.JsonObject) (*cloudcms.ResultMap, error)
+	FindNodes(repositoryId string, branchId string, config cloudcms.JsonObject, pagination cloudcms.JsonObject) (*cloudcms.ResultMap, error)
+	ListAttachments(repositoryId string, branchId string, nodeId string) (*cloudcms.ResultMap, error)
+	DownloadAttachment(repositoryId string, branchId string, nodeId string, attachmentId string) (io.ReadCloser, error)
+}
+
+var _ ExportSession = (*cloudcms.CloudCmsSession)(nil)
+
+type Format string
+
+const (
+	JSONL Format = "jsonl"
+	CSV   Format = "csv"
+)
+
+type Exporter struct {
+	session      ExportSession
+	repositoryId string
+	branchId     string
+
+	// Query selects the nodes with QueryNodes. It is ignored when Find is set.
+	Query cloudcms.JsonObject
+
+	// Find selects the nodes with FindNodes, e.g. {"query": {...}, "search": "text"}.
+	Find cloudcms.JsonObject
+
+	// Sort orders the nodes, e.g. {"_system.created_on.ms": 1, "_doc": 1}. It is raw JSON so
+	// the fields keep their order. It should end with a unique field such as _doc, as pages
+	// read by a sort with ties may overlap. The default is {"_doc": 1}.
+	Sort json.RawMessage
+
+	// PageSize is the number of nodes read at a time. Only one page is held in memory.
+	PageSize int
+
+	// Fields are the dotted property paths to export. If empty, JSONL exports whole nodes
+	// and CSV exports the flattened properties of all the nodes, read in a first pass.
+	Fields []string
+
+	// AttachmentsDir, if set, receives the attachments of every node as
+	// <nodeId>/<attachmentId><ext>.
+	AttachmentsDir string
+
+	// Out receives progress messages.
+	Out io.Writer
+}
+
+func NewExporter(session ExportSession, repositoryId string, branchId string) *Exporter {
+	return &Exporter{
+		session:      session,
+		repositoryId: repositoryId,
+		branchId:     branchId,
+		Query:        cloudcms.JsonObject{},
+		PageSize:     defaultPageSize,
+		Out:          io.Discard,
+	}
+}
+
+func (e *Exporter) logf(format string, args ...interface{}) {
+	fmt.Fprintf(e.Out, format+"\n", args...)
+}
+
+// Export writes every matching node to w and returns the number of nodes written.
+func (e *Exporter) Export(w io.Writer, format Format) (int, error) {
+	pageSize := e.PageSize
+	if pageSize <= 0 {
+		pageSize = defaultPageSize
+	}
+
+	var writer rowWriter
+	switch format {
+	case JSONL:
+		writer = &jsonlWriter{encoder: json.NewEncoder(w), fields: e.Fields}
+	case CSV:
+		fields := e.Fields
+		if len(fields) == 0 {
+			var err error
+			fields, err = e.flattenedFields(pageSize)
+			if err != nil {
+				return 0, err
+			}
+		}
+		writer = &csvWriter{writer: csv.NewWriter(w), fields: fields}
+	default:
+		return 0, fmt.Errorf("unknown format %q", format)
+	}
+
+	err := writer.begin()
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for skip := 0; ; skip += pageSize {
+		rows, err := e.page(skip, pageSize)
+		if err != nil {
+			return count, err
+		}
+
+		for _, node := range rows {
+			err = writer.write(node)
+			if err == nil && e.AttachmentsDir != "" {
+				err = e.downloadAttachments(cloudcms.ExtractId(&node))
+			}
+			if err != nil {
+				return count, err
+			}
+			count++
+		}
+
+		err = writer.flush()
+		if err != nil {
+			return count, err
+		}
+		e.logf("exported %d nodes", count)
+
+		if len(rows) < pageSize {
+			return count, nil
+		}
+	}
+}
+
+// flattenedFields reads every matching node, one page at a time, and returns the dotted
+// paths of their values that are not objects, with _doc first
+func (e *Exporter) flattenedFields(pageSize int) ([]string, error) {
+	found := map[string]bool{}
+	for skip := 0; ; skip += pageSize {
+		rows, err := e.page(skip, pageSize)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, node := range rows {
+			flatten("", node, found)
+		}
+
+		if len(rows) < pageSize {
+			break
+		}
+	}
+	delete(found, "_doc")
+
+	fields := make([]string, 0, len(found))
+	for field := range found {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	return append([]string{"_doc"}, fields...), nil
+}
+
+func (e *Exporter) page(skip int, limit int) ([]cloudcms.JsonObject, error) {
+	pagination := cloudcms.JsonObject{"skip": skip, "limit": limit, "sort": defaultSort}
+	if len(e.Sort) > 0 {
+		pagination["sort"] = e.Sort
+	}
+
+	var res *cloudcms.ResultMap
+	var err error
+	if e.Find != nil {
+		res, err = e.session.FindNodes(e.repositoryId, e.branchId, e.Find, pagination)
+	} else {
+		res, err = e.session.QueryNodes(e.repositoryId, e.branchId, e.Query, pagination)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return res.Rows(), nil
+}
+
+func (e *Exporter) downloadAttachments(nodeId string) error {
+	res, err := e.session.ListAttachments(e.repositoryId, e.branchId, nodeId)
+	if err != nil {
+		return err
+	}
+
+	for _, row := range res.Rows() {
+		id := row.GetString("attachmentId")
+		if id == "" || strings.ContainsAny(id, "/\\") || id == "." || id == ".." {
+			return fmt.Errorf("node %s has invalid attachment id %q", nodeId, id)
+		}
+
+		target := filepath.Join(e.AttachmentsDir, nodeId, id+attachment.Ext(row))
+		err = attachment.Download(e.session, e.repositoryId, e.branchId, nodeId, id, target)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+type rowWriter interface {
+	begin() error
+	write(node cloudcms.JsonObject) error
+	flush() error
+}
+
+type jsonlWriter struct {
+	encoder *json.Encoder
+	fields  []string
+}
+
+func (w *jsonlWriter) begin() error {
+	return nil
+}
+
+func (w *jsonlWriter) write(node cloudcms.JsonObject) error {
+	if len(w.fields) == 0 {
+		return w.encoder.Encode(node)
+	}
+
+	obj := cloudcms.JsonObject{}
+	for _, field := range w.fields {
+		val, ok := getPath(node, field)
+		if ok {
+			setPath(obj, strings.Split(field, "."), val)
+		}
+	}
+
+	return w.encoder.Encode(obj)
+}
+
+func (w *jsonlWriter) flush() error {
+	return nil
+}
+
+type csvWriter struct {
+	writer *csv.Writer
+	fields []string
+}
+
+// begin writes the header
+func (w *csvWriter) begin() error {
+	return w.writer.Write(w.fields)
+}
+
+func (w *csvWriter) write(node cloudcms.JsonObject) error {
+	record := make([]string, len(w.fields))
+	for i, field := range w.fields {
+		val, _ := getPath(node, field)
+		record[i] = formatValue(val)
+	}
+
+	return w.writer.Write(record)
+}
+
+func (w *csvWriter) flush() error {
+	w.writer.Flush()
+	return w.writer.Error()
+}
+
+// getPath returns the value at a dotted property path
+func getPath(obj cloudcms.JsonObject, field string) (interface{}, bool) {
+	var val interface{} = map[string]interface{}(obj)
+	for _, key := range strings.Split(field, ".") {
+		m, ok := val.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		val, ok = m[key]
+		if !ok {
+			return nil, false
+		}
+	}
+
+	return val, true
+}
+
+func flatten(prefix string, obj map[string]interface{}, found map[string]bool) {
+	for key, val := range obj {
+		if m, ok := val.(map[string]interface{}); ok && len(m) > 0 {
+			flatten(prefix+key+".", m, found)
+			continue
+		}
+		found[prefix+key] = true
+	}
+}
+
+// formatValue writes lists of strings comma separated, which the "list" type of a Mapping
+// reads back, and other lists and objects as JSON
+func formatValue(val interface{}) string {
+	switch v := val.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case bool:
+		return strconv.FormatBool(v)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case []interface{}:
+		items := make([]string, len(v))
+		for i, item := range v {
+			s, ok := item.(string)
+			if !ok || strings.Contains(s, ",") {
+				return formatJson(v)
+			}
+			items[i] = s
+		}
+		return strings.Join(items, ",")
+	}
+
+	return formatJson(val)
+}
+
+func formatJson(val interface{}) string {
+	data, err := json.Marshal(val)
+	if err != nil {
+		return fmt.Sprint(val)
+	}
+
+	return string(data)
+}
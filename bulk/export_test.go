@@ -0,0 +1,129 @@
+package bulk
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	cloudcms "github.com/gitana/cloudcms-go-driver"
+	"github.com/gitana/cloudcms-go-driver/internal/fakesession"
+)
+
+func newExportSession() *fakesession.Session {
+	session := fakesession.New()
+	session.Nodes["a"] = cloudcms.JsonObject{"_doc": "a", "_type": "custom:book", "title": "Hello", "price": 9.5, "author": map[string]interface{}{"name": "Jane"}, "tags": []interface{}{"x", "y"}}
+	session.Nodes["b"] = cloudcms.JsonObject{"_doc": "b", "_type": "custom:book", "title": "World, again", "inStock": true}
+	session.Nodes["c"] = cloudcms.JsonObject{"_doc": "c", "_type": "custom:book", "title": "Third", "isbn": "123"}
+	session.Nodes["d"] = cloudcms.JsonObject{"_doc": "d", "_type": "custom:author", "title": "Jane"}
+	session.UploadAttachment("repo", "master", "a", "default", strings.NewReader("cover"), "text/plain", "cover.txt")
+
+	return session
+}
+
+func TestExportCSV(t *testing.T) {
+	session := newExportSession()
+
+	exporter := NewExporter(session, "repo", "master")
+	exporter.Query = cloudcms.JsonObject{"_type": "custom:book"}
+	exporter.PageSize = 2
+
+	var buf bytes.Buffer
+	count, err := exporter.Export(&buf, CSV)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 3 {
+		t.Fatalf("expected 3 nodes, got %d", count)
+	}
+
+	// the header has the properties of every page, including c's isbn on the second
+	expected := "_doc,_type,author.name,inStock,isbn,price,tags,title\n" +
+		"a,custom:book,Jane,,,9.5,\"x,y\",Hello\n" +
+		"b,custom:book,,true,,,,\"World, again\"\n" +
+		"c,custom:book,,,123,,,Third\n"
+	if buf.String() != expected {
+		t.Fatalf("expected:\n%s\ngot:\n%s", expected, buf.String())
+	}
+
+	// the export reads back with the matching mapping
+	source, err := NewCSVSource(strings.NewReader(buf.String()), Mapping{"title": "title", "price": "price:number", "tags": "tags:list"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	record, err := source.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if record.Properties["price"] != 9.5 || len(record.Properties.GetArray("tags")) != 2 {
+		t.Fatalf("unexpected record: %v", record.Properties)
+	}
+}
+
+func TestExportJSONL(t *testing.T) {
+	session := newExportSession()
+	dir := t.TempDir()
+
+	exporter := NewExporter(session, "repo", "master")
+	exporter.Find = cloudcms.JsonObject{"query": map[string]interface{}{"title": "Hello"}}
+	exporter.Fields = []string{"title", "author.name", "missing"}
+	exporter.AttachmentsDir = dir
+
+	var buf bytes.Buffer
+	count, err := exporter.Export(&buf, JSONL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 1 || buf.String() != "{\"author\":{\"name\":\"Jane\"},\"title\":\"Hello\"}\n" {
+		t.Fatalf("unexpected export (%d):\n%s", count, buf.String())
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "a", "default.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "cover" {
+		t.Fatal("attachment not downloaded")
+	}
+
+	_, err = exporter.Export(&buf, Format("xml"))
+	if err == nil {
+		t.Fatal("expected an error for an unknown format")
+	}
+}
+
+// sortRecorder records the sort of each page
+type sortRecorder struct {
+	*fakesession.Session
+	sorts []string
+}
+
+func (s *sortRecorder) QueryNodes(repositoryId string, branchId string, query cloudcms.JsonObject, pagination cloudcms.JsonObject) (*cloudcms.ResultMap, error) {
+	data, _ := json.Marshal(pagination["sort"])
+	s.sorts = append(s.sorts, string(data))
+	return s.Session.QueryNodes(repositoryId, branchId, query, pagination)
+}
+
+func TestExportSort(t *testing.T) {
+	session := &sortRecorder{Session: newExportSession()}
+	exporter := NewExporter(session, "repo", "master")
+
+	_, err := exporter.Export(io.Discard, JSONL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	exporter.Sort = json.RawMessage(`{"title":-1,"price":1,"_doc":1}`)
+	_, err = exporter.Export(io.Discard, JSONL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{`{"_doc":1}`, `{"title":-1,"price":1,"_doc":1}`}
+	if strings.Join(session.sorts, " ") != strings.Join(expected, " ") {
+		t.Fatalf("expected sorts %v, got %v", expected, session.sorts)
+	}
+}
@@ -0,0 +1,272 @@
+package bulk
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"sync"
+
+	cloudcms "github.com/gitana/cloudcms-go-driver"
+)
+
+const (
+	defaultBatchSize   = 100
+	defaultConcurrency = 4
+)
+
+// ImportSession is the subset of *cloudcms.CloudCmsSession used by the importer.
+type ImportSession interface {
+	QueryOneNode(repositoryId string, branchId string, query cloudcms.JsonObject) (cloudcms.JsonObject, error)
+	CreateNode(repositoryId string, branchId string, obj cloudcms.JsonObject, opts map[string]string) (string, error)
+	UpdateNode(repositoryId string, branchId string, node cloudcms.JsonObject) (cloudcms.JsonObject, error)
+}
+
+var _ ImportSession = (*cloudcms.CloudCmsSession)(nil)
+
+type Status string
+
+const (
+	Created Status = "created"
+	Updated Status = "updated"
+	Failed  Status = "failed"
+)
+
+// Result is the outcome of importing one record.
+type Result struct {
+	Row    int
+	Status Status
+	NodeId string
+	Err    error
+}
+
+type Report struct {
+	Results []Result
+}
+
+func (r *Report) Count(status Status) int {
+	n := 0
+	for _, res := range r.Results {
+		if res.Status == status {
+			n++
+		}
+	}
+
+	return n
+}
+
+func (r *Report) String() string {
+	return fmt.Sprintf("%d created, %d updated, %d failed", r.Count(Created), r.Count(Updated), r.Count(Failed))
+}
+
+// sort orders the results by row, since records that cannot be read are reported before
+// the batch holding the records above them is written
+func (r *Report) sort() {
+	sort.SliceStable(r.Results, func(i, j int) bool {
+		return r.Results[i].Row < r.Results[j].Row
+	})
+}
+
+// WriteCSV writes one line per record with its row, status, node id and error.
+func (r *Report) WriteCSV(w io.Writer) error {
+	writer := csv.NewWriter(w)
+	writer.Write([]string{"row", "status", "nodeId", "error"})
+	for _, res := range r.Results {
+		msg := ""
+		if res.Err != nil {
+			msg = res.Err.Error()
+		}
+		writer.Write([]string{strconv.Itoa(res.Row), string(res.Status), res.NodeId, msg})
+	}
+	writer.Flush()
+
+	return writer.Error()
+}
+
+type Importer struct {
+	session      ImportSession
+	repositoryId string
+	branchId     string
+
+	// Type is the definition QName given to records without a _type.
+	Type string
+
+	// Key is the property identifying a record. When set, a record updates the node of the
+	// same type with the same key value instead of creating a new one.
+	Key string
+
+	// BatchSize is the number of records read before they are written. A batch is written
+	// completely before the next one is read.
+	BatchSize int
+
+	// Concurrency is the number of records of a batch written at the same time.
+	Concurrency int
+
+	// DryRun reports what would be created or updated without writing.
+	DryRun bool
+
+	// Out receives progress messages.
+	Out io.Writer
+}
+
+func NewImporter(session ImportSession, repositoryId string, branchId string) *Importer {
+	return &Importer{
+		session:      session,
+		repositoryId: repositoryId,
+		branchId:     branchId,
+		BatchSize:    defaultBatchSize,
+		Concurrency:  defaultConcurrency,
+		Out:          io.Discard,
+	}
+}
+
+func (im *Importer) logf(format string, args ...interface{}) {
+	fmt.Fprintf(im.Out, format+"\n", args...)
+}
+
+// Import writes every record of the source. Records that cannot be read or written are
+// reported as failed and do not stop the import; an error is only returned when the source
+// itself fails, along with the report of the records written so far.
+func (im *Importer) Import(source Source) (*Report, error) {
+	report := &Report{}
+
+	batchSize := im.BatchSize
+	if batchSize <= 0 {
+		batchSize = defaultBatchSize
+	}
+
+	batch := []*Record{}
+	keys := map[string]bool{}
+	for {
+		record, err := source.Next()
+		if err == io.EOF {
+			break
+		}
+
+		var rowErr *RowError
+		if errors.As(err, &rowErr) {
+			im.logf("row %d: %s: %v", rowErr.Row, Failed, rowErr.Err)
+			report.Results = append(report.Results, Result{Row: rowErr.Row, Status: Failed, Err: rowErr.Err})
+			continue
+		}
+		if err != nil {
+			report.Results = append(report.Results, im.writeBatch(batch)...)
+			report.sort()
+			return report, err
+		}
+
+		// two records with the same key are never written at the same time, so that the
+		// second one updates the node created by the first
+		key := ""
+		if im.Key != "" {
+			key = fmt.Sprint(record.Properties[im.Key])
+		}
+		if len(batch) >= batchSize || (key != "" && keys[key]) {
+			report.Results = append(report.Results, im.writeBatch(batch)...)
+			batch = []*Record{}
+			keys = map[string]bool{}
+		}
+
+		batch = append(batch, record)
+		if key != "" {
+			keys[key] = true
+		}
+	}
+
+	report.Results = append(report.Results, im.writeBatch(batch)...)
+	report.sort()
+	return report, nil
+}
+
+func (im *Importer) writeBatch(batch []*Record) []Result {
+	results := make([]Result, len(batch))
+
+	concurrency := im.Concurrency
+	if concurrency <= 0 {
+		concurrency = defaultConcurrency
+	}
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, concurrency)
+	for i, record := range batch {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, record *Record) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			results[i] = im.write(record)
+		}(i, record)
+	}
+	wg.Wait()
+
+	for _, res := range results {
+		if res.Err != nil {
+			im.logf("row %d: %s: %v", res.Row, res.Status, res.Err)
+		} else {
+			im.logf("row %d: %s %s", res.Row, res.Status, res.NodeId)
+		}
+	}
+
+	return results
+}
+
+func (im *Importer) write(record *Record) Result {
+	obj := cloudcms.JsonObject{}
+	for key, val := range record.Properties {
+		obj[key] = val
+	}
+	if obj["_type"] == nil && im.Type != "" {
+		obj["_type"] = im.Type
+	}
+
+	var existing cloudcms.JsonObject
+	if im.Key != "" {
+		val, ok := obj[im.Key]
+		if !ok || val == nil || val == "" {
+			return Result{Row: record.Row, Status: Failed, Err: fmt.Errorf("missing key property %s", im.Key)}
+		}
+
+		query := cloudcms.JsonObject{im.Key: val}
+		if obj["_type"] != nil {
+			query["_type"] = obj["_type"]
+		}
+
+		var err error
+		existing, err = im.session.QueryOneNode(im.repositoryId, im.branchId, query)
+		if err != nil {
+			return Result{Row: record.Row, Status: Failed, Err: err}
+		}
+	}
+
+	if existing == nil {
+		if im.DryRun {
+			return Result{Row: record.Row, Status: Created}
+		}
+
+		nodeId, err := im.session.CreateNode(im.repositoryId, im.branchId, obj, nil)
+		if err != nil {
+			return Result{Row: record.Row, Status: Failed, Err: err}
+		}
+		return Result{Row: record.Row, Status: Created, NodeId: nodeId}
+	}
+
+	nodeId := cloudcms.ExtractId(&existing)
+	if im.DryRun {
+		return Result{Row: record.Row, Status: Updated, NodeId: nodeId}
+	}
+
+	// properties missing from the record are kept
+	for key, val := range obj {
+		existing[key] = val
+	}
+
+	_, err := im.session.UpdateNode(im.repositoryId, im.branchId, existing)
+	if err != nil {
+		return Result{Row: record.Row, Status: Failed, NodeId: nodeId, Err: err}
+	}
+
+	return Result{Row: record.Row, Status: Updated, NodeId: nodeId}
+}
@@ -0,0 +1,170 @@
+package bulk
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	cloudcms "github.com/gitana/cloudcms-go-driver"
+	"github.com/gitana/cloudcms-go-driver/internal/fakesession"
+)
+
+func TestCSVSource(t *testing.T) {
+	data := "sku,name,price,in stock,tags,author\n" +
+		"b1,Hello,9.5,true,\"a, b\",Jane\n" +
+		"b2,,x,false,,\n"
+
+	source, err := NewCSVSource(strings.NewReader(data), Mapping{
+		"sku":      "sku",
+		"name":     "title",
+		"price":    "price:number",
+		"in stock": "inStock:boolean",
+		"tags":     "tags:list",
+		"author":   "author.name",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	record, err := source.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if record.Row != 2 {
+		t.Fatalf("expected row 2, got %d", record.Row)
+	}
+
+	p := record.Properties
+	if p["title"] != "Hello" || p["price"] != 9.5 || p["inStock"] != true {
+		t.Fatalf("unexpected properties: %v", p)
+	}
+	if tags := p.GetArray("tags"); len(tags) != 2 || tags[1] != "b" {
+		t.Fatalf("unexpected tags: %v", p["tags"])
+	}
+	author := p.GetObject("author")
+	if author.GetString("name") != "Jane" {
+		t.Fatalf("unexpected author: %v", p["author"])
+	}
+
+	_, err = source.Next()
+	rowErr, ok := err.(*RowError)
+	if !ok || rowErr.Row != 3 || !strings.Contains(err.Error(), "price") {
+		t.Fatalf("expected a row error for the price, got %v", err)
+	}
+
+	_, err = NewCSVSource(strings.NewReader(data), Mapping{"isbn": "isbn"})
+	if err == nil {
+		t.Fatal("expected an error for a missing column")
+	}
+
+	source, err = NewCSVSource(strings.NewReader("sku,name\n\"b1,Hello\n"), Mapping{"sku": "sku"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = source.Next()
+	rowErr, ok = err.(*RowError)
+	if !ok || rowErr.Row != 2 {
+		t.Fatalf("expected a row error for a malformed first row, got %v", err)
+	}
+}
+
+func TestJSONLSource(t *testing.T) {
+	data := "{\"title\": \"a\"}\n\n{bad\n{\"title\": \"c\"}\n"
+	source := NewJSONLSource(strings.NewReader(data))
+
+	record, err := source.Next()
+	if err != nil || record.Row != 1 || record.Properties["title"] != "a" {
+		t.Fatalf("unexpected first record: %v %v", record, err)
+	}
+
+	_, err = source.Next()
+	if rowErr, ok := err.(*RowError); !ok || rowErr.Row != 3 {
+		t.Fatalf("expected a row error on line 3, got %v", err)
+	}
+
+	record, err = source.Next()
+	if err != nil || record.Row != 4 {
+		t.Fatalf("unexpected last record: %v %v", record, err)
+	}
+
+	_, err = source.Next()
+	if err != io.EOF {
+		t.Fatalf("expected EOF, got %v", err)
+	}
+}
+
+func TestImport(t *testing.T) {
+	session := fakesession.New()
+	session.Nodes["existing"] = cloudcms.JsonObject{"_doc": "existing", "_type": "custom:book", "sku": "b1", "title": "Old", "pages": 10.0}
+	session.FailTitle = "Broken"
+
+	data := "{\"sku\": \"b1\", \"title\": \"Hello\"}\n" +
+		"{\"sku\": \"b2\", \"title\": \"World\"}\n" +
+		"{\"sku\": \"b2\", \"title\": \"World again\"}\n" +
+		"{\"title\": \"No key\"}\n" +
+		"{\"sku\": \"b3\", \"title\": \"Broken\"}\n" +
+		"not json\n"
+
+	importer := NewImporter(session, "repo", "master")
+	importer.Type = "custom:book"
+	importer.Key = "sku"
+	importer.BatchSize = 2
+
+	report, err := importer.Import(NewJSONLSource(strings.NewReader(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	actual := []string{}
+	for _, res := range report.Results {
+		actual = append(actual, fmt.Sprintf("%d %s", res.Row, res.Status))
+	}
+	expected := "1 updated,2 created,3 updated,4 failed,5 failed,6 failed"
+	if strings.Join(actual, ",") != expected {
+		t.Fatalf("expected %s, got %s", expected, strings.Join(actual, ","))
+	}
+	if report.String() != "1 created, 2 updated, 3 failed" {
+		t.Fatalf("unexpected summary: %s", report)
+	}
+
+	if len(session.Nodes) != 2 {
+		t.Fatalf("expected 2 nodes, got %d", len(session.Nodes))
+	}
+	book := session.Nodes["existing"]
+	if book["title"] != "Hello" || book["pages"] != 10.0 {
+		t.Fatalf("existing node not merged: %v", book)
+	}
+	if session.Nodes[report.Results[1].NodeId]["title"] != "World again" {
+		t.Fatal("duplicate key should update the node created by the earlier row")
+	}
+
+	var buf bytes.Buffer
+	err = report.WriteCSV(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "4,failed,,missing key property sku") {
+		t.Fatalf("unexpected report:\n%s", buf.String())
+	}
+}
+
+func TestImportDryRun(t *testing.T) {
+	session := fakesession.New()
+	source, err := NewCSVSource(strings.NewReader("title\nA\nB\n"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	importer := NewImporter(session, "repo", "master")
+	importer.DryRun = true
+
+	report, err := importer.Import(source)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Count(Created) != 2 || len(session.Nodes) != 0 {
+		t.Fatalf("dry run should plan 2 creates and write nothing: %s", report)
+	}
+}
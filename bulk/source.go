@@ -0,0 +1,215 @@
+// Package bulk imports records from JSON Lines and CSV files into a branch, and exports the
+// nodes matching a query to JSON Lines or CSV.
+package bulk
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	cloudcms "github.com/gitana/cloudcms-go-driver"
+)
+
+// Source reads records one at a time. Next returns io.EOF after the last record. If a single
+// record cannot be read, Next returns a *RowError and reading may continue.
+type Source interface {
+	Next() (*Record, error)
+}
+
+type Record struct {
+	// Row is the line of the record in the input, starting at 1.
+	Row        int
+	Properties cloudcms.JsonObject
+}
+
+type RowError struct {
+	Row int
+	Err error
+}
+
+func (e *RowError) Error() string {
+	return fmt.Sprintf("row %d: %v", e.Row, e.Err)
+}
+
+func (e *RowError) Unwrap() error {
+	return e.Err
+}
+
+type jsonlSource struct {
+	scanner *bufio.Scanner
+	line    int
+}
+
+// NewJSONLSource reads one JSON object per line. Blank lines are skipped.
+func NewJSONLSource(r io.Reader) Source {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+
+	return &jsonlSource{scanner: scanner}
+}
+
+func (s *jsonlSource) Next() (*Record, error) {
+	for s.scanner.Scan() {
+		s.line++
+
+		line := strings.TrimSpace(s.scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		var properties cloudcms.JsonObject
+		err := json.Unmarshal([]byte(line), &properties)
+		if err != nil {
+			return nil, &RowError{Row: s.line, Err: err}
+		}
+
+		return &Record{Row: s.line, Properties: properties}, nil
+	}
+
+	err := s.scanner.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	return nil, io.EOF
+}
+
+// Mapping maps CSV columns to properties. A property may be a dotted path for nested
+// objects, and may end with a type: "price:number", "inStock:boolean", "pages:integer",
+// "tags:list" (comma separated) or "metadata:json". The default type is string.
+type Mapping map[string]string
+
+type column struct {
+	index    int
+	path     []string
+	dataType string
+}
+
+type csvSource struct {
+	reader  *csv.Reader
+	columns []column
+}
+
+// NewCSVSource reads a CSV file whose first line names the columns. Columns missing from
+// mapping are skipped; if mapping is nil every column is read as a string property of the
+// same name. Empty cells are left out of the record.
+func NewCSVSource(r io.Reader, mapping Mapping) (Source, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CSV header: %v", err)
+	}
+
+	found := map[string]bool{}
+	columns := []column{}
+	for i, name := range header {
+		name = strings.TrimSpace(strings.TrimPrefix(name, "\ufeff"))
+		found[name] = true
+
+		target := name
+		if mapping != nil {
+			var ok bool
+			target, ok = mapping[name]
+			if !ok {
+				continue
+			}
+		}
+
+		property, dataType, _ := strings.Cut(target, ":")
+		switch dataType {
+		case "", "string", "number", "integer", "boolean", "list", "json":
+		default:
+			return nil, fmt.Errorf("column %s: unknown type %q", name, dataType)
+		}
+
+		columns = append(columns, column{index: i, path: strings.Split(property, "."), dataType: dataType})
+	}
+
+	for name := range mapping {
+		if !found[name] {
+			return nil, fmt.Errorf("column %s is not in the CSV header", name)
+		}
+	}
+
+	return &csvSource{reader: reader, columns: columns}, nil
+}
+
+func (s *csvSource) Next() (*Record, error) {
+	fields, err := s.reader.Read()
+	if err == io.EOF {
+		return nil, io.EOF
+	}
+
+	if err != nil {
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			return nil, &RowError{Row: parseErr.StartLine, Err: parseErr.Err}
+		}
+		return nil, err
+	}
+	row, _ := s.reader.FieldPos(0)
+
+	properties := cloudcms.JsonObject{}
+	for _, col := range s.columns {
+		if col.index >= len(fields) || fields[col.index] == "" {
+			continue
+		}
+
+		val, err := convert(fields[col.index], col.dataType)
+		if err != nil {
+			return nil, &RowError{Row: row, Err: fmt.Errorf("%s: %v", strings.Join(col.path, "."), err)}
+		}
+
+		setPath(properties, col.path, val)
+	}
+
+	return &Record{Row: row, Properties: properties}, nil
+}
+
+func convert(s string, dataType string) (interface{}, error) {
+	switch dataType {
+	case "number":
+		return strconv.ParseFloat(strings.TrimSpace(s), 64)
+	case "integer":
+		n, err := strconv.ParseInt(strings.TrimSpace(s), 10, 64)
+		return float64(n), err
+	case "boolean":
+		return strconv.ParseBool(strings.TrimSpace(s))
+	case "list":
+		items := []interface{}{}
+		for _, item := range strings.Split(s, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		return items, nil
+	case "json":
+		var v interface{}
+		err := json.Unmarshal([]byte(s), &v)
+		return v, err
+	}
+
+	return s, nil
+}
+
+// setPath sets a value in nested objects, creating them as needed
+func setPath(obj cloudcms.JsonObject, path []string, val interface{}) {
+	m := map[string]interface{}(obj)
+	for _, key := range path[:len(path)-1] {
+		next, ok := m[key].(map[string]interface{})
+		if !ok {
+			next = map[string]interface{}{}
+			m[key] = next
+		}
+		m = next
+	}
+
+	m[path[len(path)-1]] = val
+}
@@ -0,0 +1,234 @@
+package cloudcms
+
+import (
+	"container/list"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const defaultCacheTTL = time.Minute
+
+// Cache stores the responses of node reads and queries. A cached response is not deleted
+// when it goes stale: writes change the keys of the branch instead, so a backend only has to
+// expire entries. Those writes are only known to the session that made them, so each
+// session keys its entries apart: a backend shared by several sessions, or processes, saves
+// memory but not requests. Implementations must be safe for concurrent use.
+type Cache interface {
+	Get(key string) ([]byte, bool)
+	Set(key string, value []byte, ttl time.Duration)
+}
+
+// MemoryCache is a Cache that keeps responses in memory, evicting the least recently used
+// once it holds more than its maximum number of entries.
+type MemoryCache struct {
+	mu         sync.Mutex
+	maxEntries int
+	entries    *list.List
+	index      map[string]*list.Element
+	now        func() time.Time
+}
+
+var _ Cache = (*MemoryCache)(nil)
+
+type memoryEntry struct {
+	key     string
+	value   []byte
+	expires time.Time
+}
+
+// NewMemoryCache returns a cache of up to maxEntries responses, or of any number if
+// maxEntries is zero.
+func NewMemoryCache(maxEntries int) *MemoryCache {
+	return &MemoryCache{
+		maxEntries: maxEntries,
+		entries:    list.New(),
+		index:      map[string]*list.Element{},
+		now:        time.Now,
+	}
+}
+
+func (c *MemoryCache) Get(key string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.index[key]
+	if !ok {
+		return nil, false
+	}
+	entry := el.Value.(*memoryEntry)
+	if !entry.expires.IsZero() && !c.now().Before(entry.expires) {
+		c.entries.Remove(el)
+		delete(c.index, key)
+		return nil, false
+	}
+
+	c.entries.MoveToFront(el)
+	return entry.value, true
+}
+
+func (c *MemoryCache) Set(key string, value []byte, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var expires time.Time
+	if ttl > 0 {
+		expires = c.now().Add(ttl)
+	}
+
+	if el, ok := c.index[key]; ok {
+		entry := el.Value.(*memoryEntry)
+		entry.value = value
+		entry.expires = expires
+		c.entries.MoveToFront(el)
+		return
+	}
+
+	c.index[key] = c.entries.PushFront(&memoryEntry{key: key, value: value, expires: expires})
+	for c.maxEntries > 0 && c.entries.Len() > c.maxEntries {
+		el := c.entries.Back()
+		c.entries.Remove(el)
+		delete(c.index, el.Value.(*memoryEntry).key)
+	}
+}
+
+// Len returns the number of entries, including expired ones not yet evicted.
+func (c *MemoryCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.entries.Len()
+}
+
+// responseCache keys responses by repository and branch generation. Every write to a branch
+// bumps its generation and every write elsewhere in a repository bumps the repository's, so
+// the responses cached before the write are never read again. The keys start with a
+// random salt, so another session sharing the backend, whose writes are not seen here, never
+// reads them.
+type responseCache struct {
+	backend Cache
+	ttl     time.Duration
+	salt    string
+
+	mu          sync.Mutex
+	generations map[string]uint64
+}
+
+// newResponseCache returns nil if the config sets neither a Cache nor a CacheSize
+func newResponseCache(config *CloudcmsConfig) *responseCache {
+	if config == nil || (config.Cache == nil && config.CacheSize <= 0) {
+		return nil
+	}
+
+	c := &responseCache{
+		backend:     config.Cache,
+		ttl:         time.Duration(config.CacheTTL * float64(time.Second)),
+		salt:        randomSalt(),
+		generations: map[string]uint64{},
+	}
+	if c.backend == nil {
+		c.backend = NewMemoryCache(config.CacheSize)
+	}
+	if c.ttl <= 0 {
+		c.ttl = defaultCacheTTL
+	}
+
+	return c
+}
+
+// caches reports whether the response of a request is cached: the reads of a branch
+func (c *responseCache) caches(method string, path string) bool {
+	_, branchId := cacheScope(path)
+	return branchId != "" && method != http.MethodHead && isRead(method, path)
+}
+
+// key returns the cache key of a request in the current generation of its branch
+func (c *responseCache) key(method string, path string, query string, locale string, body []byte) string {
+	repositoryId, branchId := cacheScope(path)
+
+	c.mu.Lock()
+	repoGen := c.generations[repositoryId]
+	branchGen := c.generations[repositoryId+"/"+branchId]
+	c.mu.Unlock()
+
+	sum := sha256.Sum256([]byte(method + "\n" + path + "?" + query + "\n" + locale + "\n" + string(body)))
+	return fmt.Sprintf("%s:%s/%s:%d.%d:%x", c.salt, repositoryId, branchId, repoGen, branchGen, sum)
+}
+
+func randomSalt() string {
+	b := make([]byte, 8)
+	_, err := rand.Read(b)
+	if err != nil {
+		// the clock still tells the sessions of a process apart
+		return strconv.FormatInt(time.Now().UnixNano(), 36)
+	}
+
+	return hex.EncodeToString(b)
+}
+
+// invalidate forgets the responses a write to path may have changed
+func (c *responseCache) invalidate(method string, path string) {
+	if isRead(method, path) {
+		return
+	}
+
+	repositoryId, branchId := cacheScope(path)
+	if repositoryId == "" {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if branchId == "" {
+		c.generations[repositoryId]++
+	} else {
+		c.generations[repositoryId+"/"+branchId]++
+	}
+}
+
+// cacheScope returns the repository and branch of an API path, if any
+func cacheScope(path string) (string, string) {
+	parts := strings.Split(strings.TrimPrefix(path, "/"), "/")
+	if len(parts) < 2 || parts[0] != "repositories" || parts[1] == "" {
+		return "", ""
+	}
+	if len(parts) < 4 || parts[2] != "branches" {
+		return parts[1], ""
+	}
+
+	return parts[1], parts[3]
+}
+
+// readActions are the POST endpoints that only read. The branch GraphQL endpoint only
+// answers queries.
+var readActions = []string{"/query", "/find", "/search", "/traverse", "/tree", "/graphql"}
+
+func isRead(method string, path string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	case http.MethodPost:
+		for _, action := range readActions {
+			if strings.HasSuffix(path, action) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// NoCache returns a copy of the session that neither reads nor fills the cache. Its writes
+// still invalidate the responses cached by the session.
+func (session *CloudCmsSession) NoCache() *CloudCmsSession {
+	res := *session
+	res.noCache = true
+	return &res
+}
@@ -0,0 +1,152 @@
+package cloudcms
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestMemoryCache(t *testing.T) {
+	now := time.Now()
+	cache := NewMemoryCache(2)
+	cache.now = func() time.Time { return now }
+
+	cache.Set("a", []byte("1"), time.Minute)
+	cache.Set("b", []byte("2"), time.Minute)
+	cache.Get("a")
+	cache.Set("c", []byte("3"), time.Second)
+
+	if _, ok := cache.Get("b"); ok {
+		t.Error("the least recently used entry should be evicted")
+	}
+	if v, ok := cache.Get("a"); !ok || string(v) != "1" {
+		t.Errorf("unexpected entry %q", v)
+	}
+
+	now = now.Add(2 * time.Second)
+	if _, ok := cache.Get("c"); ok {
+		t.Error("expired entry returned")
+	}
+	if cache.Len() != 1 {
+		t.Errorf("expected 1 entry, got %d", cache.Len())
+	}
+}
+
+func TestCacheScope(t *testing.T) {
+	for path, expected := range map[string][2]string{
+		"/repositories/r/branches/b/nodes/n": {"r", "b"},
+		"/repositories/r/branches/b":         {"r", "b"},
+		"/repositories/r/branches":           {"r", ""},
+		"/repositories/r/changesets/query":   {"r", ""},
+		"/projects/p":                        {"", ""},
+	} {
+		repositoryId, branchId := cacheScope(path)
+		if repositoryId != expected[0] || branchId != expected[1] {
+			t.Errorf("%s: expected %v, got %s %s", path, expected, repositoryId, branchId)
+		}
+	}
+}
+
+func TestResponseCache(t *testing.T) {
+	var mu sync.Mutex
+	hits := map[string]int{}
+	session := newTestSession(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		hits[r.Method+" "+r.URL.Path]++
+		count := hits[r.Method+" "+r.URL.Path]
+		mu.Unlock()
+
+		switch r.URL.Path {
+		case "/repositories/r/branches/b/nodes/query":
+			w.Write([]byte(`{"rows": [{"_doc": "n1"}], "size": 1, "total_rows": 1, "offset": 0}`))
+		default:
+			fmt.Fprintf(w, `{"_doc": "n1", "title": "%s %d"}`, r.Header.Get("Accept-Language"), count)
+		}
+	}))
+	session.cache = newResponseCache(&CloudcmsConfig{CacheSize: 10})
+
+	read := func(s *CloudCmsSession, branchId string) string {
+		node, err := s.ReadNode("r", branchId, "n1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return node.GetString("title")
+	}
+
+	if read(session, "b") != " 1" || read(session, "b") != " 1" {
+		t.Fatal("second read should come from the cache")
+	}
+	node, _ := session.ReadNode("r", "b", "n1")
+	node["title"] = "changed"
+	if read(session, "b") != " 1" {
+		t.Fatal("cached responses should be decoded on every read")
+	}
+
+	session.QueryNodes("r", "b", JsonObject{"title": "a"}, nil)
+	session.QueryNodes("r", "b", JsonObject{"title": "a"}, nil)
+	session.QueryNodes("r", "b", JsonObject{"title": "b"}, nil)
+	if hits["POST /repositories/r/branches/b/nodes/query"] != 2 {
+		t.Fatalf("expected 2 queries, got %d", hits["POST /repositories/r/branches/b/nodes/query"])
+	}
+
+	if read(session.NoCache(), "b") != " 2" {
+		t.Fatal("NoCache should ask the server")
+	}
+	if read(session.WithLocale("fr"), "b") != "fr 3" {
+		t.Fatal("locales should be cached apart")
+	}
+
+	read(session, "other")
+	_, err := session.NoCache().UpdateNode("r", "b", JsonObject{"_doc": "n1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if read(session, "b") != " 4" {
+		t.Fatal("a write should invalidate the branch")
+	}
+	if read(session, "other") != " 1" {
+		t.Fatal("a write should not invalidate other branches")
+	}
+
+	session.Post("/repositories/r/branches", nil, MapToReader(nil))
+	if read(session, "other") != " 2" {
+		t.Fatal("a repository write should invalidate its branches")
+	}
+
+	shared := *session
+	shared.cache = newResponseCache(&CloudcmsConfig{Cache: session.cache.backend})
+	if read(&shared, "other") != " 3" {
+		t.Fatal("sessions sharing a backend should not read each other's responses")
+	}
+}
+
+func TestResponseCacheGraphQL(t *testing.T) {
+	hits := map[string]int{}
+	session := newTestSession(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits[r.Method+" "+r.URL.Path]++
+
+		switch r.URL.Path {
+		case "/repositories/r/branches/b/graphql":
+			w.Write([]byte(`{"data": {"n1": {"title": "a"}}}`))
+		default:
+			w.Write([]byte(`{"_doc": "n1", "title": "a"}`))
+		}
+	}))
+	session.cache = newResponseCache(&CloudcmsConfig{CacheSize: 10})
+
+	if _, err := session.ReadNode("r", "b", "n1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := session.GraphQLQuery("r", "b", "{ n1 { title } }", "", nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := session.ReadNode("r", "b", "n1"); err != nil {
+		t.Fatal(err)
+	}
+
+	if hits["GET /repositories/r/branches/b/nodes/n1"] != 1 {
+		t.Fatalf("a GraphQL query should not invalidate the branch, got %d reads", hits["GET /repositories/r/branches/b/nodes/n1"])
+	}
+}
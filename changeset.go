@@ -0,0 +1,158 @@
+package cloudcms
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Changeset is an entry in the history of a repository. Id has the form "revision:hash".
+type Changeset struct {
+	Id        string
+	Revision  int
+	Branch    string
+	Parents   []string
+	Tags      []string
+	Active    bool
+	CreatedBy string
+	CreatedOn time.Time
+	Object    JsonObject
+}
+
+func ToChangeset(obj JsonObject) *Changeset {
+	changeset := &Changeset{
+		Id:      obj.GetString("_doc"),
+		Branch:  obj.GetString("branch"),
+		Parents: toStrings(obj.GetArray("parents")),
+		Tags:    toStrings(obj.GetArray("tags")),
+		Object:  obj,
+	}
+
+	if active, ok := obj["active"].(bool); ok {
+		changeset.Active = active
+	}
+
+	changeset.Revision = getInt(obj, "revision")
+	if changeset.Revision == 0 {
+		changeset.Revision = ChangesetRevision(changeset.Id)
+	}
+
+	system := obj.GetObject("_system")
+	if system != nil {
+		changeset.CreatedBy = system.GetString("created_by")
+		createdOn := system.GetObject("created_on")
+		if ms, ok := createdOn["ms"].(float64); ok {
+			changeset.CreatedOn = time.UnixMilli(int64(ms))
+		}
+	}
+
+	return changeset
+}
+
+// ChangesetRevision returns the revision of a changeset id such as "12:a1b2c3", or 0 if
+// the id has no revision.
+func ChangesetRevision(changesetId string) int {
+	revision, _, _ := strings.Cut(changesetId, ":")
+	n, err := strconv.Atoi(revision)
+	if err != nil {
+		return 0
+	}
+
+	return n
+}
+
+func (session *CloudCmsSession) ReadChangeset(repositoryId string, changesetId string) (*Changeset, error) {
+	session = session.named("ReadChangeset")
+	res, err := session.Get(fmt.Sprintf("/repositories/%s/changesets/%s", repositoryId, changesetId), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return ToChangeset(res), nil
+}
+
+func (session *CloudCmsSession) ListChangesets(repositoryId string, pagination JsonObject) (*ResultMap, error) {
+	session = session.named("ListChangesets")
+	res, err := session.Get(fmt.Sprintf("/repositories/%s/changesets", repositoryId), ToParams(pagination))
+	if err != nil {
+		return nil, err
+	}
+
+	return ToResultMap(res), nil
+}
+
+func (session *CloudCmsSession) QueryChangesets(repositoryId string, query JsonObject, pagination JsonObject) (*ResultMap, error) {
+	session = session.named("QueryChangesets")
+	res, err := session.Post(fmt.Sprintf("/repositories/%s/changesets/query", repositoryId), ToParams(pagination), MapToReader(query))
+	if err != nil {
+		return nil, err
+	}
+
+	return ToResultMap(res), nil
+}
+
+// ListChangesetNodes lists the nodes written by a changeset.
+func (session *CloudCmsSession) ListChangesetNodes(repositoryId string, changesetId string, pagination JsonObject) (*ResultMap, error) {
+	session = session.named("ListChangesetNodes")
+	res, err := session.Get(fmt.Sprintf("/repositories/%s/changesets/%s/nodes", repositoryId, changesetId), ToParams(pagination))
+	if err != nil {
+		return nil, err
+	}
+
+	return ToResultMap(res), nil
+}
+
+// ReadChangesetHistory runs the changeset history job for a branch, waits for it and returns
+// the changesets after fromChangesetId up to and including toChangesetId, oldest first.
+// Either id may be empty to leave that end open.
+func (session *CloudCmsSession) ReadChangesetHistory(repositoryId string, branchId string, fromChangesetId string, toChangesetId string) ([]*Changeset, error) {
+	session = session.named("ReadChangesetHistory")
+	params := url.Values{}
+	if fromChangesetId != "" {
+		params.Add("root", fromChangesetId)
+	}
+	if toChangesetId != "" {
+		params.Add("tip", toChangesetId)
+	}
+
+	jobId, err := session.startChangesetHistory(repositoryId, branchId, params)
+	if err != nil {
+		return nil, err
+	}
+
+	err = session.WaitForJob(jobId)
+	if err != nil {
+		return nil, err
+	}
+
+	job, err := session.ReadJob(jobId)
+	if err != nil {
+		return nil, err
+	}
+
+	rows := job.GetObjectArray("changesets")
+	if rows == nil {
+		rows = job.GetObjectArray("results")
+	}
+
+	from := ChangesetRevision(fromChangesetId)
+	to := ChangesetRevision(toChangesetId)
+
+	res := []*Changeset{}
+	for _, row := range rows {
+		changeset := ToChangeset(row)
+		if changeset.Revision <= from || (to > 0 && changeset.Revision > to) {
+			continue
+		}
+		res = append(res, changeset)
+	}
+
+	sort.SliceStable(res, func(i, j int) bool {
+		return res[i].Revision < res[j].Revision
+	})
+
+	return res, nil
+}
@@ -0,0 +1,106 @@
+package cloudcms
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestReadChangesetHistory(t *testing.T) {
+	session := newTestSession(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/history/start"):
+			if r.URL.Query().Get("root") != "2:b" || r.URL.Query().Get("tip") != "4:d" {
+				t.Errorf("unexpected history config %v", r.URL.Query())
+			}
+			json.NewEncoder(w).Encode(JsonObject{"_doc": "job1"})
+		case r.URL.Path == "/jobs/job1":
+			json.NewEncoder(w).Encode(JsonObject{
+				"state": "FINISHED",
+				"changesets": []JsonObject{
+					{"_doc": "4:d", "branch": "master", "_system": JsonObject{"created_by": "admin", "created_on": JsonObject{"ms": 1700000000000}}},
+					{"_doc": "3:c", "revision": 3, "parents": []string{"2:b"}},
+					{"_doc": "2:b", "revision": 2},
+					{"_doc": "5:e", "revision": 5},
+				},
+			})
+		default:
+			t.Errorf("unexpected request %s", r.URL.Path)
+		}
+	}))
+
+	history, err := session.ReadChangesetHistory("repo", "master", "2:b", "4:d")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(history) != 2 || history[0].Id != "3:c" || history[1].Id != "4:d" {
+		t.Fatalf("unexpected history %v", history)
+	}
+	if history[0].Parents[0] != "2:b" {
+		t.Fatal("failed to read parents")
+	}
+	if history[1].Revision != 4 || history[1].CreatedBy != "admin" || history[1].CreatedOn.UnixMilli() != 1700000000000 {
+		t.Fatalf("failed to read changeset %+v", history[1])
+	}
+}
+
+func TestChangesets(t *testing.T) {
+	session, repository := setupTestRepository(t)
+
+	repositoryId := ExtractId(&repository)
+	branchId := "master"
+	defer session.DeleteRepository(repositoryId)
+
+	nodeId, err := session.CreateNode(repositoryId, branchId, JsonObject{"title": "first"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	node, err := session.ReadNode(repositoryId, branchId, nodeId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	systemObj := node.GetObject("_system")
+	changesetId := systemObj.GetString("changeset")
+
+	changeset, err := session.ReadChangeset(repositoryId, changesetId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if changeset.Id != changesetId || changeset.Revision == 0 {
+		t.Fatal("failed to read changeset")
+	}
+
+	nodes, err := session.ListChangesetNodes(repositoryId, changesetId, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !contains(nodeId, nodes.Rows()) {
+		t.Fatal("changeset nodes should include the created node")
+	}
+
+	changesets, err := session.ListChangesets(repositoryId, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if changesets.Size() == 0 {
+		t.Fatal("no changesets listed")
+	}
+
+	_, err = session.UpdateNode(repositoryId, branchId, JsonObject{"_doc": nodeId, "title": "second"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	history, err := session.ReadChangesetHistory(repositoryId, branchId, changesetId, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range history {
+		if c.Revision <= changeset.Revision {
+			t.Fatal("history should only include later changesets")
+		}
+	}
+}
@@ -0,0 +1,151 @@
+// Command cloudcms-gen generates Go structs and typed session wrappers from Cloud CMS
+// content type definitions.
+//
+// Definitions are read from a JSON export with -input, or queried from a branch with
+// -repository and -branch, using the session from cloudcms.ConnectDefault; see
+// cloudcms.FindConfig for where it looks.
+//
+// With -operations, it instead generates typed functions for the GraphQL operations in the
+// given .graphql files and directories. They are checked against the schema in -schema, or
+// against the schema of the branch given by -repository and -branch.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	cloudcms "github.com/gitana/cloudcms-go-driver"
+	"github.com/gitana/cloudcms-go-driver/codegen"
+	"github.com/gitana/cloudcms-go-driver/graphql"
+)
+
+func main() {
+	input := flag.String("input", "", "JSON file of exported definitions")
+	repositoryId := flag.String("repository", "", "repository to read definitions from")
+	branchId := flag.String("branch", "master", "branch to read definitions from")
+	pkg := flag.String("package", "models", "package name of the generated file")
+	output := flag.String("output", "", "file to write (default stdout)")
+	operations := flag.String("operations", "", "comma separated .graphql files or directories of operations")
+	schema := flag.String("schema", "", "GraphQL schema file to check operations against")
+	flag.Parse()
+
+	var err error
+	if *operations != "" {
+		err = runOperations(*operations, *schema, *repositoryId, *branchId, *pkg, *output)
+	} else {
+		err = run(*input, *repositoryId, *branchId, *pkg, *output)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "cloudcms-gen: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(input string, repositoryId string, branchId string, pkg string, output string) error {
+	var defs []*cloudcms.Definition
+
+	switch {
+	case input != "":
+		f, err := os.Open(input)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		defs, err = codegen.LoadDefinitions(f)
+		if err != nil {
+			return err
+		}
+	case repositoryId != "":
+		session, err := cloudcms.ConnectDefault()
+		if err != nil {
+			return err
+		}
+
+		defs, err = codegen.FetchDefinitions(session, repositoryId, branchId)
+		if err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("either -input or -repository is required")
+	}
+
+	src, err := codegen.GenerateTypes(defs, codegen.Options{Package: pkg})
+	if err != nil {
+		return err
+	}
+
+	return write(src, output)
+}
+
+func runOperations(operations string, schemaFile string, repositoryId string, branchId string, pkg string, output string) error {
+	var schema *graphql.Schema
+
+	switch {
+	case schemaFile != "":
+		f, err := os.Open(schemaFile)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		schema, err = codegen.LoadSchema(f)
+		if err != nil {
+			return err
+		}
+	case repositoryId != "":
+		session, err := cloudcms.ConnectDefault()
+		if err != nil {
+			return err
+		}
+
+		schema, err = session.ReadGraphQLSchema(repositoryId, branchId)
+		if err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("either -schema or -repository is required")
+	}
+
+	paths := []string{}
+	for _, path := range strings.Split(operations, ",") {
+		info, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			paths = append(paths, path)
+			continue
+		}
+
+		matches, err := filepath.Glob(filepath.Join(path, "*.graphql"))
+		if err != nil {
+			return err
+		}
+		paths = append(paths, matches...)
+	}
+
+	sources, err := codegen.LoadSources(paths)
+	if err != nil {
+		return err
+	}
+
+	src, err := codegen.GenerateOperations(schema, sources, codegen.Options{Package: pkg})
+	if err != nil {
+		return err
+	}
+
+	return write(src, output)
+}
+
+func write(src []byte, output string) error {
+	if output == "" {
+		_, err := os.Stdout.Write(src)
+		return err
+	}
+
+	return os.WriteFile(output, src, 0644)
+}
@@ -0,0 +1,169 @@
+// Command cloudcms-import creates or updates nodes from a JSON Lines or CSV file.
+//
+//	cloudcms-import -repository <id> -file books.jsonl -type custom:book -key sku
+//	cloudcms-import -repository <id> -file books.csv -map "SKU=sku,Name=title,Price=price:number" -key sku
+//	cloudcms-import -repository <id> -file books.csv -dry-run -report report.csv
+//
+// The session comes from cloudcms.ConnectDefault; see cloudcms.FindConfig for where it looks.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	cloudcms "github.com/gitana/cloudcms-go-driver"
+	"github.com/gitana/cloudcms-go-driver/bulk"
+)
+
+func main() {
+	repositoryId := flag.String("repository", "", "repository to import into")
+	branchId := flag.String("branch", "master", "branch to import into")
+	file := flag.String("file", "", "JSON Lines or CSV file to import")
+	format := flag.String("format", "", "jsonl or csv (default: from the file extension)")
+	mapping := flag.String("map", "", "comma separated column=property[:type] pairs for CSV files")
+	typeQName := flag.String("type", "", "definition QName for records without a _type")
+	key := flag.String("key", "", "property used to update existing nodes instead of creating new ones")
+	batchSize := flag.Int("batch", 100, "records per batch")
+	concurrency := flag.Int("concurrency", 4, "records written at the same time")
+	dryRun := flag.Bool("dry-run", false, "report the changes without making them")
+	reportFile := flag.String("report", "", "write the per-row report to this CSV file")
+	flag.Parse()
+
+	opts := &options{
+		repositoryId: *repositoryId,
+		branchId:     *branchId,
+		file:         *file,
+		format:       *format,
+		mapping:      *mapping,
+		typeQName:    *typeQName,
+		key:          *key,
+		batchSize:    *batchSize,
+		concurrency:  *concurrency,
+		dryRun:       *dryRun,
+		reportFile:   *reportFile,
+	}
+
+	err := run(opts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "cloudcms-import: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+type options struct {
+	repositoryId string
+	branchId     string
+	file         string
+	format       string
+	mapping      string
+	typeQName    string
+	key          string
+	batchSize    int
+	concurrency  int
+	dryRun       bool
+	reportFile   string
+}
+
+func run(opts *options) error {
+	if opts.repositoryId == "" {
+		return fmt.Errorf("-repository is required")
+	}
+	if opts.file == "" {
+		return fmt.Errorf("-file is required")
+	}
+
+	f, err := os.Open(opts.file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	format := opts.format
+	if format == "" {
+		format = strings.TrimPrefix(strings.ToLower(filepath.Ext(opts.file)), ".")
+	}
+
+	var source bulk.Source
+	switch format {
+	case "jsonl", "ndjson":
+		source = bulk.NewJSONLSource(f)
+	case "csv":
+		mapping, err := parseMapping(opts.mapping)
+		if err != nil {
+			return err
+		}
+		source, err = bulk.NewCSVSource(f, mapping)
+		if err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unknown format %q, expected jsonl or csv", format)
+	}
+
+	session, err := cloudcms.ConnectDefault()
+	if err != nil {
+		return err
+	}
+
+	importer := bulk.NewImporter(session, opts.repositoryId, opts.branchId)
+	importer.Type = opts.typeQName
+	importer.Key = opts.key
+	importer.BatchSize = opts.batchSize
+	importer.Concurrency = opts.concurrency
+	importer.DryRun = opts.dryRun
+	importer.Out = os.Stdout
+
+	report, err := importer.Import(source)
+	if report != nil && opts.reportFile != "" {
+		werr := writeReport(opts.reportFile, report)
+		if werr != nil && err == nil {
+			err = werr
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(report)
+	if failed := report.Count(bulk.Failed); failed > 0 {
+		return fmt.Errorf("%d records failed", failed)
+	}
+
+	return nil
+}
+
+// parseMapping reads "column=property,column=property:type" pairs
+func parseMapping(s string) (bulk.Mapping, error) {
+	if s == "" {
+		return nil, nil
+	}
+
+	mapping := bulk.Mapping{}
+	for _, pair := range strings.Split(s, ",") {
+		column, property, ok := strings.Cut(pair, "=")
+		if !ok || strings.TrimSpace(column) == "" || strings.TrimSpace(property) == "" {
+			return nil, fmt.Errorf("invalid mapping %q, expected column=property", pair)
+		}
+		mapping[strings.TrimSpace(column)] = strings.TrimSpace(property)
+	}
+
+	return mapping, nil
+}
+
+func writeReport(file string, report *bulk.Report) error {
+	f, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+
+	err = report.WriteCSV(f)
+	if err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
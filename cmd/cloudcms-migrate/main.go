@@ -0,0 +1,81 @@
+// Command cloudcms-migrate applies the JSON migrations in a directory to a branch.
+//
+//	cloudcms-migrate -repository <id> -branch master -dir migrations
+//	cloudcms-migrate -repository <id> -dir migrations -dry-run
+//	cloudcms-migrate -repository <id> -dir migrations -rollback-to 2
+//	cloudcms-migrate -repository <id> -status
+//
+// The session comes from cloudcms.ConnectDefault; see cloudcms.FindConfig for where it looks.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	cloudcms "github.com/gitana/cloudcms-go-driver"
+	"github.com/gitana/cloudcms-go-driver/migrate"
+)
+
+func main() {
+	repositoryId := flag.String("repository", "", "repository to migrate")
+	branchId := flag.String("branch", "master", "branch to migrate")
+	dir := flag.String("dir", "migrations", "directory of migration files")
+	dryRun := flag.Bool("dry-run", false, "log the steps without applying them")
+	rollbackTo := flag.Int("rollback-to", -1, "roll back applied migrations newer than this version")
+	status := flag.Bool("status", false, "list applied migrations and exit")
+	flag.Parse()
+
+	err := run(*repositoryId, *branchId, *dir, *dryRun, *rollbackTo, *status)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "cloudcms-migrate: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(repositoryId string, branchId string, dir string, dryRun bool, rollbackTo int, status bool) error {
+	if repositoryId == "" {
+		return fmt.Errorf("-repository is required")
+	}
+
+	session, err := cloudcms.ConnectDefault()
+	if err != nil {
+		return err
+	}
+
+	runner := migrate.NewRunner(session, repositoryId, branchId)
+	runner.DryRun = dryRun
+	runner.Out = os.Stdout
+
+	if status {
+		applied, err := runner.Applied()
+		if err != nil {
+			return err
+		}
+		for _, a := range applied {
+			fmt.Printf("%d %s\n", a.Version, a.Name)
+		}
+		return nil
+	}
+
+	migrations, err := migrate.LoadDir(dir)
+	if err != nil {
+		return err
+	}
+
+	var done []migrate.Migration
+	if rollbackTo >= 0 {
+		done, err = runner.Down(migrations, rollbackTo)
+	} else {
+		done, err = runner.Up(migrations)
+	}
+	if err != nil {
+		return err
+	}
+
+	if len(done) == 0 {
+		fmt.Println("nothing to do")
+	}
+
+	return nil
+}
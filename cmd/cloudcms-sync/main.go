@@ -0,0 +1,64 @@
+// Command cloudcms-sync pushes a directory of JSON and asset files to a folder on a branch,
+// or pulls the folder back into the directory.
+//
+//	cloudcms-sync -repository <id> -branch master -dir content -path /site
+//	cloudcms-sync -repository <id> -dir content -path /site -delete -dry-run
+//	cloudcms-sync -repository <id> -dir content -path /site -pull
+//
+// The session comes from cloudcms.ConnectDefault; see cloudcms.FindConfig for where it looks.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	cloudcms "github.com/gitana/cloudcms-go-driver"
+	"github.com/gitana/cloudcms-go-driver/fssync"
+)
+
+func main() {
+	repositoryId := flag.String("repository", "", "repository to sync with")
+	branchId := flag.String("branch", "master", "branch to sync with")
+	dir := flag.String("dir", ".", "local directory")
+	folderPath := flag.String("path", "/", "folder path on the branch")
+	pull := flag.Bool("pull", false, "write the folder to the directory instead of pushing")
+	deleteOrphans := flag.Bool("delete", false, "delete nodes or files that no longer exist on the other side")
+	dryRun := flag.Bool("dry-run", false, "log the changes without making them")
+	flag.Parse()
+
+	err := run(*repositoryId, *branchId, *dir, *folderPath, *pull, *deleteOrphans, *dryRun)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "cloudcms-sync: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(repositoryId string, branchId string, dir string, folderPath string, pull bool, deleteOrphans bool, dryRun bool) error {
+	if repositoryId == "" {
+		return fmt.Errorf("-repository is required")
+	}
+
+	session, err := cloudcms.ConnectDefault()
+	if err != nil {
+		return err
+	}
+
+	syncer := fssync.NewSyncer(session, repositoryId, branchId, dir, folderPath)
+	syncer.Delete = deleteOrphans
+	syncer.DryRun = dryRun
+	syncer.Out = os.Stdout
+
+	var changes []fssync.Change
+	if pull {
+		changes, err = syncer.Pull()
+	} else {
+		changes, err = syncer.Push()
+	}
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("%d changes\n", len(changes))
+	return nil
+}
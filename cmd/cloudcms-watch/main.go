@@ -0,0 +1,84 @@
+// Command cloudcms-watch polls a branch and prints its node changes as JSON lines until
+// interrupted.
+//
+//	cloudcms-watch -repository <id> -branch master -cursor .cloudcms-cursor.json
+//	cloudcms-watch -repository <id> -mode changeset -interval 30s
+//	cloudcms-watch -repository <id> -query '{"_type": "custom:article"}'
+//
+// The session comes from cloudcms.ConnectDefault; see cloudcms.FindConfig for where it looks.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"time"
+
+	cloudcms "github.com/gitana/cloudcms-go-driver"
+	"github.com/gitana/cloudcms-go-driver/watch"
+)
+
+func main() {
+	repositoryId := flag.String("repository", "", "repository to watch")
+	branchId := flag.String("branch", "master", "branch to watch")
+	mode := flag.String("mode", string(watch.ByModified), "modified or changeset (changeset also reports deletions)")
+	query := flag.String("query", "", "query narrowing the nodes watched in modified mode, as JSON")
+	interval := flag.Duration("interval", 10*time.Second, "time between polls")
+	cursor := flag.String("cursor", "", "file keeping the position between runs")
+	flag.Parse()
+
+	err := run(*repositoryId, *branchId, *mode, *query, *interval, *cursor)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "cloudcms-watch: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(repositoryId string, branchId string, mode string, query string, interval time.Duration, cursor string) error {
+	if repositoryId == "" {
+		return fmt.Errorf("-repository is required")
+	}
+	if mode != string(watch.ByModified) && mode != string(watch.ByChangeset) {
+		return fmt.Errorf("unknown mode %q, expected modified or changeset", mode)
+	}
+
+	session, err := cloudcms.ConnectDefault()
+	if err != nil {
+		return err
+	}
+
+	watcher := watch.NewWatcher(session, repositoryId, branchId, watch.Mode(mode))
+	watcher.Interval = interval
+	watcher.Out = os.Stderr
+	if query != "" {
+		err = json.Unmarshal([]byte(query), &watcher.Query)
+		if err != nil {
+			return fmt.Errorf("invalid -query: %v", err)
+		}
+	}
+	if cursor != "" {
+		watcher.Store = watch.NewFileStore(cursor)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	events := make(chan watch.Event)
+	errs := make(chan error, 1)
+	go func() { errs <- watcher.Watch(ctx, events) }()
+
+	encoder := json.NewEncoder(os.Stdout)
+	for event := range events {
+		encoder.Encode(map[string]interface{}{
+			"type":      event.Type,
+			"nodeId":    event.NodeId,
+			"changeset": event.Changeset,
+			"node":      event.Node,
+		})
+	}
+
+	return <-errs
+}
@@ -0,0 +1,75 @@
+package codegen
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	cloudcms "github.com/gitana/cloudcms-go-driver"
+	"github.com/gitana/cloudcms-go-driver/query"
+)
+
+const fetchPageSize = 100
+
+// LoadDefinitions reads definitions from a JSON export, either an array of definition
+// nodes or a query result object with a "rows" array.
+func LoadDefinitions(r io.Reader) ([]*cloudcms.Definition, error) {
+	var raw interface{}
+	err := json.NewDecoder(r).Decode(&raw)
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []interface{}
+	switch v := raw.(type) {
+	case []interface{}:
+		rows = v
+	case map[string]interface{}:
+		rows, _ = v["rows"].([]interface{})
+	}
+	if rows == nil {
+		return nil, fmt.Errorf("expected an array of definitions or an object with rows")
+	}
+
+	defs := make([]*cloudcms.Definition, 0, len(rows))
+	for i, row := range rows {
+		obj, ok := row.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("definition %d is not an object", i)
+		}
+
+		def, err := cloudcms.DefinitionFromJson(obj)
+		if err != nil {
+			return nil, fmt.Errorf("definition %d: %v", i, err)
+		}
+		defs = append(defs, def)
+	}
+
+	return defs, nil
+}
+
+// FetchDefinitions queries every d:type node on a branch.
+func FetchDefinitions(session *cloudcms.CloudCmsSession, repositoryId string, branchId string) ([]*cloudcms.Definition, error) {
+	q := query.New().Type(cloudcms.DefinitionType).Build()
+
+	defs := []*cloudcms.Definition{}
+	for skip := 0; ; skip += fetchPageSize {
+		pagination := query.Paginate().Limit(fetchPageSize).Skip(skip).SortAsc("_qname").Build()
+		res, err := session.QueryNodes(repositoryId, branchId, q, pagination)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, row := range res.Rows() {
+			def, err := cloudcms.DefinitionFromJson(row)
+			if err != nil {
+				return nil, err
+			}
+			defs = append(defs, def)
+		}
+
+		if res.Size() < fetchPageSize {
+			return defs, nil
+		}
+	}
+}
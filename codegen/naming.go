@@ -0,0 +1,53 @@
+package codegen
+
+import (
+	"strings"
+	"unicode"
+)
+
+// exportedName converts a property name or qname local part such as "first-name" or
+// "page_count" into an exported Go identifier like FirstName or PageCount.
+func exportedName(name string) string {
+	words := strings.FieldsFunc(name, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	var b strings.Builder
+	for _, word := range words {
+		runes := []rune(word)
+		runes[0] = unicode.ToUpper(runes[0])
+		b.WriteString(string(runes))
+	}
+
+	res := b.String()
+	if res == "" {
+		return "Field"
+	}
+	// digits and letters without case, e.g. in 名前, cannot start an exported name
+	if !unicode.IsUpper([]rune(res)[0]) {
+		res = "X" + res
+	}
+
+	return res
+}
+
+func qnameParts(qname string) (string, string) {
+	idx := strings.Index(qname, ":")
+	if idx < 0 {
+		return "", qname
+	}
+
+	return qname[:idx], qname[idx+1:]
+}
+
+func plural(name string) string {
+	lower := strings.ToLower(name)
+	switch {
+	case strings.HasSuffix(lower, "y") && len(lower) > 1 && !strings.ContainsRune("aeiou", rune(lower[len(lower)-2])):
+		return name[:len(name)-1] + "ies"
+	case strings.HasSuffix(lower, "s"), strings.HasSuffix(lower, "x"), strings.HasSuffix(lower, "ch"), strings.HasSuffix(lower, "sh"):
+		return name + "es"
+	}
+
+	return name + "s"
+}
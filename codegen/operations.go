@@ -0,0 +1,442 @@
+package codegen
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/format"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/gitana/cloudcms-go-driver/graphql"
+)
+
+// Source is a file of GraphQL operations and fragments. Fragments may be used by operations
+// in other sources.
+type Source struct {
+	Name string
+	Body string
+}
+
+// LoadSources reads .graphql files.
+func LoadSources(paths []string) ([]Source, error) {
+	sources := make([]Source, 0, len(paths))
+	for _, path := range paths {
+		body, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		sources = append(sources, Source{Name: path, Body: string(body)})
+	}
+
+	return sources, nil
+}
+
+// LoadSchema parses a schema in the GraphQL schema definition language.
+func LoadSchema(r io.Reader) (*graphql.Schema, error) {
+	sdl, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	return graphql.ParseSchema(string(sdl))
+}
+
+// scalarTypes maps built-in scalars to Go types. Custom scalars become interface{}.
+var scalarTypes = map[string]string{
+	"String":  "string",
+	"ID":      "string",
+	"Int":     "int64",
+	"Float":   "float64",
+	"Boolean": "bool",
+}
+
+// GenerateOperations emits, for every named operation in sources, a document constant,
+// variables and response structs and a function that runs the operation. Every operation
+// is validated against schema first, so generation fails if an operation no longer
+// matches it.
+func GenerateOperations(schema *graphql.Schema, sources []Source, opts Options) ([]byte, error) {
+	if opts.Package == "" {
+		return nil, fmt.Errorf("package name is required")
+	}
+	if opts.Generator == "" {
+		opts.Generator = "cloudcms-gen"
+	}
+
+	// sources are parsed as one document so fragments can be shared, and locations are
+	// mapped back to their file by line
+	var body strings.Builder
+	starts := make([]int, len(sources))
+	line := 1
+	for i, src := range sources {
+		starts[i] = line
+		body.WriteString(src.Body)
+		body.WriteString("\n")
+		line += strings.Count(src.Body, "\n") + 1
+	}
+	position := func(loc graphql.Location) string {
+		for i := len(sources) - 1; i >= 0; i-- {
+			if loc.Line >= starts[i] {
+				return fmt.Sprintf("%s:%d:%d", sources[i].Name, loc.Line-starts[i]+1, loc.Column)
+			}
+		}
+		return loc.String()
+	}
+
+	doc, err := graphql.ParseQuery(body.String())
+	if err != nil {
+		var syntaxErr *graphql.SyntaxError
+		if errors.As(err, &syntaxErr) {
+			return nil, fmt.Errorf("%s: %s", position(syntaxErr.Location), syntaxErr.Message)
+		}
+		return nil, err
+	}
+
+	err = schema.Validate(doc)
+	if err != nil {
+		var validationErrs graphql.ValidationErrors
+		if !errors.As(err, &validationErrs) {
+			return nil, err
+		}
+		msgs := make([]string, len(validationErrs))
+		for i, e := range validationErrs {
+			msgs[i] = fmt.Sprintf("%s: %s", position(e.Location), e.Message)
+		}
+		return nil, fmt.Errorf("operations do not match the schema:\n%s", strings.Join(msgs, "\n"))
+	}
+
+	g := &operationGen{
+		schema:   schema,
+		doc:      doc,
+		declared: map[string]bool{},
+		named:    map[string]string{},
+	}
+
+	fmt.Fprintf(&g.buf, "// Code generated by %s. DO NOT EDIT.\n\n", opts.Generator)
+	fmt.Fprintf(&g.buf, "package %s\n\n", opts.Package)
+	fmt.Fprintf(&g.buf, "import (\n\tcloudcms %q\n)\n\n", "github.com/gitana/cloudcms-go-driver")
+
+	for _, op := range doc.Operations {
+		if op.Name == "" {
+			return nil, fmt.Errorf("%s: operations must be named", position(op.Location))
+		}
+		if op.Type == "subscription" {
+			return nil, fmt.Errorf("%s: subscription %s is not supported", position(op.Location), op.Name)
+		}
+
+		err = g.operation(op)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", position(op.Location), err)
+		}
+	}
+
+	// enums and input objects are shared by all operations and emitted last
+	for i := 0; i < len(g.pending); i++ {
+		err = g.namedType(g.pending[i])
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	src, err := format.Source(g.buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("failed to format generated source: %v", err)
+	}
+
+	return src, nil
+}
+
+type operationGen struct {
+	schema   *graphql.Schema
+	doc      *graphql.Document
+	buf      bytes.Buffer
+	declared map[string]bool
+
+	// named maps enum and input object names to their Go types; pending are not emitted yet
+	named   map[string]string
+	pending []*graphql.TypeDefinition
+}
+
+func (g *operationGen) declare(name string) error {
+	if g.declared[name] {
+		return fmt.Errorf("duplicate type name: %s", name)
+	}
+	g.declared[name] = true
+
+	return nil
+}
+
+// selectedField is a response key with the selections of every field selected under it
+type selectedField struct {
+	key        string
+	definition *graphql.FieldDefinition
+	selections []graphql.Selection
+}
+
+type selectedObject struct {
+	name   string
+	fields []*selectedField
+}
+
+func (g *operationGen) operation(op *graphql.Operation) error {
+	name := exportedName(op.Name)
+	for _, n := range []string{name, name + "Document", name + "Variables", name + "Response"} {
+		if err := g.declare(n); err != nil {
+			return err
+		}
+	}
+
+	document := g.doc.Extract(op.Name).String()
+	fmt.Fprintf(&g.buf, "// %sDocument is the %s %s and the fragments it uses.\n", name, op.Name, op.Type)
+	if strings.Contains(document, "`") {
+		fmt.Fprintf(&g.buf, "const %sDocument = %q\n\n", name, document)
+	} else {
+		fmt.Fprintf(&g.buf, "const %sDocument = `%s`\n\n", name, document)
+	}
+
+	if len(op.Variables) > 0 {
+		fmt.Fprintf(&g.buf, "type %sVariables struct {\n", name)
+		seen := map[string]string{}
+		for _, v := range op.Variables {
+			field := exportedName(v.Name)
+			if other, ok := seen[field]; ok {
+				return fmt.Errorf("variables $%s and $%s both map to field %s", other, v.Name, field)
+			}
+			seen[field] = v.Name
+
+			tag := v.Name
+			if !v.Type.NonNull {
+				tag += ",omitempty"
+			}
+			fmt.Fprintf(&g.buf, "\t%s %s `json:%q`\n", field, g.inputType(v.Type), tag)
+		}
+		g.buf.WriteString("}\n\n")
+	}
+
+	root := g.schema.RootType(op.Type)
+	response := &selectedObject{name: name + "Response", fields: g.collect(root, op.SelectionSet, nil)}
+	nested, err := g.object(response, name)
+	if err != nil {
+		return err
+	}
+
+	g.function(name, op)
+
+	for len(nested) > 0 {
+		obj := nested[0]
+		nested = nested[1:]
+
+		if err := g.declare(obj.name); err != nil {
+			return err
+		}
+		more, err := g.object(obj, obj.name)
+		if err != nil {
+			return err
+		}
+		nested = append(nested, more...)
+	}
+
+	return nil
+}
+
+// collect merges the fields selected on parent, including those of fragments, by response key
+func (g *operationGen) collect(parent *graphql.TypeDefinition, selections []graphql.Selection, fields []*selectedField) []*selectedField {
+	for _, selection := range selections {
+		switch sel := selection.(type) {
+		case *graphql.Field:
+			var existing *selectedField
+			for _, f := range fields {
+				if f.key == sel.ResponseKey() {
+					existing = f
+				}
+			}
+			if existing == nil {
+				existing = &selectedField{key: sel.ResponseKey(), definition: parent.Field(sel.Name)}
+				if sel.Name == "__typename" {
+					existing.definition = &graphql.FieldDefinition{Name: sel.Name, Type: &graphql.TypeRef{Name: "String", NonNull: true}}
+				}
+				fields = append(fields, existing)
+			}
+			existing.selections = append(existing.selections, sel.SelectionSet...)
+
+		case *graphql.InlineFragment:
+			t := parent
+			if sel.TypeCondition != "" {
+				t = g.schema.Type(sel.TypeCondition)
+			}
+			fields = g.collect(t, sel.SelectionSet, fields)
+
+		case *graphql.FragmentSpread:
+			f := g.doc.Fragment(sel.Name)
+			fields = g.collect(g.schema.Type(f.TypeCondition), f.SelectionSet, fields)
+		}
+	}
+
+	return fields
+}
+
+func (g *operationGen) object(obj *selectedObject, prefix string) ([]*selectedObject, error) {
+	fmt.Fprintf(&g.buf, "type %s struct {\n", obj.name)
+
+	seen := map[string]string{}
+	nested := []*selectedObject{}
+	for _, f := range obj.fields {
+		field := exportedName(f.key)
+		if other, ok := seen[field]; ok {
+			return nil, fmt.Errorf("%s: fields %q and %q both map to %s", obj.name, other, f.key, field)
+		}
+		seen[field] = f.key
+		if f.definition == nil {
+			return nil, fmt.Errorf("%s: cannot generate a type for field %q", obj.name, f.key)
+		}
+
+		goType, n := g.outputType(prefix+field, f.definition.Type, f.selections)
+		if n != nil {
+			nested = append(nested, n)
+		}
+
+		if f.definition.Description != "" {
+			fmt.Fprintf(&g.buf, "\t// %s\n", strings.ReplaceAll(f.definition.Description, "\n", " "))
+		}
+		fmt.Fprintf(&g.buf, "\t%s %s `json:%q`\n", field, goType, f.key)
+	}
+	g.buf.WriteString("}\n\n")
+
+	return nested, nil
+}
+
+func (g *operationGen) outputType(name string, t *graphql.TypeRef, selections []graphql.Selection) (string, *selectedObject) {
+	if t.Elem != nil {
+		elem, nested := g.outputType(name, t.Elem, selections)
+		return "[]" + elem, nested
+	}
+
+	def := g.schema.Type(t.Name)
+	switch def.Kind {
+	case graphql.KindScalar:
+		return scalarType(def.Name), nil
+	case graphql.KindEnum:
+		return g.namedTypeName(def), nil
+	}
+
+	return "*" + name, &selectedObject{name: name, fields: g.collect(def, selections, nil)}
+}
+
+func (g *operationGen) inputType(t *graphql.TypeRef) string {
+	if t.Elem != nil {
+		return "[]" + g.inputType(t.Elem)
+	}
+
+	def := g.schema.Type(t.Name)
+	var goType string
+	switch def.Kind {
+	case graphql.KindScalar:
+		goType = scalarType(def.Name)
+	case graphql.KindInputObject:
+		return "*" + g.namedTypeName(def)
+	default:
+		goType = g.namedTypeName(def)
+	}
+
+	// a nullable value is a pointer, so 0, false and "" can be sent and nil leaves it out
+	if !t.NonNull && goType != "interface{}" {
+		return "*" + goType
+	}
+
+	return goType
+}
+
+func scalarType(name string) string {
+	if goType, ok := scalarTypes[name]; ok {
+		return goType
+	}
+
+	return "interface{}"
+}
+
+// namedTypeName returns the Go type of an enum or input object, queueing it to be emitted
+func (g *operationGen) namedTypeName(def *graphql.TypeDefinition) string {
+	if name, ok := g.named[def.Name]; ok {
+		return name
+	}
+
+	name := exportedName(def.Name)
+	g.named[def.Name] = name
+	g.pending = append(g.pending, def)
+
+	return name
+}
+
+func (g *operationGen) namedType(def *graphql.TypeDefinition) error {
+	name := g.named[def.Name]
+	if err := g.declare(name); err != nil {
+		return err
+	}
+
+	if def.Description != "" {
+		fmt.Fprintf(&g.buf, "// %s\n", strings.ReplaceAll(def.Description, "\n", "\n// "))
+	}
+
+	if def.Kind == graphql.KindEnum {
+		fmt.Fprintf(&g.buf, "type %s string\n\n", name)
+		g.buf.WriteString("const (\n")
+		for _, v := range def.EnumValues {
+			fmt.Fprintf(&g.buf, "\t%s%s %s = %q\n", name, exportedName(v.Name), name, v.Name)
+		}
+		g.buf.WriteString(")\n\n")
+		return nil
+	}
+
+	fmt.Fprintf(&g.buf, "type %s struct {\n", name)
+	for _, f := range def.InputFields {
+		tag := f.Name
+		if !f.Type.NonNull {
+			tag += ",omitempty"
+		}
+		fmt.Fprintf(&g.buf, "\t%s %s `json:%q`\n", exportedName(f.Name), g.inputType(f.Type), tag)
+	}
+	g.buf.WriteString("}\n\n")
+
+	return nil
+}
+
+func (g *operationGen) function(name string, op *graphql.Operation) {
+	params := ""
+	variables := "nil"
+	assign := ":="
+	if len(op.Variables) > 0 {
+		params = fmt.Sprintf(", variables *%sVariables", name)
+		variables = "vars"
+		assign = "="
+	}
+
+	fmt.Fprintf(&g.buf, "// %[1]s runs the %[2]s %[3]s. If the response has errors, the partial response is\n", name, op.Name, op.Type)
+	g.buf.WriteString("// returned together with cloudcms.GraphQLErrors.\n")
+	fmt.Fprintf(&g.buf, "func %s(session *cloudcms.CloudCmsSession, repositoryId string, branchId string%s) (*%sResponse, error) {\n", name, params, name)
+	if len(op.Variables) > 0 {
+		g.buf.WriteString(`	vars, err := cloudcms.ToJsonObject(variables)
+	if err != nil {
+		return nil, err
+	}
+
+`)
+	}
+	fmt.Fprintf(&g.buf, `	var res %[1]sResponse
+	err %[2]s session.GraphQL(repositoryId, branchId, &cloudcms.GraphQLRequest{
+		Query:         %[1]sDocument,
+		OperationName: %[3]q,
+		Variables:     %[4]s,
+	}, &res)
+	if err != nil {
+		if _, ok := err.(cloudcms.GraphQLErrors); ok {
+			return &res, err
+		}
+		return nil, err
+	}
+
+	return &res, nil
+}
+
+`, name, assign, op.Name, variables)
+}
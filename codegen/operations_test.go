@@ -0,0 +1,78 @@
+package codegen
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gitana/cloudcms-go-driver/graphql"
+)
+
+func loadTestSchema(t *testing.T) *graphql.Schema {
+	f, err := os.Open(filepath.Join("testdata", "schema.graphql"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	schema, err := LoadSchema(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return schema
+}
+
+func TestGenerateOperations(t *testing.T) {
+	schema := loadTestSchema(t)
+
+	sources, err := LoadSources([]string{
+		filepath.Join("testdata", "operations", "books.graphql"),
+		filepath.Join("testdata", "operations", "search.graphql"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	src, err := GenerateOperations(schema, sources, Options{Package: "queries"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	golden := filepath.Join("testdata", "operations.go.golden")
+	if *update {
+		err = os.WriteFile(golden, src, 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	expected, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(src, expected) {
+		t.Fatalf("generated source does not match %s:\n%s", golden, src)
+	}
+}
+
+func TestGenerateOperationsInvalid(t *testing.T) {
+	schema := loadTestSchema(t)
+
+	sources := []Source{
+		{Name: "a.graphql", Body: "query A {\n  custom_books { title }\n}"},
+		{Name: "b.graphql", Body: "query B {\n  custom_books {\n    summary\n  }\n}"},
+	}
+
+	_, err := GenerateOperations(schema, sources, Options{Package: "queries"})
+	if err == nil || !strings.Contains(err.Error(), `b.graphql:3:5: cannot query field "summary" on type custom_book`) {
+		t.Fatalf("expected validation error in b.graphql, got %v", err)
+	}
+
+	_, err = GenerateOperations(schema, []Source{{Name: "c.graphql", Body: "{ custom_books { title } }"}}, Options{Package: "queries"})
+	if err == nil || !strings.Contains(err.Error(), "c.graphql:1:1: operations must be named") {
+		t.Fatalf("expected unnamed operation error, got %v", err)
+	}
+}
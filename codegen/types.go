@@ -0,0 +1,321 @@
+// Package codegen generates Go source from Cloud CMS content models.
+package codegen
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"sort"
+	"strings"
+
+	cloudcms "github.com/gitana/cloudcms-go-driver"
+)
+
+type Options struct {
+	// Package is the package name of the generated file.
+	Package string
+
+	// Generator is named in the "Code generated" header.
+	Generator string
+}
+
+type typeGen struct {
+	buf      bytes.Buffer
+	structs  bytes.Buffer
+	declared map[string]bool
+}
+
+// reserved properties are emitted on every generated struct
+var reservedProperties = map[string]bool{
+	"_doc":   true,
+	"_type":  true,
+	"_qname": true,
+}
+
+// GenerateTypes emits structs, qname constants and typed session wrappers for every d:type
+// definition in defs.
+func GenerateTypes(defs []*cloudcms.Definition, opts Options) ([]byte, error) {
+	if opts.Package == "" {
+		return nil, fmt.Errorf("package name is required")
+	}
+	if opts.Generator == "" {
+		opts.Generator = "cloudcms-gen"
+	}
+
+	types := []*cloudcms.Definition{}
+	for _, def := range defs {
+		if def.TypeQName == "" || def.TypeQName == cloudcms.DefinitionType {
+			types = append(types, def)
+		}
+	}
+	sort.Slice(types, func(i, j int) bool {
+		return types[i].QName < types[j].QName
+	})
+
+	names, err := typeNames(types)
+	if err != nil {
+		return nil, err
+	}
+
+	g := &typeGen{declared: make(map[string]bool)}
+	for _, name := range names {
+		if g.declared[name] {
+			return nil, fmt.Errorf("duplicate type name: %s", name)
+		}
+		g.declared[name] = true
+	}
+
+	fmt.Fprintf(&g.buf, "// Code generated by %s. DO NOT EDIT.\n\n", opts.Generator)
+	fmt.Fprintf(&g.buf, "package %s\n\n", opts.Package)
+	fmt.Fprintf(&g.buf, "import (\n\tcloudcms %q\n)\n\n", "github.com/gitana/cloudcms-go-driver")
+
+	if len(types) > 0 {
+		g.buf.WriteString("const (\n")
+		for i, def := range types {
+			fmt.Fprintf(&g.buf, "\t%sQName = %q\n", names[i], def.QName)
+		}
+		g.buf.WriteString(")\n\n")
+	}
+
+	for i, def := range types {
+		if err := g.definition(names[i], def); err != nil {
+			return nil, err
+		}
+	}
+
+	g.buf.Write(g.structs.Bytes())
+
+	src, err := format.Source(g.buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("failed to format generated source: %v", err)
+	}
+
+	return src, nil
+}
+
+// typeNames derives a Go type name from the local part of each qname, falling back to
+// prefix and local part when two definitions share the same local name.
+func typeNames(defs []*cloudcms.Definition) ([]string, error) {
+	counts := map[string]int{}
+	for _, def := range defs {
+		if def.QName == "" {
+			return nil, fmt.Errorf("definition is missing a qname: %s", def.Id)
+		}
+		_, local := qnameParts(def.QName)
+		counts[exportedName(local)]++
+	}
+
+	names := make([]string, len(defs))
+	for i, def := range defs {
+		prefix, local := qnameParts(def.QName)
+		name := exportedName(local)
+		if counts[name] > 1 {
+			name = exportedName(prefix) + name
+		}
+		names[i] = name
+	}
+
+	return names, nil
+}
+
+func (g *typeGen) definition(name string, def *cloudcms.Definition) error {
+	description := def.Description
+	if description == "" {
+		description = def.Title
+	}
+
+	fmt.Fprintf(&g.structs, "// %s mirrors the %s definition.", name, def.QName)
+	if description != "" {
+		fmt.Fprintf(&g.structs, "\n// %s", strings.ReplaceAll(description, "\n", "\n// "))
+	}
+	fmt.Fprintf(&g.structs, "\ntype %s struct {\n", name)
+	g.structs.WriteString("\tId string `json:\"_doc,omitempty\"`\n")
+	g.structs.WriteString("\tType string `json:\"_type,omitempty\"`\n")
+	g.structs.WriteString("\tQName string `json:\"_qname,omitempty\"`\n")
+
+	nested, err := g.fields(name, def.Properties, true)
+	if err != nil {
+		return err
+	}
+	g.structs.WriteString("}\n\n")
+
+	g.wrappers(name)
+
+	for _, n := range nested {
+		if err := g.object(n.name, n.schema); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+type nestedType struct {
+	name   string
+	schema *cloudcms.Schema
+}
+
+func (g *typeGen) object(name string, schema *cloudcms.Schema) error {
+	if g.declared[name] {
+		return fmt.Errorf("duplicate type name: %s", name)
+	}
+	g.declared[name] = true
+
+	fmt.Fprintf(&g.structs, "type %s struct {\n", name)
+	nested, err := g.fields(name, schema.Properties, false)
+	if err != nil {
+		return err
+	}
+	g.structs.WriteString("}\n\n")
+
+	for _, n := range nested {
+		if err := g.object(n.name, n.schema); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// fields writes a field for each property. The fields of a definition's struct follow Id,
+// Type and QName, so properties with those names get a Field suffix, e.g. TypeField.
+func (g *typeGen) fields(parent string, properties map[string]*cloudcms.Schema, topLevel bool) ([]nestedType, error) {
+	keys := make([]string, 0, len(properties))
+	for key := range properties {
+		if !topLevel || !reservedProperties[key] {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	seen := map[string]string{}
+	nested := []nestedType{}
+	for _, key := range keys {
+		schema := properties[key]
+		field := exportedName(key)
+		if topLevel && (field == "Id" || field == "Type" || field == "QName") {
+			field += "Field"
+		}
+		if other, ok := seen[field]; ok {
+			return nil, fmt.Errorf("%s: properties %q and %q both map to field %s", parent, other, key, field)
+		}
+		seen[field] = key
+
+		goType, n := goType(parent+field, schema)
+		nested = append(nested, n...)
+
+		tag := key
+		if schema == nil || !schema.Required {
+			tag += ",omitempty"
+		}
+
+		if schema != nil && schema.Description != "" {
+			fmt.Fprintf(&g.structs, "\t// %s\n", strings.ReplaceAll(schema.Description, "\n", " "))
+		}
+		fmt.Fprintf(&g.structs, "\t%s %s `json:%q`\n", field, goType, tag)
+	}
+
+	return nested, nil
+}
+
+func goType(name string, schema *cloudcms.Schema) (string, []nestedType) {
+	if schema == nil {
+		return "interface{}", nil
+	}
+
+	switch schema.Type {
+	case "string":
+		return "string", nil
+	case "integer":
+		return "int64", nil
+	case "number":
+		return "float64", nil
+	case "boolean":
+		return "bool", nil
+	case "array":
+		itemType, nested := goType(name+"Item", schema.Items)
+		return "[]" + itemType, nested
+	case "object":
+		if len(schema.Properties) == 0 {
+			return "cloudcms.JsonObject", nil
+		}
+		return "*" + name, []nestedType{{name: name, schema: schema}}
+	}
+
+	return "interface{}", nil
+}
+
+func (g *typeGen) wrappers(name string) {
+	fmt.Fprintf(&g.structs, `func Read%[1]s(session *cloudcms.CloudCmsSession, repositoryId string, branchId string, nodeId string) (*%[1]s, error) {
+	obj, err := session.ReadNode(repositoryId, branchId, nodeId)
+	if err != nil {
+		return nil, err
+	}
+
+	var res %[1]s
+	err = cloudcms.DecodeJsonObject(obj, &res)
+	if err != nil {
+		return nil, err
+	}
+
+	return &res, nil
+}
+
+// Query%[2]s queries %[1]s nodes. The _type constraint is added to query.
+func Query%[2]s(session *cloudcms.CloudCmsSession, repositoryId string, branchId string, query cloudcms.JsonObject, pagination cloudcms.JsonObject) ([]*%[1]s, error) {
+	q := cloudcms.JsonObject{}
+	for key, val := range query {
+		q[key] = val
+	}
+	q["_type"] = %[1]sQName
+
+	res, err := session.QueryNodes(repositoryId, branchId, q, pagination)
+	if err != nil {
+		return nil, err
+	}
+
+	rows := make([]*%[1]s, 0, res.Size())
+	for _, obj := range res.Rows() {
+		var row %[1]s
+		err = cloudcms.DecodeJsonObject(obj, &row)
+		if err != nil {
+			return nil, err
+		}
+		rows = append(rows, &row)
+	}
+
+	return rows, nil
+}
+
+func Create%[1]s(session *cloudcms.CloudCmsSession, repositoryId string, branchId string, node *%[1]s) (string, error) {
+	node.Type = %[1]sQName
+
+	obj, err := cloudcms.ToJsonObject(node)
+	if err != nil {
+		return "", err
+	}
+
+	nodeId, err := session.CreateNode(repositoryId, branchId, obj, nil)
+	if err != nil {
+		return "", err
+	}
+
+	node.Id = nodeId
+	return nodeId, nil
+}
+
+// Update%[1]s replaces the stored node with node. Properties not modeled by %[1]s are dropped.
+func Update%[1]s(session *cloudcms.CloudCmsSession, repositoryId string, branchId string, node *%[1]s) error {
+	node.Type = %[1]sQName
+
+	obj, err := cloudcms.ToJsonObject(node)
+	if err != nil {
+		return err
+	}
+
+	_, err = session.UpdateNode(repositoryId, branchId, obj)
+	return err
+}
+
+`, name, plural(name))
+}
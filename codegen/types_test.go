@@ -0,0 +1,167 @@
+package codegen
+
+import (
+	"bytes"
+	"flag"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	cloudcms "github.com/gitana/cloudcms-go-driver"
+)
+
+var update = flag.Bool("update", false, "update golden files")
+
+func TestGenerateTypes(t *testing.T) {
+	f, err := os.Open(filepath.Join("testdata", "definitions.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	defs, err := LoadDefinitions(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	src, err := GenerateTypes(defs, Options{Package: "models"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	golden := filepath.Join("testdata", "models.go.golden")
+	if *update {
+		err = os.WriteFile(golden, src, 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	expected, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(src, expected) {
+		t.Fatalf("generated source does not match %s:\n%s", golden, src)
+	}
+}
+
+func TestGenerateTypesNameCollision(t *testing.T) {
+	defs := []*cloudcms.Definition{
+		{QName: "store:book", TypeQName: cloudcms.DefinitionType},
+		{QName: "library:book", TypeQName: cloudcms.DefinitionType},
+	}
+
+	src, err := GenerateTypes(defs, Options{Package: "models"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(src, []byte("type StoreBook struct")) || !bytes.Contains(src, []byte("type LibraryBook struct")) {
+		t.Fatalf("expected prefixed type names:\n%s", src)
+	}
+}
+
+func TestGenerateTypesFieldCollision(t *testing.T) {
+	defs := []*cloudcms.Definition{
+		{
+			QName:     "store:book",
+			TypeQName: cloudcms.DefinitionType,
+			Properties: map[string]*cloudcms.Schema{
+				"page_count": {Type: "integer"},
+				"pageCount":  {Type: "integer"},
+			},
+		},
+	}
+
+	_, err := GenerateTypes(defs, Options{Package: "models"})
+	if err == nil {
+		t.Fatal("expected field collision error")
+	}
+}
+
+func TestGenerateTypesReservedFields(t *testing.T) {
+	defs := []*cloudcms.Definition{
+		{
+			QName:     "store:book",
+			TypeQName: cloudcms.DefinitionType,
+			Properties: map[string]*cloudcms.Schema{
+				"type": {Type: "string"},
+				"id":   {Type: "string"},
+				"名前":   {Type: "string"},
+				"binding": {Type: "object", Properties: map[string]*cloudcms.Schema{
+					"type": {Type: "string"},
+				}},
+			},
+		},
+	}
+
+	src, err := GenerateTypes(defs, Options{Package: "models"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	book := structFields(t, src, "Book")
+	for field, tag := range map[string]string{
+		"Id":        `json:"_doc,omitempty"`,
+		"Type":      `json:"_type,omitempty"`,
+		"TypeField": `json:"type,omitempty"`,
+		"IdField":   `json:"id,omitempty"`,
+		"X名前":       `json:"名前,omitempty"`,
+	} {
+		if book[field] != tag {
+			t.Errorf("expected Book.%s with tag %s, got %q", field, tag, book[field])
+		}
+	}
+
+	binding := structFields(t, src, "BookBinding")
+	if len(binding) != 1 || binding["Type"] != `json:"type,omitempty"` {
+		t.Errorf("nested objects should not reserve Type, got %v", binding)
+	}
+}
+
+// structFields returns the fields of a generated struct with their tags
+func structFields(t *testing.T, src []byte, name string) map[string]string {
+	file, err := parser.ParseFile(token.NewFileSet(), "models.go", src, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fields := map[string]string{}
+	ast.Inspect(file, func(n ast.Node) bool {
+		spec, ok := n.(*ast.TypeSpec)
+		if !ok || spec.Name.Name != name {
+			return true
+		}
+		for _, f := range spec.Type.(*ast.StructType).Fields.List {
+			tag, _ := strconv.Unquote(f.Tag.Value)
+			fields[f.Names[0].Name] = tag
+		}
+		return false
+	})
+	if len(fields) == 0 {
+		t.Fatalf("no struct %s in:\n%s", name, src)
+	}
+
+	return fields
+}
+
+func TestExportedName(t *testing.T) {
+	cases := map[string]string{
+		"title":      "Title",
+		"page-count": "PageCount",
+		"page_count": "PageCount",
+		"inStock":    "InStock",
+		"3d":         "X3d",
+		"名前":         "X名前",
+	}
+
+	for in, expected := range cases {
+		if actual := exportedName(in); actual != expected {
+			t.Fatalf("exportedName(%q) = %q, expected %q", in, actual, expected)
+		}
+	}
+}
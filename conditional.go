@@ -0,0 +1,114 @@
+package cloudcms
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+)
+
+// ErrNotModified is returned when a request is answered 304 Not Modified where a body was
+// expected, e.g. because a middleware made it conditional. Only requests sent with an
+// If-None-Match or If-Modified-Since header get the 304 response itself.
+var ErrNotModified = errors.New("not modified")
+
+// Validators identify a version of a resource, as returned in the ETag and Last-Modified
+// headers.
+type Validators struct {
+	ETag         string
+	LastModified string
+}
+
+// IsZero reports whether the server returned no validators.
+func (v Validators) IsZero() bool {
+	return v.ETag == "" && v.LastModified == ""
+}
+
+// setHeader makes the request conditional on the resource having changed
+func (v Validators) setHeader(header http.Header) {
+	if v.ETag != "" {
+		header.Set("If-None-Match", v.ETag)
+	}
+	if v.LastModified != "" {
+		header.Set("If-Modified-Since", v.LastModified)
+	}
+}
+
+// isConditional reports whether a request header asks for a 304 when nothing changed
+func isConditional(header http.Header) bool {
+	return header.Get("If-None-Match") != "" || header.Get("If-Modified-Since") != ""
+}
+
+// ValidatorsOf returns the validators in a response header.
+func ValidatorsOf(header http.Header) Validators {
+	return Validators{
+		ETag:         header.Get("ETag"),
+		LastModified: header.Get("Last-Modified"),
+	}
+}
+
+// ConditionalResult is the response to a conditional request.
+type ConditionalResult struct {
+	// Object is the response body, nil if Unchanged.
+	Object JsonObject
+
+	// Unchanged is set if the server answered 304 Not Modified.
+	Unchanged bool
+
+	// Validators identify the version returned, or the one asked about if Unchanged. They
+	// are passed to the next request.
+	Validators Validators
+}
+
+// GetIfChanged sends a GET that only returns a body if the resource no longer matches
+// validators. Zero validators make an ordinary GET whose result carries the validators for
+// the next one. The response cache is not used.
+func (session *CloudCmsSession) GetIfChanged(uri string, params url.Values, validators Validators) (*ConditionalResult, error) {
+	session = session.named("GetIfChanged")
+	params = jsonParams(params)
+	if len(params) > 0 {
+		uri += "?" + params.Encode()
+	}
+
+	req, err := http.NewRequest("GET", session.config.BaseURL+uri, nil)
+	if err != nil {
+		return nil, err
+	}
+	validators.setHeader(req.Header)
+
+	resp, err := session.Request(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	res := &ConditionalResult{Validators: ValidatorsOf(resp.Header)}
+	if resp.StatusCode == http.StatusNotModified {
+		io.Copy(io.Discard, resp.Body)
+		res.Unchanged = true
+
+		// a 304 need not repeat the validators
+		if res.Validators.ETag == "" {
+			res.Validators.ETag = validators.ETag
+		}
+		if res.Validators.LastModified == "" {
+			res.Validators.LastModified = validators.LastModified
+		}
+		return res, nil
+	}
+
+	err = json.NewDecoder(resp.Body).Decode(&res.Object)
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// ReadNodeIfChanged reads a node unless it still matches validators, e.g. when polling.
+func (session *CloudCmsSession) ReadNodeIfChanged(repositoryId string, branchId string, nodeId string, validators Validators) (*ConditionalResult, error) {
+	session = session.named("ReadNodeIfChanged")
+	return session.GetIfChanged(fmt.Sprintf("/repositories/%s/branches/%s/nodes/%s", repositoryId, branchId, nodeId), nil, validators)
+}
@@ -0,0 +1,84 @@
+package cloudcms
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestReadNodeIfChanged(t *testing.T) {
+	version := 1
+	session := newTestSession(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		etag := fmt.Sprintf(`"v%d"`, version)
+		if r.URL.Path == "/always-304" || r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		if r.Header.Get("If-Modified-Since") != "" && r.Header.Get("If-None-Match") == "" {
+			t.Error("If-Modified-Since sent without the ETag")
+		}
+
+		w.Header().Set("ETag", etag)
+		w.Header().Set("Last-Modified", "Mon, 19 Oct 2026 10:00:00 GMT")
+		fmt.Fprintf(w, `{"_doc": "n1", "version": %d}`, version)
+	}))
+
+	res, err := session.ReadNodeIfChanged("r", "b", "n1", Validators{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Unchanged || res.Object.GetString("version") != "1" || res.Validators.ETag != `"v1"` {
+		t.Fatalf("unexpected first result %+v", res)
+	}
+
+	res, err = session.ReadNodeIfChanged("r", "b", "n1", res.Validators)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !res.Unchanged || res.Object != nil {
+		t.Fatalf("expected the node to be unchanged, got %+v", res)
+	}
+	if res.Validators.ETag != `"v1"` || res.Validators.LastModified != "Mon, 19 Oct 2026 10:00:00 GMT" {
+		t.Fatalf("validators should be kept when the 304 has none, got %+v", res.Validators)
+	}
+
+	version = 2
+	res, err = session.ReadNodeIfChanged("r", "b", "n1", res.Validators)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Unchanged || res.Object.GetString("version") != "2" || res.Validators.ETag != `"v2"` {
+		t.Fatalf("unexpected changed result %+v", res)
+	}
+
+	req, _ := http.NewRequest("GET", session.config.BaseURL+"/always-304", nil)
+	req.Header.Set("If-None-Match", `"v1"`)
+	resp, err := session.Request(req)
+	if err != nil || resp.StatusCode != http.StatusNotModified {
+		t.Fatalf("a 304 to a conditional request should not be an error, got %v", err)
+	}
+	resp.Body.Close()
+
+	req, _ = http.NewRequest("GET", session.config.BaseURL+"/always-304", nil)
+	_, err = session.Request(req)
+	if !errors.Is(err, ErrNotModified) {
+		t.Fatalf("expected ErrNotModified from Request, got %v", err)
+	}
+
+	_, err = session.Get("/always-304", nil)
+	if !errors.Is(err, ErrNotModified) {
+		t.Fatalf("expected ErrNotModified from Get, got %v", err)
+	}
+
+	_, err = session.Download("/always-304", nil)
+	if !errors.Is(err, ErrNotModified) {
+		t.Fatalf("expected ErrNotModified from Download, got %v", err)
+	}
+
+	_, err = session.MultipartPost("/always-304", nil, "multipart/form-data; boundary=x", strings.NewReader("--x--"))
+	if !errors.Is(err, ErrNotModified) {
+		t.Fatalf("expected ErrNotModified from MultipartPost, got %v", err)
+	}
+}
@@ -0,0 +1,468 @@
+package cloudcms
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Environment variables read by FindConfig. The settings variables override the config file.
+const (
+	EnvConfig                = "CLOUDCMS_CONFIG"
+	EnvProfile               = "CLOUDCMS_PROFILE"
+	EnvBaseURL               = "CLOUDCMS_BASE_URL"
+	EnvClientKey             = "CLOUDCMS_CLIENT_KEY"
+	EnvClientSecret          = "CLOUDCMS_CLIENT_SECRET"
+	EnvUsername              = "CLOUDCMS_USERNAME"
+	EnvPassword              = "CLOUDCMS_PASSWORD"
+	EnvDebug                 = "CLOUDCMS_DEBUG"
+	EnvRateLimit             = "CLOUDCMS_RATE_LIMIT"
+	EnvRateBurst             = "CLOUDCMS_RATE_BURST"
+	EnvMaxConcurrentRequests = "CLOUDCMS_MAX_CONCURRENT_REQUESTS"
+	EnvMaxRetries            = "CLOUDCMS_MAX_RETRIES"
+	EnvLogLevel              = "CLOUDCMS_LOG_LEVEL"
+	EnvCacheSize             = "CLOUDCMS_CACHE_SIZE"
+	EnvCacheTTL              = "CLOUDCMS_CACHE_TTL"
+)
+
+// configFileNames are looked for in the working directory, then in the cloudcms directory of
+// the user config dir
+var configFileNames = []string{
+	"gitana.json",
+	"gitana-test.json",
+	"cloudcms.json",
+	"cloudcms-test.json",
+	"cloudcms.yaml",
+	"cloudcms.yml",
+	"cloudcms.env",
+}
+
+type configField struct {
+	key string
+	env string
+	set func(config *CloudcmsConfig, value string) error
+}
+
+var configFields = []configField{
+	{"baseURL", EnvBaseURL, func(c *CloudcmsConfig, v string) error { c.BaseURL = v; return nil }},
+	{"clientKey", EnvClientKey, func(c *CloudcmsConfig, v string) error { c.Client_id = v; return nil }},
+	{"clientSecret", EnvClientSecret, func(c *CloudcmsConfig, v string) error { c.Client_secret = v; return nil }},
+	{"username", EnvUsername, func(c *CloudcmsConfig, v string) error { c.Username = v; return nil }},
+	{"password", EnvPassword, func(c *CloudcmsConfig, v string) error { c.Password = v; return nil }},
+	{"debug", EnvDebug, func(c *CloudcmsConfig, v string) (err error) { c.Debug, err = strconv.ParseBool(v); return }},
+	{"rateLimit", EnvRateLimit, func(c *CloudcmsConfig, v string) (err error) { c.RateLimit, err = strconv.ParseFloat(v, 64); return }},
+	{"rateBurst", EnvRateBurst, func(c *CloudcmsConfig, v string) (err error) { c.RateBurst, err = strconv.Atoi(v); return }},
+	{"maxConcurrentRequests", EnvMaxConcurrentRequests, func(c *CloudcmsConfig, v string) (err error) { c.MaxConcurrentRequests, err = strconv.Atoi(v); return }},
+	{"maxRetries", EnvMaxRetries, func(c *CloudcmsConfig, v string) (err error) { c.MaxRetries, err = strconv.Atoi(v); return }},
+	{"logLevel", EnvLogLevel, func(c *CloudcmsConfig, v string) error { c.LogLevel = v; return nil }},
+	{"cacheSize", EnvCacheSize, func(c *CloudcmsConfig, v string) (err error) { c.CacheSize, err = strconv.Atoi(v); return }},
+	{"cacheTTL", EnvCacheTTL, func(c *CloudcmsConfig, v string) (err error) { c.CacheTTL, err = strconv.ParseFloat(v, 64); return }},
+}
+
+// FindConfig loads the config of ConnectDefault:
+//
+//  1. The file named by $CLOUDCMS_CONFIG, which must exist, or else the first of
+//     gitana.json, gitana-test.json, cloudcms.json, cloudcms-test.json, cloudcms.yaml,
+//     cloudcms.yml and cloudcms.env found in the working directory, then in the cloudcms
+//     directory of the user config dir (e.g. ~/.config/cloudcms). No file is needed if the
+//     environment holds every setting.
+//  2. The profile named by $CLOUDCMS_PROFILE, if the file has profiles.
+//  3. The CLOUDCMS_* variables, e.g. CLOUDCMS_BASE_URL, which override the file unless
+//     empty.
+//
+// The result is validated, and the error says what is missing and where it was looked for.
+func FindConfig() (*CloudcmsConfig, error) {
+	path := os.Getenv(EnvConfig)
+	searched := []string{}
+	if path != "" {
+		_, err := os.Stat(path)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", EnvConfig, err)
+		}
+	} else {
+		dirs := []string{}
+		wd, err := os.Getwd()
+		if err == nil {
+			dirs = append(dirs, wd)
+		}
+		userDir, err := os.UserConfigDir()
+		if err == nil {
+			dirs = append(dirs, filepath.Join(userDir, "cloudcms"))
+		}
+
+		path, searched = findConfigFile(dirs)
+	}
+
+	config := &CloudcmsConfig{}
+	source := "environment"
+	if path != "" {
+		var err error
+		config, err = LoadConfigFile(path, os.Getenv(EnvProfile))
+		if err != nil {
+			return nil, err
+		}
+		source = path
+	}
+
+	set, err := applyConfigEnv(config)
+	if err != nil {
+		return nil, err
+	}
+	if path == "" && !set {
+		return nil, fmt.Errorf("no Cloud CMS config found: set %s, set %s and the other CLOUDCMS_* variables, or add one of %s (searched %s)",
+			EnvConfig, EnvBaseURL, strings.Join(configFileNames, ", "), strings.Join(searched, ", "))
+	}
+
+	err = config.Validate()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", source, err)
+	}
+
+	return config, nil
+}
+
+// findConfigFile returns the first config file in the directories, and the directories
+// searched
+func findConfigFile(dirs []string) (string, []string) {
+	for _, dir := range dirs {
+		for _, name := range configFileNames {
+			path := filepath.Join(dir, name)
+			info, err := os.Stat(path)
+			if err == nil && !info.IsDir() {
+				return path, dirs
+			}
+		}
+	}
+
+	return "", dirs
+}
+
+// LoadConfigFile reads a config file. JSON files use the gitana.json keys, YAML files the
+// same keys, and .env files the CLOUDCMS_* variable names.
+//
+// A JSON or YAML file may hold named profiles under "profiles", which override the settings
+// at the top of the file. The profile used is the one named, else the one named by
+// "defaultProfile", else the top of the file alone.
+func LoadConfigFile(path string, profile string) (*CloudcmsConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var values map[string]interface{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		values, err = parseYaml(data)
+	case ".env":
+		values, err = parseEnvFile(data)
+	default:
+		err = json.Unmarshal(data, &values)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+
+	config, err := configFromValues(values, profile)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+
+	return config, nil
+}
+
+func configFromValues(values map[string]interface{}, profile string) (*CloudcmsConfig, error) {
+	config := &CloudcmsConfig{}
+	err := applyConfigValues(config, values)
+	if err != nil {
+		return nil, err
+	}
+
+	profiles, _ := values["profiles"].(map[string]interface{})
+	if profile == "" {
+		profile, _ = values["defaultProfile"].(string)
+	}
+	if profile == "" {
+		return config, nil
+	}
+
+	obj, ok := profiles[profile].(map[string]interface{})
+	if !ok {
+		names := make([]string, 0, len(profiles))
+		for name := range profiles {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("profile %q not found (profiles: %s)", profile, strings.Join(names, ", "))
+	}
+
+	err = applyConfigValues(config, obj)
+	if err != nil {
+		return nil, fmt.Errorf("profile %s: %v", profile, err)
+	}
+
+	return config, nil
+}
+
+// applyConfigValues sets the known keys, matched regardless of case (e.g. baseUrl or
+// clientkey), and ignores the others
+func applyConfigValues(config *CloudcmsConfig, values map[string]interface{}) error {
+	for _, field := range configFields {
+		val := lookupKey(values, field.key)
+		if val == nil {
+			continue
+		}
+
+		var s string
+		switch v := val.(type) {
+		case string:
+			s = v
+		case float64:
+			s = strconv.FormatFloat(v, 'f', -1, 64)
+		case bool:
+			s = strconv.FormatBool(v)
+		default:
+			return fmt.Errorf("%s must be a string, number or boolean", field.key)
+		}
+
+		err := field.set(config, s)
+		if err != nil {
+			return fmt.Errorf("invalid %s %q", field.key, s)
+		}
+	}
+
+	return nil
+}
+
+// lookupKey returns the value of key, or of a key differing only in case
+func lookupKey(values map[string]interface{}, key string) interface{} {
+	if val, ok := values[key]; ok {
+		return val
+	}
+	for name, val := range values {
+		if strings.EqualFold(name, key) {
+			return val
+		}
+	}
+
+	return nil
+}
+
+// applyConfigEnv sets the settings found in the environment, and reports whether there
+// were any. An empty variable is unset, so e.g. CLOUDCMS_DEBUG= keeps the file's value.
+func applyConfigEnv(config *CloudcmsConfig) (bool, error) {
+	set := false
+	for _, field := range configFields {
+		val := os.Getenv(field.env)
+		if val == "" {
+			continue
+		}
+
+		err := field.set(config, val)
+		if err != nil {
+			return set, fmt.Errorf("invalid %s %q", field.env, val)
+		}
+		set = true
+	}
+
+	return set, nil
+}
+
+// Validate reports every missing or invalid setting.
+func (config *CloudcmsConfig) Validate() error {
+	errs := []error{}
+	missing := func(key string, env string) {
+		errs = append(errs, fmt.Errorf("missing %s (or %s)", key, env))
+	}
+
+	if config.BaseURL == "" {
+		missing("baseURL", EnvBaseURL)
+	} else {
+		u, err := url.Parse(config.BaseURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			errs = append(errs, fmt.Errorf("baseURL %q is not an http or https URL", config.BaseURL))
+		}
+	}
+	if config.Client_id == "" {
+		missing("clientKey", EnvClientKey)
+	}
+	if config.Client_secret == "" {
+		missing("clientSecret", EnvClientSecret)
+	}
+	if config.Username == "" {
+		missing("username", EnvUsername)
+	}
+	if config.Password == "" {
+		missing("password", EnvPassword)
+	}
+	if config.RateLimit < 0 {
+		errs = append(errs, fmt.Errorf("rateLimit must not be negative"))
+	}
+	if config.RateBurst < 0 {
+		errs = append(errs, fmt.Errorf("rateBurst must not be negative"))
+	}
+	if config.MaxConcurrentRequests < 0 {
+		errs = append(errs, fmt.Errorf("maxConcurrentRequests must not be negative"))
+	}
+	if config.CacheSize < 0 {
+		errs = append(errs, fmt.Errorf("cacheSize must not be negative"))
+	}
+	if config.CacheTTL < 0 {
+		errs = append(errs, fmt.Errorf("cacheTTL must not be negative"))
+	}
+
+	return errors.Join(errs...)
+}
+
+// parseEnvFile reads KEY=VALUE lines named after the CLOUDCMS_* variables
+func parseEnvFile(data []byte) (map[string]interface{}, error) {
+	keys := map[string]string{}
+	for _, field := range configFields {
+		keys[field.env] = field.key
+	}
+
+	values := map[string]interface{}{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		name, val, ok := strings.Cut(strings.TrimPrefix(text, "export "), "=")
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected NAME=value", line)
+		}
+		if key, ok := keys[strings.TrimSpace(name)]; ok {
+			values[key] = unquote(strings.TrimSpace(val))
+		}
+	}
+
+	return values, scanner.Err()
+}
+
+// parseYaml reads the YAML used by config files: nested mappings of scalars, with # comments.
+// Scalars are kept as strings.
+func parseYaml(data []byte) (map[string]interface{}, error) {
+	type level struct {
+		indent int
+		values map[string]interface{}
+	}
+
+	root := map[string]interface{}{}
+	stack := []level{{indent: -1, values: root}}
+	pending := ""
+	pendingIndent := 0
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for line := 1; scanner.Scan(); line++ {
+		text := scanner.Text()
+		trimmed := strings.TrimSpace(text)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") || trimmed == "---" {
+			continue
+		}
+		if strings.Contains(text, "\t") {
+			return nil, fmt.Errorf("line %d: tabs are not allowed in YAML indentation", line)
+		}
+		indent := len(text) - len(strings.TrimLeft(text, " "))
+
+		// a key without a value opens a mapping if the next line is indented further
+		if pending != "" {
+			if indent <= pendingIndent {
+				return nil, fmt.Errorf("line %d: %s has no value", line-1, pending)
+			}
+			child := map[string]interface{}{}
+			stack[len(stack)-1].values[pending] = child
+			stack = append(stack, level{indent: indent, values: child})
+			pending = ""
+		}
+
+		for indent < stack[len(stack)-1].indent {
+			stack = stack[:len(stack)-1]
+		}
+		if indent != stack[len(stack)-1].indent && len(stack) > 1 {
+			return nil, fmt.Errorf("line %d: unexpected indentation", line)
+		}
+		if len(stack) == 1 {
+			stack[0].indent = indent
+		}
+
+		key, val, ok := strings.Cut(trimmed, ":")
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected key: value", line)
+		}
+		key = unquote(strings.TrimSpace(key))
+		val = strings.TrimSpace(val)
+		if val == "" {
+			pending = key
+			pendingIndent = indent
+			continue
+		}
+		if !strings.HasPrefix(val, `"`) && !strings.HasPrefix(val, "'") {
+			if i := strings.Index(val, " #"); i >= 0 {
+				val = strings.TrimSpace(val[:i])
+			}
+		}
+		stack[len(stack)-1].values[key] = unquote(val)
+	}
+	if pending != "" {
+		stack[len(stack)-1].values[pending] = nil
+	}
+
+	return root, scanner.Err()
+}
+
+func unquote(s string) string {
+	if len(s) >= 2 && (s[0] == '"' || s[0] == '\'') && s[len(s)-1] == s[0] {
+		if s[0] == '"' {
+			u, err := strconv.Unquote(s)
+			if err == nil {
+				return u
+			}
+		}
+		return s[1 : len(s)-1]
+	}
+
+	return s
+}
+
+// LoadConfig returns the config of FindConfig, or nil.
+//
+// Deprecated: use FindConfig, which says why no config was found.
+func LoadConfig() *CloudcmsConfig {
+	config, err := FindConfig()
+	if err != nil {
+		return nil
+	}
+
+	return config
+}
+
+// ReadConfig returns the config in a file, or nil.
+//
+// Deprecated: use LoadConfigFile, which says why the file could not be read.
+func ReadConfig(path string) *CloudcmsConfig {
+	config, err := LoadConfigFile(path, "")
+	if err != nil {
+		return nil
+	}
+
+	return config
+}
+
+// ConnectDefault connects with the config of FindConfig.
+func ConnectDefault() (*CloudCmsSession, error) {
+	config, err := FindConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	return Connect(config)
+}
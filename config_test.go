@@ -0,0 +1,205 @@
+package cloudcms
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// isolateConfig clears the CLOUDCMS_* variables and moves to an empty working directory and
+// user config dir
+func isolateConfig(t *testing.T) string {
+	for _, name := range []string{EnvConfig, EnvProfile} {
+		t.Setenv(name, "")
+		os.Unsetenv(name)
+	}
+	for _, field := range configFields {
+		t.Setenv(field.env, "")
+		os.Unsetenv(field.env)
+	}
+
+	dir := t.TempDir()
+	t.Setenv("HOME", filepath.Join(dir, "home"))
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(dir, "config"))
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	work := filepath.Join(dir, "work")
+	os.MkdirAll(work, 0755)
+	err = os.Chdir(work)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+
+	return dir
+}
+
+func writeConfig(t *testing.T, path string, data string) {
+	err := os.MkdirAll(filepath.Dir(path), 0755)
+	if err == nil {
+		err = os.WriteFile(path, []byte(data), 0600)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+const profilesJson = `{
+	"clientKey": "key",
+	"clientSecret": "secret",
+	"username": "user",
+	"password": "pass",
+	"baseURL": "https://api.cloudcms.com",
+	"application": "ignored",
+	"defaultProfile": "dev",
+	"profiles": {
+		"dev": {"baseURL": "http://localhost:8080", "debug": true},
+		"prod": {"clientKey": "prod-key", "rateLimit": 10}
+	}
+}`
+
+func TestFindConfigProfiles(t *testing.T) {
+	dir := isolateConfig(t)
+	writeConfig(t, filepath.Join(dir, "work", "gitana.json"), profilesJson)
+
+	config, err := FindConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.BaseURL != "http://localhost:8080" || !config.Debug || config.Client_id != "key" {
+		t.Fatalf("expected the default profile, got %+v", config)
+	}
+
+	t.Setenv(EnvProfile, "prod")
+	t.Setenv(EnvPassword, "from-env")
+	config, err = FindConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.BaseURL != "https://api.cloudcms.com" || config.Client_id != "prod-key" || config.RateLimit != 10 || config.Password != "from-env" {
+		t.Fatalf("unexpected prod config %+v", config)
+	}
+
+	t.Setenv(EnvProfile, "staging")
+	_, err = FindConfig()
+	if err == nil || !strings.Contains(err.Error(), `profile "staging" not found (profiles: dev, prod)`) {
+		t.Fatalf("expected a missing profile error, got %v", err)
+	}
+}
+
+func TestFindConfigSources(t *testing.T) {
+	dir := isolateConfig(t)
+
+	_, err := FindConfig()
+	if err == nil || !strings.Contains(err.Error(), "no Cloud CMS config found") || !strings.Contains(err.Error(), filepath.Join(dir, "work")) {
+		t.Fatalf("expected a not found error naming the directories, got %v", err)
+	}
+
+	// the user config dir
+	writeConfig(t, filepath.Join(dir, "config", "cloudcms", "cloudcms.yaml"), `
+# shared settings
+baseUrl: https://api.cloudcms.com
+clientkey: "key"
+clientSecret: 'secret'
+username: user # inline comment
+password: "p#ss"
+`)
+	config, err := FindConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.BaseURL != "https://api.cloudcms.com" || config.Client_id != "key" || config.Client_secret != "secret" || config.Username != "user" || config.Password != "p#ss" {
+		t.Fatalf("unexpected config %+v", config)
+	}
+
+	// $CLOUDCMS_CONFIG wins over the search
+	envFile := filepath.Join(dir, "ci.env")
+	writeConfig(t, envFile, "CLOUDCMS_BASE_URL=https://ci.example.com\nexport CLOUDCMS_CLIENT_KEY=ci\nCLOUDCMS_CLIENT_SECRET=\"s\"\nCLOUDCMS_USERNAME=u\nCLOUDCMS_PASSWORD=p\nCLOUDCMS_MAX_RETRIES=5\n")
+	t.Setenv(EnvConfig, envFile)
+	config, err = FindConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.BaseURL != "https://ci.example.com" || config.Client_id != "ci" || config.MaxRetries != 5 {
+		t.Fatalf("unexpected config %+v", config)
+	}
+
+	t.Setenv(EnvConfig, filepath.Join(dir, "missing.json"))
+	_, err = FindConfig()
+	if err == nil || !strings.Contains(err.Error(), EnvConfig) {
+		t.Fatalf("expected an error for a missing %s, got %v", EnvConfig, err)
+	}
+}
+
+func TestFindConfigEnvironment(t *testing.T) {
+	isolateConfig(t)
+	t.Setenv(EnvBaseURL, "ftp://example.com")
+	t.Setenv(EnvClientKey, "key")
+
+	_, err := FindConfig()
+	if err == nil {
+		t.Fatal("expected a validation error")
+	}
+	for _, message := range []string{
+		"environment: ",
+		`baseURL "ftp://example.com" is not an http or https URL`,
+		"missing clientSecret (or CLOUDCMS_CLIENT_SECRET)",
+		"missing password (or CLOUDCMS_PASSWORD)",
+	} {
+		if !strings.Contains(err.Error(), message) {
+			t.Errorf("expected %q in %v", message, err)
+		}
+	}
+
+	t.Setenv(EnvBaseURL, "https://api.cloudcms.com")
+	t.Setenv(EnvClientSecret, "secret")
+	t.Setenv(EnvUsername, "user")
+	t.Setenv(EnvPassword, "pass")
+	t.Setenv(EnvMaxConcurrentRequests, "four")
+	_, err = FindConfig()
+	if err == nil || !strings.Contains(err.Error(), `invalid CLOUDCMS_MAX_CONCURRENT_REQUESTS "four"`) {
+		t.Fatalf("expected an invalid value error, got %v", err)
+	}
+
+	t.Setenv(EnvMaxConcurrentRequests, "4")
+	t.Setenv(EnvDebug, "")
+	config, err := FindConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.MaxConcurrentRequests != 4 || config.Password != "pass" || config.Debug {
+		t.Fatalf("unexpected config %+v", config)
+	}
+}
+
+func TestParseYamlProfiles(t *testing.T) {
+	values, err := parseYaml([]byte(`
+username: user
+profiles:
+  dev:
+    baseURL: http://localhost:8080
+    debug: true
+  prod:
+    baseURL: https://api.cloudcms.com
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config, err := configFromValues(values, "dev")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.Username != "user" || config.BaseURL != "http://localhost:8080" || !config.Debug {
+		t.Fatalf("unexpected config %+v", config)
+	}
+
+	_, err = parseYaml([]byte("profiles:\n  dev:\n    baseURL: x\n   debug: true\n"))
+	if err == nil {
+		t.Fatal("expected an indentation error")
+	}
+}
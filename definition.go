@@ -0,0 +1,324 @@
+package cloudcms
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+)
+
+const (
+	DefinitionType        = "d:type"
+	DefinitionAssociation = "d:association"
+	DefinitionFeature     = "d:feature"
+
+	FormType           = "n:form"
+	HasFormAssociation = "a:has_form"
+
+	formKeyProperty = "form-key"
+)
+
+// Schema is a JSON schema property as used by Cloud CMS definitions.
+type Schema struct {
+	Type        string             `json:"type,omitempty"`
+	Title       string             `json:"title,omitempty"`
+	Description string             `json:"description,omitempty"`
+	Required    bool               `json:"required,omitempty"`
+	ReadOnly    bool               `json:"readonly,omitempty"`
+	Default     interface{}        `json:"default,omitempty"`
+	Enum        []interface{}      `json:"enum,omitempty"`
+	Format      string             `json:"format,omitempty"`
+	Pattern     string             `json:"pattern,omitempty"`
+	MinLength   *int               `json:"minLength,omitempty"`
+	MaxLength   *int               `json:"maxLength,omitempty"`
+	Minimum     *float64           `json:"minimum,omitempty"`
+	Maximum     *float64           `json:"maximum,omitempty"`
+	MinItems    *int               `json:"minItems,omitempty"`
+	MaxItems    *int               `json:"maxItems,omitempty"`
+	Items       *Schema            `json:"items,omitempty"`
+	Properties  map[string]*Schema `json:"properties,omitempty"`
+
+	// Extra holds the keys not modeled above, e.g. "uniqueItems" or "_relator", so that they
+	// are written back unchanged.
+	Extra JsonObject `json:"-"`
+}
+
+// schemaFields is Schema without its JSON methods
+type schemaFields Schema
+
+var schemaKeys = map[string]bool{
+	"type": true, "title": true, "description": true, "required": true, "readonly": true,
+	"default": true, "enum": true, "format": true, "pattern": true, "minLength": true,
+	"maxLength": true, "minimum": true, "maximum": true, "minItems": true, "maxItems": true,
+	"items": true, "properties": true,
+}
+
+func (s *Schema) UnmarshalJSON(data []byte) error {
+	var fields schemaFields
+	err := json.Unmarshal(data, &fields)
+	if err != nil {
+		return err
+	}
+
+	var all JsonObject
+	err = json.Unmarshal(data, &all)
+	if err != nil {
+		return err
+	}
+
+	*s = Schema(fields)
+	for key, val := range all {
+		if schemaKeys[key] {
+			continue
+		}
+		if s.Extra == nil {
+			s.Extra = JsonObject{}
+		}
+		s.Extra[key] = val
+	}
+
+	return nil
+}
+
+func (s Schema) MarshalJSON() ([]byte, error) {
+	data, err := json.Marshal(schemaFields(s))
+	if err != nil || len(s.Extra) == 0 {
+		return data, err
+	}
+
+	obj := JsonObject{}
+	err = json.Unmarshal(data, &obj)
+	if err != nil {
+		return nil, err
+	}
+	for key, val := range s.Extra {
+		if !schemaKeys[key] {
+			obj[key] = val
+		}
+	}
+
+	return json.Marshal(obj)
+}
+
+// Definition is a content type, association type or feature definition node.
+type Definition struct {
+	Id                string                `json:"_doc,omitempty"`
+	QName             string                `json:"_qname"`
+	TypeQName         string                `json:"_type"`
+	Parent            string                `json:"_parent,omitempty"`
+	Title             string                `json:"title,omitempty"`
+	Description       string                `json:"description,omitempty"`
+	Type              string                `json:"type,omitempty"`
+	Properties        map[string]*Schema    `json:"properties,omitempty"`
+	MandatoryFeatures map[string]JsonObject `json:"mandatoryFeatures,omitempty"`
+
+	// the node as read from the server, so that updates keep properties not modeled above
+	raw JsonObject
+}
+
+type FormAssociation struct {
+	Key    string
+	FormId string
+}
+
+var definitionKeys = []string{"_doc", "_qname", "_type", "_parent", "title", "description", "type", "properties", "mandatoryFeatures"}
+
+func DefinitionFromJson(obj JsonObject) (*Definition, error) {
+	var def Definition
+	err := DecodeJsonObject(obj, &def)
+	if err != nil {
+		return nil, err
+	}
+
+	def.raw = obj
+	return &def, nil
+}
+
+func (def *Definition) ToJson() (JsonObject, error) {
+	obj, err := ToJsonObject(def)
+	if err != nil {
+		return nil, err
+	}
+
+	res := JsonObject{}
+	for key, val := range def.raw {
+		res[key] = val
+	}
+	for _, key := range definitionKeys {
+		delete(res, key)
+	}
+	for key, val := range obj {
+		res[key] = val
+	}
+
+	return res, nil
+}
+
+// ListDefinitions lists the definitions on a branch. Filter may be "type", "association" or "feature",
+// or empty to list all of them.
+func (session *CloudCmsSession) ListDefinitions(repositoryId string, branchId string, filter string) ([]*Definition, error) {
+	session = session.named("ListDefinitions")
+	params := url.Values{}
+	if filter != "" {
+		params.Add("filter", filter)
+	}
+
+	res, err := session.Get(fmt.Sprintf("/repositories/%s/branches/%s/definitions", repositoryId, branchId), params)
+	if err != nil {
+		return nil, err
+	}
+
+	rows := res.GetObjectArray("rows")
+	defs := make([]*Definition, 0, len(rows))
+	for _, row := range rows {
+		def, err := DefinitionFromJson(row)
+		if err != nil {
+			return nil, err
+		}
+		defs = append(defs, def)
+	}
+
+	return defs, nil
+}
+
+func (session *CloudCmsSession) ReadDefinition(repositoryId string, branchId string, qname string) (*Definition, error) {
+	session = session.named("ReadDefinition")
+	res, err := session.Get(fmt.Sprintf("/repositories/%s/branches/%s/definitions/%s", repositoryId, branchId, qname), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return DefinitionFromJson(res)
+}
+
+func (session *CloudCmsSession) CreateDefinition(repositoryId string, branchId string, def *Definition) (string, error) {
+	session = session.named("CreateDefinition")
+	if def.TypeQName == "" {
+		def.TypeQName = DefinitionType
+	}
+	if def.Type == "" {
+		def.Type = "object"
+	}
+
+	obj, err := def.ToJson()
+	if err != nil {
+		return "", err
+	}
+
+	nodeId, err := session.CreateNode(repositoryId, branchId, obj, nil)
+	if err != nil {
+		return "", err
+	}
+
+	def.Id = nodeId
+	return nodeId, nil
+}
+
+func (session *CloudCmsSession) UpdateDefinition(repositoryId string, branchId string, def *Definition) (*Definition, error) {
+	session = session.named("UpdateDefinition")
+	if def.Id == "" {
+		return nil, fmt.Errorf("failed to determine definition ID: %s", def.QName)
+	}
+
+	obj, err := def.ToJson()
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := session.UpdateNode(repositoryId, branchId, obj)
+	if err != nil {
+		return nil, err
+	}
+
+	return DefinitionFromJson(res)
+}
+
+func (session *CloudCmsSession) DeleteDefinition(repositoryId string, branchId string, def *Definition) error {
+	session = session.named("DeleteDefinition")
+	if def.Id == "" {
+		return fmt.Errorf("failed to determine definition ID: %s", def.QName)
+	}
+
+	return session.DeleteNode(repositoryId, branchId, def.Id)
+}
+
+func (session *CloudCmsSession) ListForms(repositoryId string, branchId string, definitionQName string) ([]FormAssociation, error) {
+	session = session.named("ListForms")
+	def, err := session.ReadDefinition(repositoryId, branchId, definitionQName)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := session.ListOutgoingAssociations(repositoryId, branchId, def.Id, HasFormAssociation, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	forms := make([]FormAssociation, 0, len(res.rows))
+	for _, association := range res.rows {
+		forms = append(forms, FormAssociation{
+			Key:    association.GetString(formKeyProperty),
+			FormId: association.GetString("target"),
+		})
+	}
+
+	return forms, nil
+}
+
+func (session *CloudCmsSession) ReadForm(repositoryId string, branchId string, definitionQName string, formKey string) (JsonObject, error) {
+	session = session.named("ReadForm")
+	forms, err := session.ListForms(repositoryId, branchId, definitionQName)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, form := range forms {
+		if form.Key == formKey {
+			return session.ReadNode(repositoryId, branchId, form.FormId)
+		}
+	}
+
+	return nil, nil
+}
+
+// CreateForm creates a form node and binds it to the definition under formKey.
+func (session *CloudCmsSession) CreateForm(repositoryId string, branchId string, definitionQName string, formKey string, form JsonObject) (string, error) {
+	session = session.named("CreateForm")
+	def, err := session.ReadDefinition(repositoryId, branchId, definitionQName)
+	if err != nil {
+		return "", err
+	}
+
+	obj := JsonObject{}
+	for key, val := range form {
+		obj[key] = val
+	}
+	obj["_type"] = FormType
+
+	formId, err := session.CreateNode(repositoryId, branchId, obj, nil)
+	if err != nil {
+		return "", err
+	}
+
+	_, err = session.Associate(repositoryId, branchId, def.Id, formId, HasFormAssociation, "", JsonObject{formKeyProperty: formKey})
+	if err != nil {
+		return "", err
+	}
+
+	return formId, nil
+}
+
+func (session *CloudCmsSession) DeleteForm(repositoryId string, branchId string, definitionQName string, formKey string) error {
+	session = session.named("DeleteForm")
+	forms, err := session.ListForms(repositoryId, branchId, definitionQName)
+	if err != nil {
+		return err
+	}
+
+	for _, form := range forms {
+		if form.Key == formKey {
+			return session.DeleteNode(repositoryId, branchId, form.FormId)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,200 @@
+package cloudcms
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+func TestDefinitionToJson(t *testing.T) {
+	def, err := DefinitionFromJson(JsonObject{
+		"_doc":        "abc",
+		"_qname":      "custom:book",
+		"_type":       "d:type",
+		"type":        "object",
+		"description": "old description",
+		"properties": map[string]interface{}{
+			"title": map[string]interface{}{"type": "string", "required": true},
+		},
+		"_system": map[string]interface{}{"changeset": "1:1"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !def.Properties["title"].Required {
+		t.Fatal("failed to decode property schema")
+	}
+
+	def.Description = ""
+	def.Properties["author"] = &Schema{Type: "string"}
+
+	obj, err := def.ToJson()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if obj["description"] != nil {
+		t.Fatal("cleared description should be removed")
+	}
+	if obj.GetObject("_system") == nil {
+		t.Fatal("unmodeled properties should be kept")
+	}
+	properties := obj.GetObject("properties")
+	if properties.GetObject("author") == nil {
+		t.Fatal("new property missing")
+	}
+}
+
+func TestSchemaKeepsUnmodeledKeys(t *testing.T) {
+	def, err := DefinitionFromJson(JsonObject{
+		"_qname": "custom:book",
+		"_type":  "d:type",
+		"properties": map[string]interface{}{
+			"tags": map[string]interface{}{
+				"type":        "array",
+				"uniqueItems": true,
+				"items":       map[string]interface{}{"type": "string", "maxLength": 20.0, "x-hint": "tag"},
+			},
+			"author": map[string]interface{}{
+				"type":                 "object",
+				"_relator":             map[string]interface{}{"nodeType": "custom:author"},
+				"additionalProperties": false,
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if def.Properties["tags"].Extra["uniqueItems"] != true {
+		t.Fatalf("unexpected extra keys %v", def.Properties["tags"].Extra)
+	}
+
+	def.Properties["tags"].Title = "Tags"
+	obj, err := def.ToJson()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	properties := obj.GetObject("properties")
+	tags := properties.GetObject("tags")
+	items := tags.GetObject("items")
+	author := properties.GetObject("author")
+	relator := author.GetObject("_relator")
+	if tags["uniqueItems"] != true || tags["title"] != "Tags" || items["x-hint"] != "tag" || items["maxLength"] != 20.0 {
+		t.Fatalf("unexpected tags schema %v", tags)
+	}
+	if relator.GetString("nodeType") != "custom:author" || author["additionalProperties"] != false {
+		t.Fatalf("unexpected author schema %v", author)
+	}
+}
+
+func TestCreateDefinition(t *testing.T) {
+	var created JsonObject
+	session := newTestSession(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" || r.URL.Path != "/repositories/r/branches/b/nodes" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			return
+		}
+		json.NewDecoder(r.Body).Decode(&created)
+		w.Write([]byte(`{"_doc": "def1"}`))
+	}))
+
+	def := &Definition{
+		QName:       "custom:book",
+		Description: "Book",
+		Properties: map[string]*Schema{
+			"title": {Type: "string", Required: true},
+		},
+	}
+	id, err := session.CreateDefinition("r", "b", def)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id != "def1" || def.Id != "def1" {
+		t.Fatalf("unexpected id %s", id)
+	}
+
+	properties := created.GetObject("properties")
+	title := properties.GetObject("title")
+	if created["_type"] != DefinitionType || created["type"] != "object" || created["_qname"] != "custom:book" || title["required"] != true {
+		t.Fatalf("unexpected definition %v", created)
+	}
+}
+
+func TestDefinitions(t *testing.T) {
+	session, repository := setupTestRepository(t)
+
+	repositoryId := ExtractId(&repository)
+	branchId := "master"
+	defer session.DeleteRepository(repositoryId)
+
+	def := &Definition{
+		QName:       "custom:book",
+		Title:       "Book",
+		Description: "Definitions Test Book Type",
+		Properties: map[string]*Schema{
+			"title":  {Type: "string", Required: true},
+			"author": {Type: "string"},
+		},
+	}
+
+	_, err := session.CreateDefinition(repositoryId, branchId, def)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	def, err = session.ReadDefinition(repositoryId, branchId, "custom:book")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if def.Properties["author"] == nil {
+		t.Fatal("failed to read definition")
+	}
+
+	def.Properties["pages"] = &Schema{Type: "number"}
+	def, err = session.UpdateDefinition(repositoryId, branchId, def)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if def.Properties["pages"] == nil {
+		t.Fatal("failed to update definition")
+	}
+
+	defs, err := session.ListDefinitions(repositoryId, branchId, "type")
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := false
+	for _, d := range defs {
+		if d.QName == "custom:book" {
+			found = true
+		}
+	}
+	if !found {
+		t.Fatal("definition not listed")
+	}
+
+	formId, err := session.CreateForm(repositoryId, branchId, "custom:book", "master", JsonObject{
+		"fields": JsonObject{
+			"title": JsonObject{"type": "text"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	forms, err := session.ListForms(repositoryId, branchId, "custom:book")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(forms) != 1 || forms[0].Key != "master" || forms[0].FormId != formId {
+		t.Fatal("failed to list forms")
+	}
+
+	form, err := session.ReadForm(repositoryId, branchId, "custom:book", "master")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if form.GetObject("fields") == nil {
+		t.Fatal("failed to read form")
+	}
+}
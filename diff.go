@@ -0,0 +1,238 @@
+package cloudcms
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+type DiffOp string
+
+const (
+	DiffAdded   DiffOp = "added"
+	DiffRemoved DiffOp = "removed"
+	DiffChanged DiffOp = "changed"
+)
+
+// DiffEntry is a single difference between two JSON documents. Path names the value, e.g.
+// "title", "tags[2]" or "publisher.name". From is nil for added values, To for removed ones.
+type DiffEntry struct {
+	Op   DiffOp
+	Path string
+	From interface{}
+	To   interface{}
+}
+
+func (e DiffEntry) String() string {
+	switch e.Op {
+	case DiffAdded:
+		return fmt.Sprintf("+ %s: %s", e.Path, diffValue(e.To))
+	case DiffRemoved:
+		return fmt.Sprintf("- %s: %s", e.Path, diffValue(e.From))
+	}
+
+	return fmt.Sprintf("~ %s: %s -> %s", e.Path, diffValue(e.From), diffValue(e.To))
+}
+
+func diffValue(v interface{}) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("%v", v)
+	}
+
+	return string(b)
+}
+
+// NodeDiff is the difference between two versions of a node.
+type NodeDiff struct {
+	NodeId          string
+	FromChangesetId string
+	ToChangesetId   string
+	Entries         []DiffEntry
+}
+
+// String renders the diff for review, one entry per line.
+func (d *NodeDiff) String() string {
+	to := d.ToChangesetId
+	if to == "" {
+		to = "current"
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "--- %s@%s\n", d.NodeId, d.FromChangesetId)
+	fmt.Fprintf(&b, "+++ %s@%s\n", d.NodeId, to)
+	for _, e := range d.Entries {
+		b.WriteString(e.String())
+		b.WriteString("\n")
+	}
+
+	return b.String()
+}
+
+// DiffNodeVersions compares the version of a node at fromChangesetId with its version at
+// toChangesetId, or with the current node if toChangesetId is empty. System properties
+// (_system) are ignored.
+func (session *CloudCmsSession) DiffNodeVersions(repositoryId string, branchId string, nodeId string, fromChangesetId string, toChangesetId string) (*NodeDiff, error) {
+	session = session.named("DiffNodeVersions")
+	from, err := session.ReadVersion(repositoryId, branchId, nodeId, fromChangesetId, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var to JsonObject
+	if toChangesetId == "" {
+		to, err = session.ReadNode(repositoryId, branchId, nodeId)
+	} else {
+		to, err = session.ReadVersion(repositoryId, branchId, nodeId, toChangesetId, nil)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &NodeDiff{
+		NodeId:          nodeId,
+		FromChangesetId: fromChangesetId,
+		ToChangesetId:   toChangesetId,
+		Entries:         DiffJson(from, to, "_system"),
+	}, nil
+}
+
+// DiffJson compares two JSON documents, skipping the top level keys in ignore. Entries are
+// sorted by path.
+func DiffJson(from JsonObject, to JsonObject, ignore ...string) []DiffEntry {
+	skip := map[string]bool{}
+	for _, key := range ignore {
+		skip[key] = true
+	}
+
+	a := map[string]interface{}{}
+	for key, val := range from {
+		if !skip[key] {
+			a[key] = val
+		}
+	}
+	b := map[string]interface{}{}
+	for key, val := range to {
+		if !skip[key] {
+			b[key] = val
+		}
+	}
+
+	entries := []DiffEntry{}
+	diffValues("", a, b, &entries)
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return lessPath(entries[i].Path, entries[j].Path)
+	})
+
+	return entries
+}
+
+func diffValues(path string, from interface{}, to interface{}, entries *[]DiffEntry) {
+	switch a := from.(type) {
+	case map[string]interface{}:
+		if b, ok := toMap(to); ok {
+			for key, val := range a {
+				if other, ok := b[key]; ok {
+					diffValues(joinPath(path, key), val, other, entries)
+				} else {
+					*entries = append(*entries, DiffEntry{Op: DiffRemoved, Path: joinPath(path, key), From: val})
+				}
+			}
+			for key, val := range b {
+				if _, ok := a[key]; !ok {
+					*entries = append(*entries, DiffEntry{Op: DiffAdded, Path: joinPath(path, key), To: val})
+				}
+			}
+			return
+		}
+
+	case JsonObject:
+		diffValues(path, map[string]interface{}(a), to, entries)
+		return
+
+	case []interface{}:
+		if b, ok := to.([]interface{}); ok {
+			for i := 0; i < len(a) || i < len(b); i++ {
+				itemPath := fmt.Sprintf("%s[%d]", path, i)
+				switch {
+				case i >= len(b):
+					*entries = append(*entries, DiffEntry{Op: DiffRemoved, Path: itemPath, From: a[i]})
+				case i >= len(a):
+					*entries = append(*entries, DiffEntry{Op: DiffAdded, Path: itemPath, To: b[i]})
+				default:
+					diffValues(itemPath, a[i], b[i], entries)
+				}
+			}
+			return
+		}
+	}
+
+	if !reflect.DeepEqual(from, to) {
+		*entries = append(*entries, DiffEntry{Op: DiffChanged, Path: path, From: from, To: to})
+	}
+}
+
+func toMap(v interface{}) (map[string]interface{}, bool) {
+	switch m := v.(type) {
+	case map[string]interface{}:
+		return m, true
+	case JsonObject:
+		return m, true
+	}
+
+	return nil, false
+}
+
+// lessPath orders paths with their numbers compared as numbers, so "items.2" and
+// "tags[2]" come before "items.10" and "tags[10]"
+func lessPath(a string, b string) bool {
+	for a != "" && b != "" {
+		if isDigit(a[0]) && isDigit(b[0]) {
+			na, nb := digits(a), digits(b)
+			ta, tb := strings.TrimLeft(na, "0"), strings.TrimLeft(nb, "0")
+			if len(ta) != len(tb) {
+				return len(ta) < len(tb)
+			}
+			if ta != tb {
+				return ta < tb
+			}
+			a, b = a[len(na):], b[len(nb):]
+			continue
+		}
+		if a[0] != b[0] {
+			return a[0] < b[0]
+		}
+		a, b = a[1:], b[1:]
+	}
+
+	return len(a) < len(b)
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// digits returns the digits s starts with
+func digits(s string) string {
+	i := 0
+	for i < len(s) && isDigit(s[i]) {
+		i++
+	}
+
+	return s[:i]
+}
+
+// joinPath appends key to path, quoting keys that would make the path ambiguous
+func joinPath(path string, key string) string {
+	if key == "" || strings.ContainsAny(key, ".[]\"") {
+		return fmt.Sprintf("%s[%q]", path, key)
+	}
+	if path == "" {
+		return key
+	}
+
+	return path + "." + key
+}
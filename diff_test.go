@@ -0,0 +1,118 @@
+package cloudcms
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDiffJson(t *testing.T) {
+	from := JsonObject{
+		"_doc":   "abc",
+		"title":  "hello",
+		"author": "mr bean",
+		"tags":   []interface{}{"a", "b"},
+		"publisher": map[string]interface{}{
+			"name": "old",
+			"city": "paris",
+		},
+		"a.b":     true,
+		"_system": map[string]interface{}{"changeset": "1:a"},
+	}
+	to := JsonObject{
+		"_doc":  "abc",
+		"title": "goodbye",
+		"tags":  []interface{}{"a", "c", "d"},
+		"publisher": map[string]interface{}{
+			"name": "new",
+			"city": "paris",
+		},
+		"a.b":     true,
+		"pages":   float64(10),
+		"_system": map[string]interface{}{"changeset": "2:b"},
+	}
+
+	expected := []string{
+		`- author: "mr bean"`,
+		`+ pages: 10`,
+		`~ publisher.name: "old" -> "new"`,
+		`~ tags[1]: "b" -> "c"`,
+		`+ tags[2]: "d"`,
+		`~ title: "hello" -> "goodbye"`,
+	}
+
+	entries := DiffJson(from, to, "_system")
+	if len(entries) != len(expected) {
+		t.Fatalf("expected %d entries, got %v", len(expected), entries)
+	}
+	for i, e := range entries {
+		if e.String() != expected[i] {
+			t.Fatalf("entry %d: expected %s, got %s", i, expected[i], e)
+		}
+	}
+
+	entries = DiffJson(JsonObject{"x.y": 1.0}, JsonObject{"x.y": 2.0})
+	if len(entries) != 1 || entries[0].Path != `["x.y"]` {
+		t.Fatalf("expected quoted path, got %v", entries)
+	}
+}
+
+func TestDiffJsonNumericOrder(t *testing.T) {
+	from := JsonObject{"tags": []interface{}{}, "items": map[string]interface{}{}}
+	to := JsonObject{"tags": []interface{}{}, "items": map[string]interface{}{}}
+	for i := 0; i < 11; i++ {
+		to["tags"] = append(to["tags"].([]interface{}), float64(i))
+	}
+	to["items"].(map[string]interface{})["10"] = true
+	to["items"].(map[string]interface{})["2"] = true
+
+	paths := []string{}
+	for _, e := range DiffJson(from, to) {
+		paths = append(paths, e.Path)
+	}
+	expected := "items.2 items.10 tags[0] tags[1] tags[2] tags[3] tags[4] tags[5] tags[6] tags[7] tags[8] tags[9] tags[10]"
+	if strings.Join(paths, " ") != expected {
+		t.Fatalf("expected %s, got %v", expected, paths)
+	}
+}
+
+func TestDiffNodeVersions(t *testing.T) {
+	session, repository := setupTestRepository(t)
+
+	repositoryId := ExtractId(&repository)
+	branchId := "master"
+	defer session.DeleteRepository(repositoryId)
+
+	nodeId, err := session.CreateNode(repositoryId, branchId, JsonObject{"title": "first", "author": "someone"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	node, err := session.ReadNode(repositoryId, branchId, nodeId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	systemObj := node.GetObject("_system")
+	firstChangeset := systemObj.GetString("changeset")
+
+	node["title"] = "second"
+	delete(node, "author")
+	_, err = session.UpdateNode(repositoryId, branchId, node)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	diff, err := session.DiffNodeVersions(repositoryId, branchId, nodeId, firstChangeset, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	text := diff.String()
+	if !strings.Contains(text, `~ title: "first" -> "second"`) || !strings.Contains(text, `- author: "someone"`) {
+		t.Fatalf("unexpected diff:\n%s", text)
+	}
+	for _, e := range diff.Entries {
+		if strings.HasPrefix(e.Path, "_system") {
+			t.Fatal("_system should be ignored")
+		}
+	}
+}
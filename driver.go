@@ -6,11 +6,10 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
 	"net/url"
-	"os"
-	"path/filepath"
-	"runtime/debug"
+	"strings"
 
 	"golang.org/x/oauth2"
 )
@@ -22,11 +21,56 @@ type CloudcmsConfig struct {
 	Password      string `json:"password"`
 	BaseURL       string `json:"baseURL"`
 	Debug         bool   `json:"debug"`
+
+	// RateLimit is the number of requests sent per second, in bursts of up to RateBurst.
+	RateLimit float64 `json:"rateLimit"`
+	RateBurst int     `json:"rateBurst"`
+
+	// MaxConcurrentRequests caps the requests in flight. A request holds its slot until its
+	// response body is closed, except a Download, which frees it once the headers arrive.
+	MaxConcurrentRequests int `json:"maxConcurrentRequests"`
+
+	// MaxRetries is the number of times a request answered with 429 is sent again when
+	// RateLimit or MaxConcurrentRequests is set (3 if zero, none if negative).
+	MaxRetries int `json:"maxRetries"`
+
+	// HTTPClient, if set, is used to send requests; its Transport is used unless Transport
+	// is set. Neither can be read from gitana.json.
+	HTTPClient *http.Client      `json:"-"`
+	Transport  http.RoundTripper `json:"-"`
+
+	// Middlewares wrap the oauth2 transport, the first one outermost.
+	Middlewares []Middleware `json:"-"`
+
+	// Logger receives a record for every request. If Debug is set without a Logger,
+	// slog.Default() is used. LogLevel is the level of successful requests: "debug",
+	// "info" (the default), "warn" or "error".
+	Logger   *slog.Logger `json:"-"`
+	LogLevel string       `json:"logLevel"`
+
+	// Instrumentation is told about every API request.
+	Instrumentation Instrumentation `json:"-"`
+
+	// Cache, if set, keeps node reads and queries for CacheTTL seconds (60 if zero). If only
+	// CacheSize is set, up to CacheSize responses are kept in memory.
+	Cache     Cache   `json:"-"`
+	CacheSize int     `json:"cacheSize"`
+	CacheTTL  float64 `json:"cacheTTL"`
 }
 
 type CloudCmsSession struct {
 	oauthClient *http.Client
 	config      *CloudcmsConfig
+	locale      string
+	limiter     *limiter
+	logger      *slog.Logger
+
+	instrumentation Instrumentation
+	cache           *responseCache
+	noCache         bool
+
+	// operation is the session method sending the requests, see named
+	operation string
 }
 
 type JsonObject map[string]interface{}
@@ -38,6 +82,31 @@ type ResultMap struct {
 	offset     int
 }
 
+func NewResultMap(rows []JsonObject, offset int, totalRows int) *ResultMap {
+	return &ResultMap{
+		rows:       rows,
+		size:       len(rows),
+		total_rows: totalRows,
+		offset:     offset,
+	}
+}
+
+func (res *ResultMap) Rows() []JsonObject {
+	return res.rows
+}
+
+func (res *ResultMap) Size() int {
+	return res.size
+}
+
+func (res *ResultMap) TotalRows() int {
+	return res.total_rows
+}
+
+func (res *ResultMap) Offset() int {
+	return res.offset
+}
+
 func (obj *JsonObject) GetString(key string) string {
 	val, ok := (*obj)[key]
 	if !ok {
@@ -90,35 +159,35 @@ func (obj *JsonObject) GetObjectArray(key string) []JsonObject {
 	return res
 }
 
-// This type implements the http.RoundTripper interface
-type LoggingRoundTripper struct {
-	Proxied http.RoundTripper
-}
-
-func (lrt LoggingRoundTripper) RoundTrip(req *http.Request) (res *http.Response, e error) {
-	info := fmt.Sprintf("%v %v %v", req.Method, req.URL, req.Proto)
-	fmt.Println(info)
-
-	// Send the request, get the response (or the error)
-	res, e = lrt.Proxied.RoundTrip(req)
-
-	// Handle the result.
-	if e != nil {
-		fmt.Printf("Error: %v", e)
-		debug.PrintStack()
-	} else {
-		fmt.Printf("Received %v response\n", res.Status)
+// configLogger returns the logger for the config, or nil if logging is off
+func configLogger(config *CloudcmsConfig) *slog.Logger {
+	if config.Logger != nil {
+		return config.Logger
+	}
+	if config.Debug {
+		return slog.Default()
 	}
 
-	return res, e
+	return nil
 }
 
 func buildOAuthClient(cloudcmsConfig *CloudcmsConfig) (*http.Client, error) {
 	ctx := context.Background()
 	httpClient := http.Client{}
+	if cloudcmsConfig.HTTPClient != nil {
+		httpClient = *cloudcmsConfig.HTTPClient
+	}
+	if cloudcmsConfig.Transport != nil {
+		httpClient.Transport = cloudcmsConfig.Transport
+	}
 
-	if cloudcmsConfig.Debug {
-		httpClient.Transport = LoggingRoundTripper{http.DefaultTransport}
+	logger := configLogger(cloudcmsConfig)
+	if logger != nil {
+		base := httpClient.Transport
+		if base == nil {
+			base = http.DefaultTransport
+		}
+		httpClient.Transport = NewLoggingRoundTripper(base, WithLogger(logger), WithLogLevel(parseLogLevel(cloudcmsConfig.LogLevel)))
 	}
 
 	ctx = context.WithValue(ctx, oauth2.HTTPClient, &httpClient)
@@ -137,7 +206,12 @@ func buildOAuthClient(cloudcmsConfig *CloudcmsConfig) (*http.Client, error) {
 		return nil, err
 	}
 
+	// the oauth2 client only keeps the transport
 	oauthClient := conf.Client(ctx, token)
+	oauthClient.Transport = chainMiddlewares(oauthClient.Transport, cloudcmsConfig.Middlewares)
+	oauthClient.CheckRedirect = httpClient.CheckRedirect
+	oauthClient.Jar = httpClient.Jar
+	oauthClient.Timeout = httpClient.Timeout
 	return oauthClient, nil
 }
 
@@ -188,54 +262,6 @@ func MapToReader(obj JsonObject) io.Reader {
 	return bytes.NewReader(data)
 }
 
-func LoadConfig() *CloudcmsConfig {
-	var result *CloudcmsConfig
-	result = nil
-	wd, err := os.Getwd()
-	if err != nil {
-		return nil
-	}
-
-	if result == nil {
-		result = ReadConfig(filepath.Join(wd, "gitana.json"))
-	}
-	if result == nil {
-		result = ReadConfig(filepath.Join(wd, "gitana-test.json"))
-	}
-	if result == nil {
-		result = ReadConfig(filepath.Join(wd, "cloudcms.json"))
-	}
-	if result == nil {
-		result = ReadConfig(filepath.Join(wd, "cloudcms-test.json"))
-	}
-
-	return result
-}
-
-func ReadConfig(path string) *CloudcmsConfig {
-	data, err := os.ReadFile(path)
-	if err != nil {
-		return nil
-	}
-
-	var res CloudcmsConfig
-	err = json.Unmarshal(data, &res)
-	if err != nil {
-		return nil
-	}
-
-	return &res
-}
-
-func ConnectDefault() (*CloudCmsSession, error) {
-	config := LoadConfig()
-	if config == nil {
-		return nil, fmt.Errorf("could not locate gitana.json")
-	}
-
-	return Connect(config)
-}
-
 func Connect(cloudcmsConfig *CloudcmsConfig) (*CloudCmsSession, error) {
 	oauthClient, err := buildOAuthClient(cloudcmsConfig)
 	if err != nil {
@@ -245,18 +271,59 @@ func Connect(cloudcmsConfig *CloudcmsConfig) (*CloudCmsSession, error) {
 	client := &CloudCmsSession{
 		oauthClient: oauthClient,
 		config:      cloudcmsConfig,
+		limiter:     newLimiter(cloudcmsConfig),
+		logger:      configLogger(cloudcmsConfig),
+
+		instrumentation: cloudcmsConfig.Instrumentation,
+		cache:           newResponseCache(cloudcmsConfig),
 	}
 
 	return client, nil
 }
 
 func (session *CloudCmsSession) Request(req *http.Request) (*http.Response, error) {
-	resp, err := session.oauthClient.Do(req)
+	if session.locale != "" && req.Header.Get("Accept-Language") == "" {
+		req.Header.Set("Accept-Language", acceptLanguage(session.locale))
+	}
+
+	if session.operation != "" {
+		req = req.WithContext(withOperation(req.Context(), session.operation))
+	}
+
+	var end func(status int, responseBytes int64, err error)
+	if session.instrumentation != nil {
+		req, end = session.instrument(req)
+	}
+
+	var resp *http.Response
+	var err error
+	if session.limiter != nil {
+		resp, err = session.limitedRequest(req)
+	} else {
+		resp, err = session.oauthClient.Do(req)
+	}
+	if session.cache != nil {
+		session.cache.invalidate(req.Method, strings.TrimPrefix(req.URL.Path, session.basePath()))
+	}
 	if err != nil {
+		if end != nil {
+			end(0, 0, err)
+		}
 		return nil, err
 	}
+	if end != nil {
+		resp.Body = &instrumentedBody{ReadCloser: resp.Body, status: resp.StatusCode, end: end}
+	}
 
-	// Throw error for non-2xx repsonses
+	// Throw error for non-2xx repsonses, except 304 to a conditional request
+	if resp.StatusCode == http.StatusNotModified {
+		if isConditional(req.Header) {
+			return resp, nil
+		}
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+		return nil, ErrNotModified
+	}
 	if resp.StatusCode < 200 || resp.StatusCode > 299 {
 		// Try to get response message
 		b, _ := io.ReadAll(resp.Body)
@@ -268,6 +335,17 @@ func (session *CloudCmsSession) Request(req *http.Request) (*http.Response, erro
 }
 
 func (session *CloudCmsSession) RequestJson(method string, uri string, params url.Values, body io.Reader) (JsonObject, error) {
+	target := make(JsonObject)
+	err := session.requestInto(method, uri, jsonParams(params), body, &target)
+	if err != nil {
+		return nil, err
+	}
+
+	return target, nil
+}
+
+// jsonParams asks for full objects with their metadata unless params says otherwise
+func jsonParams(params url.Values) url.Values {
 	if params == nil {
 		params = url.Values{}
 	}
@@ -279,26 +357,67 @@ func (session *CloudCmsSession) RequestJson(method string, uri string, params ur
 		params.Add("metadata", "true")
 	}
 
-	uri += "?" + params.Encode()
+	return params
+}
+
+// requestInto sends a request with a JSON body, as is, and decodes the response into target
+func (session *CloudCmsSession) requestInto(method string, uri string, params url.Values, body io.Reader, target interface{}) error {
+	cacheKey := ""
+	if session.cache != nil && !session.noCache && session.cache.caches(method, uri) {
+		var data []byte
+		if body != nil {
+			var err error
+			data, err = io.ReadAll(body)
+			if err != nil {
+				return err
+			}
+			body = bytes.NewReader(data)
+		}
+
+		// the response is decoded again on every hit, so callers may change what they get
+		cacheKey = session.cache.key(method, uri, params.Encode(), session.locale, data)
+		cached, ok := session.cache.backend.Get(cacheKey)
+		if ok {
+			return json.Unmarshal(cached, target)
+		}
+	}
+
+	if len(params) > 0 {
+		uri += "?" + params.Encode()
+	}
 
 	req, err := http.NewRequest(method, session.config.BaseURL+uri, body)
 	if err != nil {
-		return nil, err
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
 	}
 
 	resp, err := session.Request(req)
 	if err != nil {
-		return nil, err
+		return err
 	}
 	defer resp.Body.Close()
 
-	target := make(JsonObject)
-	err = json.NewDecoder(resp.Body).Decode(&target)
+	if resp.StatusCode == http.StatusNotModified {
+		return ErrNotModified
+	}
+	if cacheKey == "" {
+		return json.NewDecoder(resp.Body).Decode(target)
+	}
+
+	data, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, err
+		return err
+	}
+	err = json.Unmarshal(data, target)
+	if err != nil {
+		return err
 	}
+	session.cache.backend.Set(cacheKey, data, session.cache.ttl)
 
-	return target, nil
+	return nil
 }
 
 func (session *CloudCmsSession) Get(url string, params url.Values) (JsonObject, error) {
@@ -327,7 +446,7 @@ func (session *CloudCmsSession) Download(url string, params url.Values) (io.Read
 	}
 
 	req, _ := http.NewRequest("GET", session.config.BaseURL+url, nil)
-	resp, err := session.Request(req)
+	resp, err := session.Request(streamed(req))
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,18 @@
+package cloudcms
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// newTestSession returns a session that talks to handler instead of Cloud CMS
+func newTestSession(t *testing.T, handler http.Handler) *CloudCmsSession {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	return &CloudCmsSession{
+		oauthClient: server.Client(),
+		config:      &CloudcmsConfig{BaseURL: server.URL},
+	}
+}
@@ -0,0 +1,110 @@
+package cloudcms
+
+import (
+	"fmt"
+)
+
+type FindResult struct {
+	Hits      []SearchHit
+	Facets    map[string][]FacetBucket
+	Size      int
+	TotalRows int
+	Offset    int
+}
+
+type SearchHit struct {
+	Node       JsonObject
+	Score      float64
+	Highlights map[string][]string
+}
+
+type FacetBucket struct {
+	Key   string
+	Count int
+}
+
+// Find runs FindNodes and decodes the response into hits with their search scores and
+// highlights, plus facet buckets for any aggregations requested in the search.
+func (session *CloudCmsSession) Find(repositoryId string, branchId string, config JsonObject, pagination JsonObject) (*FindResult, error) {
+	session = session.named("Find")
+	uri := fmt.Sprintf("/repositories/%s/branches/%s/nodes/find", repositoryId, branchId)
+
+	res, err := session.Post(uri, ToParams(pagination), MapToReader(config))
+	if err != nil {
+		return nil, err
+	}
+
+	return ToFindResult(res), nil
+}
+
+func ToFindResult(obj JsonObject) *FindResult {
+	result := &FindResult{
+		Size:      getInt(obj, "size"),
+		TotalRows: getInt(obj, "total_rows"),
+		Offset:    getInt(obj, "offset"),
+		Facets:    make(map[string][]FacetBucket),
+	}
+
+	for _, row := range obj.GetObjectArray("rows") {
+		result.Hits = append(result.Hits, toSearchHit(row))
+	}
+
+	aggregations := obj.GetObject("aggregations")
+	if aggregations == nil {
+		aggregations = obj.GetObject("facets")
+	}
+	for name := range aggregations {
+		agg := aggregations.GetObject(name)
+		buckets := []FacetBucket{}
+		for _, bucket := range agg.GetObjectArray("buckets") {
+			key := bucket.GetString("key_as_string")
+			if key == "" {
+				key = bucket.GetString("key")
+			}
+			buckets = append(buckets, FacetBucket{Key: key, Count: getInt(bucket, "doc_count")})
+		}
+		result.Facets[name] = buckets
+	}
+
+	return result
+}
+
+func toSearchHit(row JsonObject) SearchHit {
+	hit := SearchHit{Node: row}
+
+	meta := row.GetObject("_search")
+	if meta == nil {
+		meta = row
+	}
+
+	if score, ok := meta["_score"].(float64); ok {
+		hit.Score = score
+	} else if score, ok := meta["score"].(float64); ok {
+		hit.Score = score
+	}
+
+	highlight := meta.GetObject("highlight")
+	if highlight == nil {
+		highlight = meta.GetObject("_highlight")
+	}
+	if highlight != nil {
+		hit.Highlights = make(map[string][]string)
+		for field, fragments := range highlight {
+			arr, _ := fragments.([]interface{})
+			for _, fragment := range arr {
+				hit.Highlights[field] = append(hit.Highlights[field], fmt.Sprintf("%v", fragment))
+			}
+		}
+	}
+
+	return hit
+}
+
+func getInt(obj JsonObject, key string) int {
+	val, ok := obj[key].(float64)
+	if !ok {
+		return 0
+	}
+
+	return int(val)
+}
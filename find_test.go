@@ -0,0 +1,91 @@
+package cloudcms
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestToFindResult(t *testing.T) {
+	var obj JsonObject
+	err := json.Unmarshal([]byte(`{
+		"size": 1,
+		"total_rows": 1,
+		"offset": 0,
+		"rows": [{
+			"_doc": "abc",
+			"title": "Cheese burger",
+			"_search": {"score": 1.5, "highlight": {"title": ["Cheese <em>burger</em>"]}}
+		}],
+		"aggregations": {
+			"meals": {"buckets": [{"key": "lunch", "doc_count": 3}, {"key": "breakfast", "doc_count": 1}]}
+		}
+	}`), &obj)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result := ToFindResult(obj)
+	if len(result.Hits) != 1 || ExtractId(&result.Hits[0].Node) != "abc" {
+		t.Fatal("wrong hits")
+	}
+	if result.Hits[0].Score != 1.5 {
+		t.Fatal("wrong score")
+	}
+	if result.Hits[0].Highlights["title"][0] != "Cheese <em>burger</em>" {
+		t.Fatal("wrong highlight")
+	}
+
+	meals := result.Facets["meals"]
+	if len(meals) != 2 || meals[0].Key != "lunch" || meals[0].Count != 3 {
+		t.Fatal("wrong facet buckets")
+	}
+}
+
+func TestFindFacets(t *testing.T) {
+	session, repository := setupTestRepository(t)
+
+	repositoryId := ExtractId(&repository)
+	branchId := "master"
+	defer session.DeleteRepository(repositoryId)
+
+	for _, obj := range []JsonObject{
+		{"title": "Cheese burger", "meal": "lunch"},
+		{"title": "Ham burger", "meal": "lunch"},
+		{"title": "Breakfast burger", "meal": "breakfast"},
+	} {
+		_, err := session.CreateNode(repositoryId, branchId, obj, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	time.Sleep(time.Second * 10)
+
+	config := JsonObject{
+		"search": JsonObject{
+			"query": JsonObject{
+				"match": JsonObject{"title": "burger"},
+			},
+			"highlight": JsonObject{
+				"fields": JsonObject{"title": JsonObject{}},
+			},
+			"aggs": JsonObject{
+				"meals": JsonObject{
+					"terms": JsonObject{"field": "meal"},
+				},
+			},
+		},
+	}
+
+	result, err := session.Find(repositoryId, branchId, config, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Hits) != 3 {
+		t.Fatal("wrong number of find results")
+	}
+	if len(result.Facets["meals"]) != 2 {
+		t.Fatal("wrong number of facet buckets")
+	}
+}
@@ -0,0 +1,212 @@
+package fssync
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	cloudcms "github.com/gitana/cloudcms-go-driver"
+)
+
+// AttachmentsSuffix marks the directory holding the attachments of a JSON file: the
+// attachments of book.json are the files in book.attachments, named after their attachment id.
+const AttachmentsSuffix = ".attachments"
+
+// localEntry is a JSON file or an asset file in the synced directory
+type localEntry struct {
+	// file is the slash separated path relative to the directory
+	file        string
+	properties  cloudcms.JsonObject
+	attachments map[string]*localAttachment
+}
+
+type localAttachment struct {
+	path     string
+	mimeType string
+	size     int64
+	hash     string
+}
+
+func isJson(file string) bool {
+	return strings.EqualFold(path.Ext(file), ".json")
+}
+
+// attachmentsDir returns the slash separated directory holding the attachments of a JSON file
+func attachmentsDir(file string) string {
+	return strings.TrimSuffix(file, path.Ext(file)) + AttachmentsSuffix
+}
+
+// scan reads the entries of dir. Hidden files and directories are skipped.
+func scan(dir string) (map[string]*localEntry, error) {
+	entries := map[string]*localEntry{}
+	attachments := map[string]map[string]*localAttachment{}
+
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if p == dir {
+			return nil
+		}
+		if strings.HasPrefix(d.Name(), ".") {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+
+		if d.IsDir() {
+			if strings.HasSuffix(rel, AttachmentsSuffix) {
+				found, err := scanAttachments(p)
+				if err != nil {
+					return err
+				}
+				attachments[rel] = found
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if !isJson(rel) {
+			att, err := readAttachment(p)
+			if err != nil {
+				return err
+			}
+			entries[rel] = &localEntry{
+				file:        rel,
+				properties:  cloudcms.JsonObject{"title": path.Base(rel)},
+				attachments: map[string]*localAttachment{"default": att},
+			}
+			return nil
+		}
+
+		data, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		var properties cloudcms.JsonObject
+		err = json.Unmarshal(data, &properties)
+		if err != nil {
+			return fmt.Errorf("%s: %v", rel, err)
+		}
+		entries[rel] = &localEntry{file: rel, properties: properties, attachments: map[string]*localAttachment{}}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for dir, found := range attachments {
+		owner := ""
+		for file, entry := range entries {
+			if isJson(file) && attachmentsDir(file) == dir {
+				owner = file
+				entry.attachments = found
+			}
+		}
+		if owner == "" {
+			return nil, fmt.Errorf("%s: no JSON file for attachments", dir)
+		}
+	}
+
+	return entries, nil
+}
+
+func scanAttachments(dir string) (map[string]*localAttachment, error) {
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	res := map[string]*localAttachment{}
+	for _, f := range files {
+		if f.IsDir() || strings.HasPrefix(f.Name(), ".") {
+			continue
+		}
+
+		id := strings.TrimSuffix(f.Name(), filepath.Ext(f.Name()))
+		if res[id] != nil {
+			return nil, fmt.Errorf("%s: more than one file for attachment %s", dir, id)
+		}
+
+		att, err := readAttachment(filepath.Join(dir, f.Name()))
+		if err != nil {
+			return nil, err
+		}
+		res[id] = att
+	}
+
+	return res, nil
+}
+
+func readAttachment(p string) (*localAttachment, error) {
+	f, err := os.Open(p)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	size, err := io.Copy(h, f)
+	if err != nil {
+		return nil, err
+	}
+
+	mimeType := mime.TypeByExtension(filepath.Ext(p))
+	if mimeType == "" {
+		mimeType = "application/octet-stream"
+	}
+
+	return &localAttachment{
+		path:     p,
+		mimeType: mimeType,
+		size:     size,
+		hash:     hex.EncodeToString(h.Sum(nil)),
+	}, nil
+}
+
+// content returns the properties of a node that are synced: everything except system
+// properties and the sync marker. _type and _qname are kept unless they are the defaults
+// assigned by Cloud CMS.
+func content(obj cloudcms.JsonObject) cloudcms.JsonObject {
+	res := cloudcms.JsonObject{}
+	for key, val := range obj {
+		if key == MarkerProperty {
+			continue
+		}
+		if strings.HasPrefix(key, "_") && key != "_type" && key != "_qname" {
+			continue
+		}
+		res[key] = val
+	}
+
+	if res["_type"] == "n:node" {
+		delete(res, "_type")
+	}
+	if qname, ok := res["_qname"].(string); ok && strings.HasPrefix(qname, "o:") {
+		delete(res, "_qname")
+	}
+
+	return res
+}
+
+// hashJson hashes the canonical encoding of obj, in which object keys are sorted
+func hashJson(obj cloudcms.JsonObject) string {
+	data, _ := json.Marshal(obj)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,202 @@
+package fssync
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	cloudcms "github.com/gitana/cloudcms-go-driver"
+	"github.com/gitana/cloudcms-go-driver/internal/attachment"
+)
+
+// Pull writes the folder to the directory. It writes the JSON files whose properties differ
+// from their node, downloads the attachments that changed on either side and, with Delete,
+// removes the files whose node is gone. The marker of a node is updated with the attachments
+// pulled. Only nodes created by Push, or given a MarkerProperty, are pulled.
+func (s *Syncer) Pull() ([]Change, error) {
+	remote, err := s.remoteNodes()
+	if err != nil {
+		return nil, err
+	}
+
+	local := map[string]*localEntry{}
+	_, err = os.Stat(s.Dir)
+	if err == nil {
+		local, err = scan(s.Dir)
+	} else if os.IsNotExist(err) {
+		err = nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	changes := []Change{}
+	for _, file := range sortedNodeFiles(remote) {
+		c, err := s.pullNode(remote[file], local[file], file)
+		changes = append(changes, c...)
+		if err != nil {
+			return changes, err
+		}
+	}
+
+	if !s.Delete {
+		return changes, nil
+	}
+
+	for _, file := range sortedEntryFiles(local) {
+		if remote[file] != nil {
+			continue
+		}
+
+		changes = append(changes, s.record(Change{Action: RemoveFile, File: file}))
+		if s.DryRun {
+			continue
+		}
+
+		err = os.Remove(s.localPath(file))
+		if err == nil && isJson(file) {
+			err = os.RemoveAll(s.localPath(attachmentsDir(file)))
+		}
+		if err != nil {
+			return changes, err
+		}
+	}
+
+	return changes, nil
+}
+
+func (s *Syncer) localPath(file string) string {
+	return filepath.Join(s.Dir, filepath.FromSlash(file))
+}
+
+func (s *Syncer) pullNode(node cloudcms.JsonObject, entry *localEntry, file string) ([]Change, error) {
+	changes := []Change{}
+	nodeId := cloudcms.ExtractId(&node)
+
+	if isJson(file) {
+		properties := content(node)
+		if entry == nil || hashJson(properties) != hashJson(content(entry.properties)) {
+			changes = append(changes, s.record(Change{Action: WriteFile, File: file, NodeId: nodeId}))
+			if !s.DryRun {
+				err := writeJson(s.localPath(file), properties)
+				if err != nil {
+					return changes, err
+				}
+			}
+		}
+	}
+
+	res, err := s.session.ListAttachments(s.repositoryId, s.branchId, nodeId)
+	if err != nil {
+		return changes, err
+	}
+
+	m := readMarker(node)
+	remoteIds := map[string]bool{}
+	hashes := map[string]string{}
+	versions := map[string]string{}
+	for _, row := range res.Rows() {
+		id := row.GetString("attachmentId")
+		if !isJson(file) && id != "default" {
+			continue
+		}
+		if strings.ContainsAny(id, "/\\") || !validFile(id) {
+			return changes, fmt.Errorf("node %s has invalid attachment id %q", nodeId, id)
+		}
+		remoteIds[id] = true
+
+		var att *localAttachment
+		if entry != nil {
+			att = entry.attachments[id]
+		}
+
+		// the attachment is unchanged if neither side moved since the last sync, as far as
+		// the marker tells: an older marker may lack the version or the hash
+		length, _ := row["length"].(float64)
+		version := remoteVersion(row)
+		if version != "" {
+			versions[id] = version
+		}
+		if att != nil && att.size == int64(length) && (m.versions[id] == "" || m.versions[id] == version) && (m.attachments[id] == "" || m.attachments[id] == att.hash) {
+			hashes[id] = att.hash
+			continue
+		}
+
+		target := s.localPath(file)
+		if isJson(file) {
+			if att != nil {
+				target = att.path
+			} else {
+				target = s.localPath(path.Join(attachmentsDir(file), id+attachment.Ext(row)))
+			}
+		}
+
+		changes = append(changes, s.record(Change{Action: DownloadAttachment, File: file, NodeId: nodeId, AttachmentId: id}))
+		if s.DryRun {
+			continue
+		}
+
+		err = attachment.Download(s.session, s.repositoryId, s.branchId, nodeId, id, target)
+		if err != nil {
+			return changes, err
+		}
+		downloaded, err := readAttachment(target)
+		if err != nil {
+			return changes, err
+		}
+		hashes[id] = downloaded.hash
+	}
+
+	// the marker records what was pulled, so the next push does not upload it back
+	if !s.DryRun && (!sameStrings(hashes, m.attachments) || !sameStrings(versions, m.versions)) {
+		obj := cloudcms.JsonObject{}
+		for key, val := range node {
+			obj[key] = val
+		}
+		obj[MarkerProperty] = s.markerObject(file, hashes, versions)
+
+		_, err = s.session.UpdateNode(s.repositoryId, s.branchId, obj)
+		if err != nil {
+			return changes, err
+		}
+	}
+
+	if !s.Delete || entry == nil || !isJson(file) {
+		return changes, nil
+	}
+
+	for _, id := range sortedAttachmentIds(entry.attachments) {
+		if remoteIds[id] {
+			continue
+		}
+
+		changes = append(changes, s.record(Change{Action: RemoveFile, File: file, NodeId: nodeId, AttachmentId: id}))
+		if s.DryRun {
+			continue
+		}
+
+		err = os.Remove(entry.attachments[id].path)
+		if err != nil {
+			return changes, err
+		}
+	}
+
+	return changes, nil
+}
+
+func writeJson(p string, obj cloudcms.JsonObject) error {
+	data, err := json.MarshalIndent(obj, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(filepath.Dir(p), 0755)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(p, append(data, '\n'), 0644)
+}
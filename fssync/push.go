@@ -0,0 +1,193 @@
+package fssync
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	cloudcms "github.com/gitana/cloudcms-go-driver"
+)
+
+// Push writes the directory to the folder. It creates nodes for new files, updates nodes
+// whose properties differ from their file, uploads attachments whose hash changed and, with
+// Delete, deletes the nodes and attachments whose file is gone.
+func (s *Syncer) Push() ([]Change, error) {
+	local, err := scan(s.Dir)
+	if err != nil {
+		return nil, err
+	}
+
+	remote, err := s.remoteNodes()
+	if err != nil {
+		return nil, err
+	}
+
+	changes := []Change{}
+	for _, file := range sortedEntryFiles(local) {
+		c, err := s.pushEntry(local[file], remote[file])
+		changes = append(changes, c...)
+		if err != nil {
+			return changes, err
+		}
+	}
+
+	if !s.Delete {
+		return changes, nil
+	}
+
+	for _, file := range sortedNodeFiles(remote) {
+		if local[file] != nil {
+			continue
+		}
+
+		node := remote[file]
+		change := s.record(Change{Action: DeleteNode, File: file, NodeId: cloudcms.ExtractId(&node)})
+		changes = append(changes, change)
+		if s.DryRun {
+			continue
+		}
+
+		err = s.session.DeleteNode(s.repositoryId, s.branchId, change.NodeId)
+		if err != nil {
+			return changes, err
+		}
+	}
+
+	return changes, nil
+}
+
+func (s *Syncer) record(change Change) Change {
+	s.logf("%s", change)
+	return change
+}
+
+func (s *Syncer) pushEntry(entry *localEntry, node cloudcms.JsonObject) ([]Change, error) {
+	changes := []Change{}
+	properties := content(entry.properties)
+
+	nodeId := ""
+	propsChanged := false
+	if node == nil {
+		changes = append(changes, s.record(Change{Action: CreateNode, File: entry.file}))
+		if !s.DryRun {
+			obj := cloudcms.JsonObject{}
+			for key, val := range properties {
+				obj[key] = val
+			}
+			obj[MarkerProperty] = s.markerObject(entry.file, nil, nil)
+
+			var err error
+			nodeId, err = s.session.CreateNode(s.repositoryId, s.branchId, obj, map[string]string{"filePath": s.nodePath(entry.file)})
+			if err != nil {
+				return changes, err
+			}
+			changes[0].NodeId = nodeId
+		}
+	} else {
+		nodeId = cloudcms.ExtractId(&node)
+		propsChanged = hashJson(content(node)) != hashJson(properties)
+	}
+
+	m := readMarker(node)
+	hashes := map[string]string{}
+	markerChanged := false
+
+	for _, id := range sortedAttachmentIds(entry.attachments) {
+		att := entry.attachments[id]
+		hashes[id] = att.hash
+		if m.attachments[id] == att.hash {
+			continue
+		}
+
+		markerChanged = true
+		changes = append(changes, s.record(Change{Action: UploadAttachment, File: entry.file, NodeId: nodeId, AttachmentId: id}))
+		if s.DryRun {
+			continue
+		}
+
+		err := s.upload(nodeId, id, att)
+		if err != nil {
+			return changes, err
+		}
+	}
+
+	removed := []string{}
+	for id := range m.attachments {
+		if hashes[id] == "" {
+			removed = append(removed, id)
+		}
+	}
+	sort.Strings(removed)
+	for _, id := range removed {
+		if !s.Delete {
+			// keep tracking the attachment so it is not reported again
+			hashes[id] = m.attachments[id]
+			continue
+		}
+
+		markerChanged = true
+		changes = append(changes, s.record(Change{Action: DeleteAttachment, File: entry.file, NodeId: nodeId, AttachmentId: id}))
+		if s.DryRun {
+			continue
+		}
+
+		err := s.session.DeleteAttachment(s.repositoryId, s.branchId, nodeId, id)
+		if err != nil {
+			return changes, err
+		}
+	}
+
+	if !propsChanged && !markerChanged {
+		return changes, nil
+	}
+	if propsChanged {
+		changes = append(changes, s.record(Change{Action: UpdateNode, File: entry.file, NodeId: nodeId}))
+	}
+	if s.DryRun {
+		return changes, nil
+	}
+
+	// the node is read again so that the attachments just uploaded are kept
+	fresh, err := s.session.ReadNode(s.repositoryId, s.branchId, nodeId)
+	if err != nil {
+		return changes, err
+	}
+
+	versions := m.versions
+	if markerChanged {
+		versions, err = s.attachmentVersions(nodeId)
+		if err != nil {
+			return changes, err
+		}
+	}
+	for id := range versions {
+		if hashes[id] == "" {
+			delete(versions, id)
+		}
+	}
+
+	obj := cloudcms.JsonObject{}
+	for key, val := range fresh {
+		if strings.HasPrefix(key, "_") {
+			obj[key] = val
+		}
+	}
+	for key, val := range properties {
+		obj[key] = val
+	}
+	obj[MarkerProperty] = s.markerObject(entry.file, hashes, versions)
+
+	_, err = s.session.UpdateNode(s.repositoryId, s.branchId, obj)
+	return changes, err
+}
+
+func (s *Syncer) upload(nodeId string, attachmentId string, att *localAttachment) error {
+	f, err := os.Open(att.path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return s.session.UploadAttachment(s.repositoryId, s.branchId, nodeId, attachmentId, f, att.mimeType, filepath.Base(att.path))
+}
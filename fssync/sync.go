@@ -0,0 +1,284 @@
+// Package fssync synchronizes a directory of JSON and asset files with a folder on a branch.
+//
+// Every JSON file becomes a node holding its properties, at the same path below the folder
+// without the .json extension. The files in the matching .attachments directory become
+// attachments of that node, named after the file without its extension. Any other file
+// becomes a node with the file as its default attachment.
+//
+// Synced nodes carry a MarkerProperty recording their file, the hashes of their attachments
+// and the versions of those attachments in Cloud CMS, so unchanged properties and
+// attachments are not written again in either direction.
+package fssync
+
+import (
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+
+	cloudcms "github.com/gitana/cloudcms-go-driver"
+)
+
+// MarkerProperty is the node property recording how the node maps to a file.
+const MarkerProperty = "fssync"
+
+const queryPageSize = 100
+
+// Session is the subset of *cloudcms.CloudCmsSession used by the syncer.
+type Session interface {
+	ReadNode(repositoryId string, branchId string, nodeId string) (cloudcms.JsonObject, error)
+	QueryNodes(repositoryId string, branchId string, query cloudcms.JsonObject, pagination cloudcms.JsonObject) (*cloudcms.ResultMap, error)
+	CreateNode(repositoryId string, branchId string, obj cloudcms.JsonObject, opts map[string]string) (string, error)
+	UpdateNode(repositoryId string, branchId string, node cloudcms.JsonObject) (cloudcms.JsonObject, error)
+	DeleteNode(repositoryId string, branchId string, nodeId string) error
+	UploadAttachment(repositoryId string, branchId string, nodeId string, attachmentId string, file io.Reader, mimeType string, filename string) error
+	DownloadAttachment(repositoryId string, branchId string, nodeId string, attachmentId string) (io.ReadCloser, error)
+	ListAttachments(repositoryId string, branchId string, nodeId string) (*cloudcms.ResultMap, error)
+	DeleteAttachment(repositoryId string, branchId string, nodeId string, attachmentId string) error
+}
+
+var _ Session = (*cloudcms.CloudCmsSession)(nil)
+
+type Action string
+
+const (
+	CreateNode         Action = "create"
+	UpdateNode         Action = "update"
+	DeleteNode         Action = "delete"
+	UploadAttachment   Action = "upload"
+	DeleteAttachment   Action = "delete-attachment"
+	WriteFile          Action = "write"
+	DownloadAttachment Action = "download"
+	RemoveFile         Action = "remove"
+)
+
+// Change is a write made, or in a dry run planned, by Push or Pull.
+type Change struct {
+	Action       Action
+	File         string
+	NodeId       string
+	AttachmentId string
+}
+
+func (c Change) String() string {
+	s := fmt.Sprintf("%s %s", c.Action, c.File)
+	if c.AttachmentId != "" {
+		s += " attachment " + c.AttachmentId
+	}
+	if c.NodeId != "" {
+		s += " (" + c.NodeId + ")"
+	}
+
+	return s
+}
+
+type Syncer struct {
+	session      Session
+	repositoryId string
+	branchId     string
+
+	// Dir is the local directory.
+	Dir string
+
+	// FolderPath is the folder on the branch that Dir maps to, e.g. "/site".
+	FolderPath string
+
+	// Delete removes nodes and attachments without a file on push, and files without a node
+	// or attachment on pull.
+	Delete bool
+
+	// DryRun reports the changes without making them.
+	DryRun bool
+
+	// Out receives progress messages.
+	Out io.Writer
+}
+
+func NewSyncer(session Session, repositoryId string, branchId string, dir string, folderPath string) *Syncer {
+	return &Syncer{
+		session:      session,
+		repositoryId: repositoryId,
+		branchId:     branchId,
+		Dir:          dir,
+		FolderPath:   "/" + strings.Trim(folderPath, "/"),
+		Out:          io.Discard,
+	}
+}
+
+func (s *Syncer) logf(format string, args ...interface{}) {
+	fmt.Fprintf(s.Out, format+"\n", args...)
+}
+
+// nodePath returns the path of the node for a file
+func (s *Syncer) nodePath(file string) string {
+	if isJson(file) {
+		file = strings.TrimSuffix(file, path.Ext(file))
+	}
+
+	return path.Join(s.FolderPath, file)
+}
+
+type marker struct {
+	file string
+
+	// attachments are the hashes of the files, and versions the remoteVersion of the
+	// attachments they were synced with
+	attachments map[string]string
+	versions    map[string]string
+}
+
+func readMarker(node cloudcms.JsonObject) marker {
+	m := marker{attachments: map[string]string{}, versions: map[string]string{}}
+
+	obj := node.GetObject(MarkerProperty)
+	if obj == nil {
+		return m
+	}
+
+	m.file = obj.GetString("file")
+	for id, hash := range obj.GetObject("attachments") {
+		if h, ok := hash.(string); ok {
+			m.attachments[id] = h
+		}
+	}
+	for id, version := range obj.GetObject("versions") {
+		if v, ok := version.(string); ok {
+			m.versions[id] = v
+		}
+	}
+
+	return m
+}
+
+func (s *Syncer) markerObject(file string, attachments map[string]string, versions map[string]string) map[string]interface{} {
+	hashes := map[string]interface{}{}
+	for id, hash := range attachments {
+		hashes[id] = hash
+	}
+	remote := map[string]interface{}{}
+	for id, version := range versions {
+		remote[id] = version
+	}
+
+	return map[string]interface{}{
+		"root":        s.FolderPath,
+		"file":        file,
+		"attachments": hashes,
+		"versions":    remote,
+	}
+}
+
+// remoteVersion identifies the content of an attachment listed by ListAttachments: its
+// checksum if it has one, or else the id of its stored object, which every upload changes.
+// It is empty if the row has neither.
+func remoteVersion(row cloudcms.JsonObject) string {
+	if checksum := row.GetString("checksum"); checksum != "" {
+		return checksum
+	}
+
+	return row.GetString("objectId")
+}
+
+// attachmentVersions returns the remoteVersion of the attachments of a node
+func (s *Syncer) attachmentVersions(nodeId string) (map[string]string, error) {
+	res, err := s.session.ListAttachments(s.repositoryId, s.branchId, nodeId)
+	if err != nil {
+		return nil, err
+	}
+
+	versions := map[string]string{}
+	for _, row := range res.Rows() {
+		if version := remoteVersion(row); version != "" {
+			versions[row.GetString("attachmentId")] = version
+		}
+	}
+
+	return versions, nil
+}
+
+func sameStrings(a map[string]string, b map[string]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for key, val := range a {
+		if b[key] != val {
+			return false
+		}
+	}
+
+	return true
+}
+
+// remoteNodes returns the synced nodes of the folder by file
+func (s *Syncer) remoteNodes() (map[string]cloudcms.JsonObject, error) {
+	nodes := map[string]cloudcms.JsonObject{}
+
+	for skip := 0; ; skip += queryPageSize {
+		res, err := s.session.QueryNodes(s.repositoryId, s.branchId, cloudcms.JsonObject{
+			MarkerProperty + ".root": s.FolderPath,
+		}, cloudcms.JsonObject{"limit": queryPageSize, "skip": skip})
+		if err != nil {
+			return nil, err
+		}
+
+		for _, node := range res.Rows() {
+			file := readMarker(node).file
+			if file == "" {
+				continue
+			}
+			if !validFile(file) {
+				return nil, fmt.Errorf("node %s has invalid file %q", cloudcms.ExtractId(&node), file)
+			}
+			nodes[file] = node
+		}
+
+		if len(res.Rows()) < queryPageSize {
+			return nodes, nil
+		}
+	}
+}
+
+// validFile reports whether file is a relative slash separated path inside the directory
+func validFile(file string) bool {
+	if file == "" || strings.HasPrefix(file, "/") || strings.Contains(file, "\\") {
+		return false
+	}
+	for _, part := range strings.Split(file, "/") {
+		if part == "" || part == "." || part == ".." {
+			return false
+		}
+	}
+
+	return true
+}
+
+func sortedNodeFiles(nodes map[string]cloudcms.JsonObject) []string {
+	files := make([]string, 0, len(nodes))
+	for file := range nodes {
+		files = append(files, file)
+	}
+	sort.Strings(files)
+
+	return files
+}
+
+func sortedEntryFiles(entries map[string]*localEntry) []string {
+	files := make([]string, 0, len(entries))
+	for file := range entries {
+		files = append(files, file)
+	}
+	sort.Strings(files)
+
+	return files
+}
+
+func sortedAttachmentIds(attachments map[string]*localAttachment) []string {
+	ids := make([]string, 0, len(attachments))
+	for id := range attachments {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	return ids
+}
@@ -0,0 +1,181 @@
+package fssync
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gitana/cloudcms-go-driver/internal/fakesession"
+)
+
+func writeFile(t *testing.T, p string, data string) {
+	err := os.MkdirAll(filepath.Dir(p), 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.WriteFile(p, []byte(data), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func expectChanges(t *testing.T, changes []Change, err error, expected ...string) {
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	actual := make([]string, len(changes))
+	for i, c := range changes {
+		actual[i] = string(c.Action) + " " + c.File
+		if c.AttachmentId != "" {
+			actual[i] += " " + c.AttachmentId
+		}
+	}
+
+	if strings.Join(actual, "\n") != strings.Join(expected, "\n") {
+		t.Fatalf("expected changes:\n%s\ngot:\n%s", strings.Join(expected, "\n"), strings.Join(actual, "\n"))
+	}
+}
+
+func TestPush(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "books", "hello.json"), `{"title": "Hello", "_type": "custom:book"}`)
+	writeFile(t, filepath.Join(dir, "books", "hello.attachments", "cover.png"), "cover")
+	writeFile(t, filepath.Join(dir, "img", "logo.png"), "logo")
+	writeFile(t, filepath.Join(dir, ".git", "config"), "ignored")
+
+	session := fakesession.New()
+	syncer := NewSyncer(session, "repo", "master", dir, "/site/")
+
+	changes, err := syncer.Push()
+	expectChanges(t, changes, err,
+		"create books/hello.json",
+		"upload books/hello.json cover",
+		"create img/logo.png",
+		"upload img/logo.png default",
+	)
+
+	bookId := session.Paths["/site/books/hello"]
+	if bookId == "" || session.Paths["/site/img/logo.png"] == "" {
+		t.Fatalf("nodes created at wrong paths: %v", session.Paths)
+	}
+	if string(session.Attachments[bookId]["cover"].Data) != "cover" {
+		t.Fatal("attachment not uploaded")
+	}
+	if session.Nodes[bookId]["_system"] == nil {
+		t.Fatal("system properties should be kept on update")
+	}
+
+	changes, err = syncer.Push()
+	expectChanges(t, changes, err)
+
+	writeFile(t, filepath.Join(dir, "books", "hello.json"), `{"title": "Hello again", "_type": "custom:book"}`)
+	changes, err = syncer.Push()
+	expectChanges(t, changes, err, "update books/hello.json")
+	if session.Nodes[bookId]["title"] != "Hello again" {
+		t.Fatal("node not updated")
+	}
+
+	writeFile(t, filepath.Join(dir, "books", "hello.attachments", "cover.png"), "new cover")
+	changes, err = syncer.Push()
+	expectChanges(t, changes, err, "upload books/hello.json cover")
+
+	os.Remove(filepath.Join(dir, "img", "logo.png"))
+	os.Remove(filepath.Join(dir, "books", "hello.attachments", "cover.png"))
+	changes, err = syncer.Push()
+	expectChanges(t, changes, err)
+
+	syncer.Delete = true
+	syncer.DryRun = true
+	changes, err = syncer.Push()
+	expectChanges(t, changes, err, "delete-attachment books/hello.json cover", "delete img/logo.png")
+	if len(session.Nodes) != 2 {
+		t.Fatal("dry run should not delete")
+	}
+
+	syncer.DryRun = false
+	changes, err = syncer.Push()
+	expectChanges(t, changes, err, "delete-attachment books/hello.json cover", "delete img/logo.png")
+	if len(session.Nodes) != 1 || len(session.Attachments[bookId]) != 0 {
+		t.Fatal("orphans not deleted")
+	}
+}
+
+func TestPull(t *testing.T) {
+	src := t.TempDir()
+	writeFile(t, filepath.Join(src, "books", "hello.json"), `{"title": "Hello"}`)
+	writeFile(t, filepath.Join(src, "books", "hello.attachments", "cover.png"), "cover")
+	writeFile(t, filepath.Join(src, "logo.png"), "logo")
+
+	session := fakesession.New()
+	_, err := NewSyncer(session, "repo", "master", src, "/site").Push()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// an editor changes the book in Cloud CMS
+	bookId := session.Paths["/site/books/hello"]
+	session.Nodes[bookId]["title"] = "Edited"
+
+	dir := filepath.Join(t.TempDir(), "site")
+	syncer := NewSyncer(session, "repo", "master", dir, "/site")
+	changes, err := syncer.Pull()
+	expectChanges(t, changes, err,
+		"write books/hello.json",
+		"download books/hello.json cover",
+		"download logo.png default",
+	)
+
+	data, err := os.ReadFile(filepath.Join(dir, "books", "hello.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "{\n  \"title\": \"Edited\"\n}\n" {
+		t.Fatalf("unexpected file:\n%s", data)
+	}
+	data, _ = os.ReadFile(filepath.Join(dir, "books", "hello.attachments", "cover.png"))
+	if string(data) != "cover" {
+		t.Fatal("attachment not downloaded")
+	}
+	data, _ = os.ReadFile(filepath.Join(dir, "logo.png"))
+	if string(data) != "logo" {
+		t.Fatal("asset not downloaded")
+	}
+
+	changes, err = syncer.Pull()
+	expectChanges(t, changes, err)
+
+	// an editor replaces the cover with one of the same size
+	session.UploadAttachment("repo", "master", bookId, "cover", strings.NewReader("COVER"), "image/png", "cover.png")
+	changes, err = syncer.Pull()
+	expectChanges(t, changes, err, "download books/hello.json cover")
+	data, _ = os.ReadFile(filepath.Join(dir, "books", "hello.attachments", "cover.png"))
+	if string(data) != "COVER" {
+		t.Fatal("replaced attachment not downloaded")
+	}
+	changes, err = syncer.Push()
+	expectChanges(t, changes, err)
+
+	writeFile(t, filepath.Join(dir, "stale.json"), `{}`)
+	syncer.Delete = true
+	changes, err = syncer.Pull()
+	expectChanges(t, changes, err, "remove stale.json")
+	if _, err := os.Stat(filepath.Join(dir, "stale.json")); !os.IsNotExist(err) {
+		t.Fatal("stale file not removed")
+	}
+}
+
+func TestValidFile(t *testing.T) {
+	for file, valid := range map[string]bool{
+		"books/hello.json": true,
+		"../escape.json":   false,
+		"/abs.json":        false,
+		"a//b.json":        false,
+		`a\b.json`:         false,
+	} {
+		if validFile(file) != valid {
+			t.Fatalf("validFile(%q) should be %v", file, valid)
+		}
+	}
+}
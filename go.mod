@@ -1,6 +1,6 @@
 module github.com/gitana/cloudcms-go-driver
 
-go 1.18
+go 1.21
 
 require golang.org/x/oauth2 v0.0.0-20220411215720-9780585627b5
 
@@ -1,33 +1,198 @@
 package cloudcms
 
 import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
-	"net/url"
+	"strings"
+
+	"github.com/gitana/cloudcms-go-driver/graphql"
 )
 
+type GraphQLRequest struct {
+	Query         string                 `json:"query,omitempty"`
+	OperationName string                 `json:"operationName,omitempty"`
+	Variables     map[string]interface{} `json:"variables,omitempty"`
+	Extensions    map[string]interface{} `json:"extensions,omitempty"`
+
+	// Persisted sends only the sha256 hash of Query, as an automatic persisted query.
+	// If the server does not know the hash yet, the request is repeated with the full query.
+	Persisted bool `json:"-"`
+}
+
+type GraphQLLocation struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
+
+type GraphQLError struct {
+	Message    string            `json:"message"`
+	Path       []interface{}     `json:"path,omitempty"`
+	Locations  []GraphQLLocation `json:"locations,omitempty"`
+	Extensions JsonObject        `json:"extensions,omitempty"`
+}
+
+func (e GraphQLError) Error() string {
+	var b strings.Builder
+	b.WriteString(e.Message)
+
+	if len(e.Path) > 0 {
+		parts := make([]string, len(e.Path))
+		for i, p := range e.Path {
+			parts[i] = fmt.Sprintf("%v", p)
+		}
+		fmt.Fprintf(&b, " at %s", strings.Join(parts, "."))
+	}
+
+	for _, loc := range e.Locations {
+		fmt.Fprintf(&b, " (line %d, column %d)", loc.Line, loc.Column)
+	}
+
+	return b.String()
+}
+
+// GraphQLErrors is returned when a GraphQL response carries errors. Any partial data in the
+// response is still decoded.
+type GraphQLErrors []GraphQLError
+
+func (errs GraphQLErrors) Error() string {
+	msgs := make([]string, len(errs))
+	for i, e := range errs {
+		msgs[i] = e.Error()
+	}
+
+	return "graphql: " + strings.Join(msgs, "; ")
+}
+
+type graphQLResponse struct {
+	Data   json.RawMessage `json:"data"`
+	Errors GraphQLErrors   `json:"errors"`
+}
+
+// GraphQL posts req to the branch's GraphQL endpoint and decodes the response data into data,
+// which may be nil.
+func (session *CloudCmsSession) GraphQL(repositoryId string, branchId string, req *GraphQLRequest, data interface{}) error {
+	session = session.named("GraphQL")
+	raw, err := session.graphQLPost(repositoryId, branchId, req)
+	if err != nil {
+		return err
+	}
+
+	var res graphQLResponse
+	err = json.Unmarshal(raw, &res)
+	if err != nil {
+		return err
+	}
+
+	if data != nil && len(res.Data) > 0 && string(res.Data) != "null" {
+		err = json.Unmarshal(res.Data, data)
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(res.Errors) > 0 {
+		return res.Errors
+	}
+
+	return nil
+}
+
+// GraphQLQuery returns the whole GraphQL response. If the response has errors, it is returned
+// together with GraphQLErrors.
 func (session *CloudCmsSession) GraphQLQuery(repositoryId string, branchId string, query string, operationName string, variables JsonObject) (JsonObject, error) {
+	session = session.named("GraphQLQuery")
+	raw, err := session.graphQLPost(repositoryId, branchId, &GraphQLRequest{
+		Query:         query,
+		OperationName: operationName,
+		Variables:     variables,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var res JsonObject
+	err = json.Unmarshal(raw, &res)
+	if err != nil {
+		return nil, err
+	}
+
+	var errs struct {
+		Errors GraphQLErrors `json:"errors"`
+	}
+	json.Unmarshal(raw, &errs)
+	if len(errs.Errors) > 0 {
+		return res, errs.Errors
+	}
+
+	return res, nil
+}
+
+func (session *CloudCmsSession) graphQLPost(repositoryId string, branchId string, req *GraphQLRequest) (json.RawMessage, error) {
 	uri := fmt.Sprintf("/repositories/%s/branches/%s/graphql", repositoryId, branchId)
-	params := url.Values{"query": []string{query}}
-	if operationName != "" {
-		params.Add("operationName", operationName)
+
+	if !req.Persisted {
+		return session.graphQLSend(uri, req)
 	}
 
-	if variables != nil {
-		variablesStr, _ := json.Marshal(variables)
-		params.Add("variables", string(variablesStr))
+	hash := sha256.Sum256([]byte(req.Query))
+	extensions := map[string]interface{}{}
+	for key, val := range req.Extensions {
+		extensions[key] = val
+	}
+	extensions["persistedQuery"] = map[string]interface{}{
+		"version":    1,
+		"sha256Hash": hex.EncodeToString(hash[:]),
 	}
 
-	res, err := session.Get(uri, params)
+	persisted := *req
+	persisted.Extensions = extensions
+	persisted.Query = ""
+
+	raw, err := session.graphQLSend(uri, &persisted)
 	if err != nil {
 		return nil, err
 	}
 
-	return res, nil
+	var res graphQLResponse
+	if json.Unmarshal(raw, &res) == nil && persistedQueryNotFound(res.Errors) {
+		persisted.Query = req.Query
+		return session.graphQLSend(uri, &persisted)
+	}
+
+	return raw, nil
+}
+
+func (session *CloudCmsSession) graphQLSend(uri string, req *GraphQLRequest) (json.RawMessage, error) {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+
+	var raw json.RawMessage
+	err = session.requestInto("POST", uri, nil, bytes.NewReader(body), &raw)
+	if err != nil {
+		return nil, err
+	}
+
+	return raw, nil
+}
+
+func persistedQueryNotFound(errs GraphQLErrors) bool {
+	for _, e := range errs {
+		if e.Message == "PersistedQueryNotFound" || e.Extensions.GetString("code") == "PERSISTED_QUERY_NOT_FOUND" {
+			return true
+		}
+	}
+
+	return false
 }
 
 func (session *CloudCmsSession) GraphQLSchema(repositoryId string, branchId string) (string, error) {
+	session = session.named("GraphQLSchema")
 	uri := fmt.Sprintf("/repositories/%s/branches/%s/graphql/schema", repositoryId, branchId)
 	reader, err := session.Download(uri, nil)
 	if err != nil {
@@ -42,3 +207,31 @@ func (session *CloudCmsSession) GraphQLSchema(repositoryId string, branchId stri
 
 	return string(bytes), nil
 }
+
+// ReadGraphQLSchema reads and parses the GraphQL schema of a branch
+func (session *CloudCmsSession) ReadGraphQLSchema(repositoryId string, branchId string) (*graphql.Schema, error) {
+	session = session.named("ReadGraphQLSchema")
+	sdl, err := session.GraphQLSchema(repositoryId, branchId)
+	if err != nil {
+		return nil, err
+	}
+
+	return graphql.ParseSchema(sdl)
+}
+
+// DiffGraphQLSchemas compares the GraphQL schemas of two branches. Breaking changes are those
+// that may fail queries written against fromBranchId when run against toBranchId.
+func (session *CloudCmsSession) DiffGraphQLSchemas(repositoryId string, fromBranchId string, toBranchId string) ([]graphql.Change, error) {
+	session = session.named("DiffGraphQLSchemas")
+	from, err := session.ReadGraphQLSchema(repositoryId, fromBranchId)
+	if err != nil {
+		return nil, err
+	}
+
+	to, err := session.ReadGraphQLSchema(repositoryId, toBranchId)
+	if err != nil {
+		return nil, err
+	}
+
+	return graphql.Diff(from, to), nil
+}
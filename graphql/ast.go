@@ -0,0 +1,371 @@
+package graphql
+
+import (
+	"sort"
+	"strings"
+)
+
+type TypeKind string
+
+const (
+	KindScalar      TypeKind = "SCALAR"
+	KindObject      TypeKind = "OBJECT"
+	KindInterface   TypeKind = "INTERFACE"
+	KindUnion       TypeKind = "UNION"
+	KindEnum        TypeKind = "ENUM"
+	KindInputObject TypeKind = "INPUT_OBJECT"
+)
+
+// TypeRef is a reference to a named type, or a list of Elem, possibly non-null.
+type TypeRef struct {
+	Name    string
+	Elem    *TypeRef
+	NonNull bool
+}
+
+func (t *TypeRef) String() string {
+	var s string
+	if t.Elem != nil {
+		s = "[" + t.Elem.String() + "]"
+	} else {
+		s = t.Name
+	}
+
+	if t.NonNull {
+		s += "!"
+	}
+
+	return s
+}
+
+func (t *TypeRef) IsList() bool {
+	return t.Elem != nil
+}
+
+// NamedType returns the name of the innermost named type, e.g. Book for [Book!]!.
+func (t *TypeRef) NamedType() string {
+	for t.Elem != nil {
+		t = t.Elem
+	}
+
+	return t.Name
+}
+
+type Schema struct {
+	QueryType        string
+	MutationType     string
+	SubscriptionType string
+	Types            map[string]*TypeDefinition
+	Directives       map[string]*DirectiveDefinition
+}
+
+type TypeDefinition struct {
+	Kind          TypeKind
+	Name          string
+	Description   string
+	Fields        []*FieldDefinition
+	Interfaces    []string
+	PossibleTypes []string
+	EnumValues    []*EnumValueDefinition
+	InputFields   []*InputValueDefinition
+	Location      Location
+}
+
+type FieldDefinition struct {
+	Name              string
+	Description       string
+	Arguments         []*InputValueDefinition
+	Type              *TypeRef
+	Deprecated        bool
+	DeprecationReason string
+}
+
+type InputValueDefinition struct {
+	Name         string
+	Description  string
+	Type         *TypeRef
+	DefaultValue *Value
+}
+
+type EnumValueDefinition struct {
+	Name        string
+	Description string
+	Deprecated  bool
+}
+
+type DirectiveDefinition struct {
+	Name        string
+	Description string
+	Arguments   []*InputValueDefinition
+	Locations   []string
+}
+
+var builtinScalars = []string{"Int", "Float", "String", "Boolean", "ID"}
+
+func IsBuiltinScalar(name string) bool {
+	for _, s := range builtinScalars {
+		if s == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (s *Schema) Type(name string) *TypeDefinition {
+	return s.Types[name]
+}
+
+func (s *Schema) Query() *TypeDefinition {
+	return s.Types[s.QueryType]
+}
+
+func (s *Schema) Mutation() *TypeDefinition {
+	return s.Types[s.MutationType]
+}
+
+func (s *Schema) Subscription() *TypeDefinition {
+	return s.Types[s.SubscriptionType]
+}
+
+// RootType returns the root type for an operation type: "query", "mutation" or "subscription".
+func (s *Schema) RootType(operation string) *TypeDefinition {
+	switch operation {
+	case "query":
+		return s.Query()
+	case "mutation":
+		return s.Mutation()
+	case "subscription":
+		return s.Subscription()
+	}
+
+	return nil
+}
+
+// TypeNames returns the names of all user defined types, sorted.
+func (s *Schema) TypeNames() []string {
+	names := []string{}
+	for name := range s.Types {
+		if !IsBuiltinScalar(name) && !strings.HasPrefix(name, "__") {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// QueryableTypes returns the object types returned by fields of the query root, keyed by the
+// field name. For Cloud CMS these are the content types exposed through GraphQL.
+func (s *Schema) QueryableTypes() map[string]*TypeDefinition {
+	res := map[string]*TypeDefinition{}
+
+	query := s.Query()
+	if query == nil {
+		return res
+	}
+
+	for _, field := range query.Fields {
+		t := s.Types[field.Type.NamedType()]
+		if t != nil && t.Kind == KindObject {
+			res[field.Name] = t
+		}
+	}
+
+	return res
+}
+
+func (t *TypeDefinition) Field(name string) *FieldDefinition {
+	for _, f := range t.Fields {
+		if f.Name == name {
+			return f
+		}
+	}
+
+	return nil
+}
+
+func (t *TypeDefinition) InputField(name string) *InputValueDefinition {
+	return findInputValue(t.InputFields, name)
+}
+
+func (t *TypeDefinition) EnumValue(name string) *EnumValueDefinition {
+	for _, v := range t.EnumValues {
+		if v.Name == name {
+			return v
+		}
+	}
+
+	return nil
+}
+
+func (t *TypeDefinition) IsComposite() bool {
+	return t.Kind == KindObject || t.Kind == KindInterface || t.Kind == KindUnion
+}
+
+func (t *TypeDefinition) IsInput() bool {
+	return t.Kind == KindScalar || t.Kind == KindEnum || t.Kind == KindInputObject
+}
+
+func (f *FieldDefinition) Argument(name string) *InputValueDefinition {
+	return findInputValue(f.Arguments, name)
+}
+
+func findInputValue(values []*InputValueDefinition, name string) *InputValueDefinition {
+	for _, v := range values {
+		if v.Name == name {
+			return v
+		}
+	}
+
+	return nil
+}
+
+type Document struct {
+	Operations []*Operation
+	Fragments  []*FragmentDefinition
+}
+
+func (d *Document) Operation(name string) *Operation {
+	for _, op := range d.Operations {
+		if op.Name == name {
+			return op
+		}
+	}
+
+	return nil
+}
+
+func (d *Document) Fragment(name string) *FragmentDefinition {
+	for _, f := range d.Fragments {
+		if f.Name == name {
+			return f
+		}
+	}
+
+	return nil
+}
+
+type Operation struct {
+	// Type is "query", "mutation" or "subscription"
+	Type         string
+	Name         string
+	Variables    []*VariableDefinition
+	Directives   []*Directive
+	SelectionSet []Selection
+	Location     Location
+}
+
+type VariableDefinition struct {
+	Name         string
+	Type         *TypeRef
+	DefaultValue *Value
+	Location     Location
+}
+
+type FragmentDefinition struct {
+	Name          string
+	TypeCondition string
+	Directives    []*Directive
+	SelectionSet  []Selection
+	Location      Location
+}
+
+// Selection is a *Field, *FragmentSpread or *InlineFragment.
+type Selection interface {
+	Loc() Location
+}
+
+type Field struct {
+	Alias        string
+	Name         string
+	Arguments    []*Argument
+	Directives   []*Directive
+	SelectionSet []Selection
+	Location     Location
+}
+
+func (f *Field) Loc() Location {
+	return f.Location
+}
+
+// ResponseKey is the key of the field in the response: its alias if it has one.
+func (f *Field) ResponseKey() string {
+	if f.Alias != "" {
+		return f.Alias
+	}
+
+	return f.Name
+}
+
+type FragmentSpread struct {
+	Name       string
+	Directives []*Directive
+	Location   Location
+}
+
+func (f *FragmentSpread) Loc() Location {
+	return f.Location
+}
+
+type InlineFragment struct {
+	TypeCondition string
+	Directives    []*Directive
+	SelectionSet  []Selection
+	Location      Location
+}
+
+func (f *InlineFragment) Loc() Location {
+	return f.Location
+}
+
+type Argument struct {
+	Name     string
+	Value    *Value
+	Location Location
+}
+
+type Directive struct {
+	Name      string
+	Arguments []*Argument
+	Location  Location
+}
+
+type ValueKind int
+
+const (
+	ValueVariable ValueKind = iota
+	ValueInt
+	ValueFloat
+	ValueString
+	ValueBoolean
+	ValueNull
+	ValueEnum
+	ValueList
+	ValueObject
+)
+
+// Value is an input value literal. Raw holds the variable name, the scalar literal as written,
+// the unquoted string or the enum value name.
+type Value struct {
+	Kind     ValueKind
+	Raw      string
+	List     []*Value
+	Fields   []*ObjectField
+	Location Location
+}
+
+type ObjectField struct {
+	Name  string
+	Value *Value
+}
+
+func (v *Value) Field(name string) *Value {
+	for _, f := range v.Fields {
+		if f.Name == name {
+			return f.Value
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,220 @@
+package graphql
+
+import (
+	"fmt"
+	"sort"
+)
+
+type ChangeKind string
+
+const (
+	TypeAdded           ChangeKind = "TYPE_ADDED"
+	TypeRemoved         ChangeKind = "TYPE_REMOVED"
+	TypeKindChanged     ChangeKind = "TYPE_KIND_CHANGED"
+	FieldAdded          ChangeKind = "FIELD_ADDED"
+	FieldRemoved        ChangeKind = "FIELD_REMOVED"
+	FieldTypeChanged    ChangeKind = "FIELD_TYPE_CHANGED"
+	ArgumentAdded       ChangeKind = "ARGUMENT_ADDED"
+	ArgumentRemoved     ChangeKind = "ARGUMENT_REMOVED"
+	ArgumentTypeChanged ChangeKind = "ARGUMENT_TYPE_CHANGED"
+	EnumValueAdded      ChangeKind = "ENUM_VALUE_ADDED"
+	EnumValueRemoved    ChangeKind = "ENUM_VALUE_REMOVED"
+	MemberAdded         ChangeKind = "MEMBER_ADDED"
+	MemberRemoved       ChangeKind = "MEMBER_REMOVED"
+	RootTypeChanged     ChangeKind = "ROOT_TYPE_CHANGED"
+)
+
+// Change is a difference between two schemas. Path names the affected element,
+// e.g. "Book", "Book.title" or "Query.books(limit)".
+type Change struct {
+	Kind     ChangeKind
+	Path     string
+	Message  string
+	Breaking bool
+}
+
+func (c Change) String() string {
+	if c.Breaking {
+		return fmt.Sprintf("BREAKING %s: %s", c.Path, c.Message)
+	}
+
+	return fmt.Sprintf("%s: %s", c.Path, c.Message)
+}
+
+// Diff compares two schemas. Changes are breaking when an operation that is valid against
+// from may fail against to.
+func Diff(from *Schema, to *Schema) []Change {
+	d := &differ{}
+
+	for op, names := range map[string][2]string{
+		"query":        {from.QueryType, to.QueryType},
+		"mutation":     {from.MutationType, to.MutationType},
+		"subscription": {from.SubscriptionType, to.SubscriptionType},
+	} {
+		if names[0] != names[1] {
+			d.add(RootTypeChanged, "schema."+op, names[0] != "", "%s root changed from %q to %q", op, names[0], names[1])
+		}
+	}
+
+	for name, fromType := range from.Types {
+		toType := to.Types[name]
+		if toType == nil {
+			d.add(TypeRemoved, name, true, "%s %s was removed", fromType.Kind, name)
+			continue
+		}
+		if fromType.Kind != toType.Kind {
+			d.add(TypeKindChanged, name, true, "changed from %s to %s", fromType.Kind, toType.Kind)
+			continue
+		}
+		d.typeDefinition(fromType, toType)
+	}
+
+	for name, toType := range to.Types {
+		if from.Types[name] == nil {
+			d.add(TypeAdded, name, false, "%s %s was added", toType.Kind, name)
+		}
+	}
+
+	sort.SliceStable(d.changes, func(i, j int) bool {
+		if d.changes[i].Path != d.changes[j].Path {
+			return d.changes[i].Path < d.changes[j].Path
+		}
+		return d.changes[i].Kind < d.changes[j].Kind
+	})
+
+	return d.changes
+}
+
+// Breaking returns the breaking changes among changes.
+func Breaking(changes []Change) []Change {
+	res := []Change{}
+	for _, c := range changes {
+		if c.Breaking {
+			res = append(res, c)
+		}
+	}
+
+	return res
+}
+
+type differ struct {
+	changes []Change
+}
+
+func (d *differ) add(kind ChangeKind, path string, breaking bool, format string, args ...interface{}) {
+	d.changes = append(d.changes, Change{Kind: kind, Path: path, Breaking: breaking, Message: fmt.Sprintf(format, args...)})
+}
+
+func (d *differ) typeDefinition(from *TypeDefinition, to *TypeDefinition) {
+	for _, f := range from.Fields {
+		path := from.Name + "." + f.Name
+		g := to.Field(f.Name)
+		if g == nil {
+			d.add(FieldRemoved, path, true, "field was removed")
+			continue
+		}
+		if f.Type.String() != g.Type.String() {
+			d.add(FieldTypeChanged, path, !safeOutputChange(f.Type, g.Type), "type changed from %s to %s", f.Type, g.Type)
+		}
+		d.arguments(path, f.Arguments, g.Arguments)
+	}
+	for _, g := range to.Fields {
+		if from.Field(g.Name) == nil {
+			d.add(FieldAdded, to.Name+"."+g.Name, false, "field was added")
+		}
+	}
+
+	for _, f := range from.InputFields {
+		path := from.Name + "." + f.Name
+		g := to.InputField(f.Name)
+		if g == nil {
+			d.add(FieldRemoved, path, true, "input field was removed")
+			continue
+		}
+		if f.Type.String() != g.Type.String() {
+			d.add(FieldTypeChanged, path, !safeInputChange(f.Type, g.Type), "type changed from %s to %s", f.Type, g.Type)
+		}
+	}
+	for _, g := range to.InputFields {
+		if from.InputField(g.Name) == nil {
+			required := g.Type.NonNull && g.DefaultValue == nil
+			d.add(FieldAdded, to.Name+"."+g.Name, required, "input field of type %s was added", g.Type)
+		}
+	}
+
+	for _, v := range from.EnumValues {
+		if to.EnumValue(v.Name) == nil {
+			d.add(EnumValueRemoved, from.Name+"."+v.Name, true, "enum value was removed")
+		}
+	}
+	for _, v := range to.EnumValues {
+		if from.EnumValue(v.Name) == nil {
+			d.add(EnumValueAdded, to.Name+"."+v.Name, false, "enum value was added")
+		}
+	}
+
+	fromMembers := append(append([]string{}, from.PossibleTypes...), from.Interfaces...)
+	toMembers := append(append([]string{}, to.PossibleTypes...), to.Interfaces...)
+	for _, m := range fromMembers {
+		if !containsString(toMembers, m) {
+			d.add(MemberRemoved, from.Name, true, "%s was removed", m)
+		}
+	}
+	for _, m := range toMembers {
+		if !containsString(fromMembers, m) {
+			d.add(MemberAdded, to.Name, false, "%s was added", m)
+		}
+	}
+}
+
+func (d *differ) arguments(path string, from []*InputValueDefinition, to []*InputValueDefinition) {
+	for _, a := range from {
+		argPath := fmt.Sprintf("%s(%s)", path, a.Name)
+		b := findInputValue(to, a.Name)
+		if b == nil {
+			d.add(ArgumentRemoved, argPath, true, "argument was removed")
+			continue
+		}
+		if a.Type.String() != b.Type.String() {
+			d.add(ArgumentTypeChanged, argPath, !safeInputChange(a.Type, b.Type), "type changed from %s to %s", a.Type, b.Type)
+		}
+	}
+	for _, b := range to {
+		if findInputValue(from, b.Name) == nil {
+			required := b.Type.NonNull && b.DefaultValue == nil
+			d.add(ArgumentAdded, fmt.Sprintf("%s(%s)", path, b.Name), required, "argument of type %s was added", b.Type)
+		}
+	}
+}
+
+// safeOutputChange reports whether clients reading a field of type from can read type to,
+// which holds when to only adds non-null constraints.
+func safeOutputChange(from *TypeRef, to *TypeRef) bool {
+	if from.NonNull && !to.NonNull {
+		return false
+	}
+	if (from.Elem == nil) != (to.Elem == nil) {
+		return false
+	}
+	if from.Elem != nil {
+		return safeOutputChange(from.Elem, to.Elem)
+	}
+
+	return from.Name == to.Name
+}
+
+// safeInputChange reports whether values valid for input type from are valid for to,
+// which holds when to only drops non-null constraints.
+func safeInputChange(from *TypeRef, to *TypeRef) bool {
+	return safeOutputChange(to, from)
+}
+
+func containsString(arr []string, s string) bool {
+	for _, v := range arr {
+		if v == s {
+			return true
+		}
+	}
+
+	return false
+}
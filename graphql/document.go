@@ -0,0 +1,218 @@
+package graphql
+
+// ParseQuery parses an executable document: operations and fragments.
+func ParseQuery(query string) (*Document, error) {
+	p, err := newParser(query)
+	if err != nil {
+		return nil, err
+	}
+
+	doc := &Document{}
+	for p.tok.kind != tokenEOF {
+		switch {
+		case p.peek("{"):
+			op := &Operation{Type: "query", Location: p.tok.loc}
+			op.SelectionSet, err = p.selectionSet()
+			doc.Operations = append(doc.Operations, op)
+		case p.peekKeyword("query"), p.peekKeyword("mutation"), p.peekKeyword("subscription"):
+			var op *Operation
+			op, err = p.operation()
+			doc.Operations = append(doc.Operations, op)
+		case p.peekKeyword("fragment"):
+			var f *FragmentDefinition
+			f, err = p.fragmentDefinition()
+			doc.Fragments = append(doc.Fragments, f)
+		default:
+			err = p.unexpected()
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if len(doc.Operations) == 0 && len(doc.Fragments) == 0 {
+		return nil, p.errorf("document has no operations")
+	}
+
+	return doc, nil
+}
+
+func (p *parser) operation() (*Operation, error) {
+	op := &Operation{Type: p.tok.value, Location: p.tok.loc}
+	err := p.advance()
+	if err != nil {
+		return nil, err
+	}
+
+	if p.tok.kind == tokenName {
+		op.Name, err = p.name()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if p.peek("(") {
+		err = p.many("(", ")", func() error {
+			v := &VariableDefinition{Location: p.tok.loc}
+			err := p.expect("$")
+			if err != nil {
+				return err
+			}
+			v.Name, err = p.name()
+			if err != nil {
+				return err
+			}
+			err = p.expect(":")
+			if err != nil {
+				return err
+			}
+			v.Type, err = p.typeRef()
+			if err != nil {
+				return err
+			}
+			if p.peek("=") {
+				err = p.advance()
+				if err != nil {
+					return err
+				}
+				v.DefaultValue, err = p.value(true)
+				if err != nil {
+					return err
+				}
+			}
+			_, err = p.directives(true)
+			op.Variables = append(op.Variables, v)
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	op.Directives, err = p.directives(false)
+	if err != nil {
+		return nil, err
+	}
+
+	op.SelectionSet, err = p.selectionSet()
+	return op, err
+}
+
+func (p *parser) fragmentDefinition() (*FragmentDefinition, error) {
+	f := &FragmentDefinition{Location: p.tok.loc}
+	err := p.expectKeyword("fragment")
+	if err != nil {
+		return nil, err
+	}
+
+	f.Name, err = p.name()
+	if err != nil {
+		return nil, err
+	}
+	if f.Name == "on" {
+		return nil, p.errorf("fragment cannot be named \"on\"")
+	}
+
+	err = p.expectKeyword("on")
+	if err != nil {
+		return nil, err
+	}
+
+	f.TypeCondition, err = p.name()
+	if err != nil {
+		return nil, err
+	}
+
+	f.Directives, err = p.directives(false)
+	if err != nil {
+		return nil, err
+	}
+
+	f.SelectionSet, err = p.selectionSet()
+	return f, err
+}
+
+func (p *parser) selectionSet() ([]Selection, error) {
+	selections := []Selection{}
+	err := p.many("{", "}", func() error {
+		selection, err := p.selection()
+		selections = append(selections, selection)
+		return err
+	})
+
+	return selections, err
+}
+
+func (p *parser) selection() (Selection, error) {
+	loc := p.tok.loc
+
+	if p.peek("...") {
+		err := p.advance()
+		if err != nil {
+			return nil, err
+		}
+
+		if p.tok.kind == tokenName && p.tok.value != "on" {
+			spread := &FragmentSpread{Location: loc}
+			spread.Name, err = p.name()
+			if err != nil {
+				return nil, err
+			}
+			spread.Directives, err = p.directives(false)
+			return spread, err
+		}
+
+		inline := &InlineFragment{Location: loc}
+		if p.peekKeyword("on") {
+			err = p.advance()
+			if err != nil {
+				return nil, err
+			}
+			inline.TypeCondition, err = p.name()
+			if err != nil {
+				return nil, err
+			}
+		}
+		inline.Directives, err = p.directives(false)
+		if err != nil {
+			return nil, err
+		}
+		inline.SelectionSet, err = p.selectionSet()
+		return inline, err
+	}
+
+	f := &Field{Location: loc}
+	name, err := p.name()
+	if err != nil {
+		return nil, err
+	}
+
+	if p.peek(":") {
+		err = p.advance()
+		if err != nil {
+			return nil, err
+		}
+		f.Alias = name
+		name, err = p.name()
+		if err != nil {
+			return nil, err
+		}
+	}
+	f.Name = name
+
+	f.Arguments, err = p.arguments(false)
+	if err != nil {
+		return nil, err
+	}
+
+	f.Directives, err = p.directives(false)
+	if err != nil {
+		return nil, err
+	}
+
+	if p.peek("{") {
+		f.SelectionSet, err = p.selectionSet()
+	}
+
+	return f, err
+}
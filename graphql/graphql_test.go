@@ -0,0 +1,204 @@
+package graphql
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+)
+
+func loadSchema(t *testing.T) *Schema {
+	sdl, err := os.ReadFile("testdata/schema.graphql")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	schema, err := ParseSchema(string(sdl))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return schema
+}
+
+func TestParseSchema(t *testing.T) {
+	schema := loadSchema(t)
+
+	if schema.QueryType != "Query" || schema.MutationType != "Mutation" {
+		t.Fatal("wrong root types")
+	}
+
+	book := schema.Type("custom_book")
+	if book == nil || book.Kind != KindObject {
+		t.Fatal("custom_book not parsed")
+	}
+	if len(book.Interfaces) != 1 || book.Interfaces[0] != "Node" {
+		t.Fatal("interfaces not parsed")
+	}
+
+	title := book.Field("title")
+	if title.Type.String() != "String!" || title.Description != "The book title" {
+		t.Fatal("title field not parsed")
+	}
+	if book.Field("tags").Type.String() != "[String!]" {
+		t.Fatal("list type not parsed")
+	}
+	if !book.Field("isbn").Deprecated || book.Field("isbn").DeprecationReason != "use identifiers" {
+		t.Fatal("deprecation not parsed")
+	}
+
+	books := schema.Query().Field("custom_books")
+	if books.Argument("sort").Type.String() != "[Sort!]" {
+		t.Fatal("arguments not parsed")
+	}
+
+	sort := schema.Type("Sort")
+	if sort.Kind != KindInputObject || sort.InputField("direction").DefaultValue.Raw != "ASC" {
+		t.Fatal("input type not parsed")
+	}
+
+	if len(schema.Type("SearchResult").PossibleTypes) != 2 {
+		t.Fatal("union not parsed")
+	}
+	if len(schema.Type("SortDirection").EnumValues) != 2 {
+		t.Fatal("enum not parsed")
+	}
+
+	queryable := schema.QueryableTypes()
+	if queryable["custom_books"] != book || queryable["custom_publishers"] == nil || queryable["search"] != nil {
+		t.Fatal("wrong queryable types")
+	}
+}
+
+func TestParseSchemaErrors(t *testing.T) {
+	_, err := ParseSchema("type Query { books: [Book] }")
+	if err == nil || !strings.Contains(err.Error(), "undefined type Book") {
+		t.Fatalf("expected undefined type error, got %v", err)
+	}
+
+	_, err = ParseSchema("type Query { books: }")
+	var syntaxErr *SyntaxError
+	if !errors.As(err, &syntaxErr) || syntaxErr.Location.Column != 21 {
+		t.Fatalf("expected syntax error at column 21, got %v", err)
+	}
+}
+
+func TestValidateQuery(t *testing.T) {
+	schema := loadSchema(t)
+
+	valid := `
+		query Books($limit: Int, $sort: [Sort!]) {
+			custom_books(limit: $limit, sort: $sort) {
+				...bookFields
+				publisher { name books(limit: 2) { title } }
+			}
+			search(text: "bean") {
+				__typename
+				... on custom_book { title }
+			}
+		}
+
+		fragment bookFields on custom_book {
+			_doc
+			title @include(if: true)
+			tags
+		}
+
+		mutation Rename {
+			updateBook(id: "abc", title: "new") { title }
+		}
+	`
+	if err := schema.ValidateQuery(valid); err != nil {
+		t.Fatal(err)
+	}
+
+	invalid := `
+		query Books($unused: Int) {
+			custom_books(limit: "ten", sort: {field: "title", direction: UP}) {
+				title
+				summary
+				publisher
+			}
+			custom_book { title { value } }
+			...missing
+		}
+	`
+	err := schema.ValidateQuery(invalid)
+
+	var errs ValidationErrors
+	if !errors.As(err, &errs) {
+		t.Fatalf("expected validation errors, got %v", err)
+	}
+
+	expected := []string{
+		`argument "limit" expects type Int`,
+		`field "direction" expects type SortDirection`,
+		`cannot query field "summary" on type custom_book`,
+		`field "publisher" of type custom_publisher must have a selection of subfields`,
+		`Query.custom_book is missing required argument "id" of type ID!`,
+		`field "title" of type String! cannot have a selection`,
+		`unknown fragment "missing"`,
+		`variable $unused is never used`,
+	}
+	if len(errs) != len(expected) {
+		t.Fatalf("expected %d errors, got %v", len(expected), errs)
+	}
+	for i, msg := range expected {
+		if errs[i].Message != msg {
+			t.Fatalf("error %d: expected %q, got %q", i, msg, errs[i].Message)
+		}
+	}
+}
+
+func TestDiff(t *testing.T) {
+	from := loadSchema(t)
+
+	to, err := ParseSchema(`
+		type custom_book {
+			_doc: ID!
+			title: String
+			author: String!
+			pages: Int
+			summary: String
+		}
+
+		type Query {
+			custom_books(q: String, limit: Int, lang: String!): [custom_book]
+			custom_book(id: ID!): custom_book
+		}
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	changes := Diff(from, to)
+	index := map[string]Change{}
+	for _, c := range changes {
+		index[string(c.Kind)+" "+c.Path] = c
+	}
+
+	cases := map[string]bool{
+		"FIELD_TYPE_CHANGED custom_book.title":      true,
+		"FIELD_TYPE_CHANGED custom_book.author":     false,
+		"FIELD_REMOVED custom_book.price":           true,
+		"FIELD_ADDED custom_book.summary":           false,
+		"ARGUMENT_REMOVED Query.custom_books(sort)": true,
+		"ARGUMENT_ADDED Query.custom_books(lang)":   true,
+		"TYPE_REMOVED custom_publisher":             true,
+		"MEMBER_REMOVED custom_book":                true,
+		"ROOT_TYPE_CHANGED schema.mutation":         true,
+	}
+	for key, breaking := range cases {
+		c, ok := index[key]
+		if !ok {
+			t.Fatalf("missing change %s in %v", key, changes)
+		}
+		if c.Breaking != breaking {
+			t.Fatalf("%s: expected breaking=%v", key, breaking)
+		}
+	}
+
+	if len(Diff(from, from)) != 0 {
+		t.Fatal("identical schemas should have no changes")
+	}
+}
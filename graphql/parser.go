@@ -0,0 +1,270 @@
+package graphql
+
+type parser struct {
+	lex *lexer
+	tok token
+}
+
+func newParser(src string) (*parser, error) {
+	p := &parser{lex: newLexer(src)}
+	err := p.advance()
+	if err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+func (p *parser) advance() error {
+	tok, err := p.lex.next()
+	if err != nil {
+		return err
+	}
+
+	p.tok = tok
+	return nil
+}
+
+func (p *parser) errorf(format string, args ...interface{}) error {
+	return p.lex.errorf(p.tok.loc, format, args...)
+}
+
+func (p *parser) unexpected() error {
+	if p.tok.kind == tokenEOF {
+		return p.errorf("unexpected end of input")
+	}
+
+	return p.errorf("unexpected %s %q", p.tok.kind, p.tok.value)
+}
+
+func (p *parser) peek(punct string) bool {
+	return p.tok.kind == tokenPunct && p.tok.value == punct
+}
+
+func (p *parser) peekKeyword(keyword string) bool {
+	return p.tok.kind == tokenName && p.tok.value == keyword
+}
+
+// skip consumes punct if it is next
+func (p *parser) skip(punct string) (bool, error) {
+	if !p.peek(punct) {
+		return false, nil
+	}
+
+	return true, p.advance()
+}
+
+func (p *parser) expect(punct string) error {
+	if !p.peek(punct) {
+		return p.errorf("expected %q, found %q", punct, p.tok.value)
+	}
+
+	return p.advance()
+}
+
+func (p *parser) expectKeyword(keyword string) error {
+	if !p.peekKeyword(keyword) {
+		return p.errorf("expected %q, found %q", keyword, p.tok.value)
+	}
+
+	return p.advance()
+}
+
+func (p *parser) name() (string, error) {
+	if p.tok.kind != tokenName {
+		return "", p.errorf("expected name, found %q", p.tok.value)
+	}
+
+	name := p.tok.value
+	return name, p.advance()
+}
+
+// many parses items between open and close, e.g. { ... }
+func (p *parser) many(open string, close string, item func() error) error {
+	err := p.expect(open)
+	if err != nil {
+		return err
+	}
+
+	for {
+		done, err := p.skip(close)
+		if err != nil || done {
+			return err
+		}
+
+		err = item()
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (p *parser) typeRef() (*TypeRef, error) {
+	var t *TypeRef
+
+	if p.peek("[") {
+		err := p.advance()
+		if err != nil {
+			return nil, err
+		}
+
+		elem, err := p.typeRef()
+		if err != nil {
+			return nil, err
+		}
+
+		err = p.expect("]")
+		if err != nil {
+			return nil, err
+		}
+
+		t = &TypeRef{Elem: elem}
+	} else {
+		name, err := p.name()
+		if err != nil {
+			return nil, err
+		}
+
+		t = &TypeRef{Name: name}
+	}
+
+	nonNull, err := p.skip("!")
+	if err != nil {
+		return nil, err
+	}
+	t.NonNull = nonNull
+
+	return t, nil
+}
+
+func (p *parser) value(constOnly bool) (*Value, error) {
+	v := &Value{Location: p.tok.loc}
+
+	switch p.tok.kind {
+	case tokenPunct:
+		switch p.tok.value {
+		case "$":
+			if constOnly {
+				return nil, p.errorf("unexpected variable in constant value")
+			}
+			err := p.advance()
+			if err != nil {
+				return nil, err
+			}
+			v.Kind = ValueVariable
+			v.Raw, err = p.name()
+			return v, err
+
+		case "[":
+			v.Kind = ValueList
+			err := p.many("[", "]", func() error {
+				item, err := p.value(constOnly)
+				v.List = append(v.List, item)
+				return err
+			})
+			return v, err
+
+		case "{":
+			v.Kind = ValueObject
+			err := p.many("{", "}", func() error {
+				name, err := p.name()
+				if err != nil {
+					return err
+				}
+				err = p.expect(":")
+				if err != nil {
+					return err
+				}
+				item, err := p.value(constOnly)
+				v.Fields = append(v.Fields, &ObjectField{Name: name, Value: item})
+				return err
+			})
+			return v, err
+		}
+
+	case tokenInt:
+		v.Kind = ValueInt
+	case tokenFloat:
+		v.Kind = ValueFloat
+	case tokenString:
+		v.Kind = ValueString
+	case tokenName:
+		switch p.tok.value {
+		case "true", "false":
+			v.Kind = ValueBoolean
+		case "null":
+			v.Kind = ValueNull
+		default:
+			v.Kind = ValueEnum
+		}
+	default:
+		return nil, p.unexpected()
+	}
+
+	if p.tok.kind == tokenPunct {
+		return nil, p.unexpected()
+	}
+
+	v.Raw = p.tok.value
+	return v, p.advance()
+}
+
+func (p *parser) arguments(constOnly bool) ([]*Argument, error) {
+	if !p.peek("(") {
+		return nil, nil
+	}
+
+	args := []*Argument{}
+	err := p.many("(", ")", func() error {
+		arg := &Argument{Location: p.tok.loc}
+		var err error
+		arg.Name, err = p.name()
+		if err != nil {
+			return err
+		}
+		err = p.expect(":")
+		if err != nil {
+			return err
+		}
+		arg.Value, err = p.value(constOnly)
+		args = append(args, arg)
+		return err
+	})
+
+	return args, err
+}
+
+func (p *parser) directives(constOnly bool) ([]*Directive, error) {
+	directives := []*Directive{}
+	for p.peek("@") {
+		d := &Directive{Location: p.tok.loc}
+		err := p.advance()
+		if err != nil {
+			return nil, err
+		}
+
+		d.Name, err = p.name()
+		if err != nil {
+			return nil, err
+		}
+
+		d.Arguments, err = p.arguments(constOnly)
+		if err != nil {
+			return nil, err
+		}
+
+		directives = append(directives, d)
+	}
+
+	return directives, nil
+}
+
+// description parses an optional string preceding a schema definition
+func (p *parser) description() (string, error) {
+	if p.tok.kind != tokenString {
+		return "", nil
+	}
+
+	desc := p.tok.value
+	return desc, p.advance()
+}
@@ -0,0 +1,177 @@
+package graphql
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// Extract returns a document holding the named operation and the fragments it uses,
+// directly or through other fragments. It returns nil if there is no such operation.
+func (d *Document) Extract(operationName string) *Document {
+	op := d.Operation(operationName)
+	if op == nil {
+		return nil
+	}
+
+	used := map[string]bool{}
+	var visit func(selections []Selection)
+	visit = func(selections []Selection) {
+		for _, selection := range selections {
+			switch sel := selection.(type) {
+			case *Field:
+				visit(sel.SelectionSet)
+			case *InlineFragment:
+				visit(sel.SelectionSet)
+			case *FragmentSpread:
+				f := d.Fragment(sel.Name)
+				if f != nil && !used[f.Name] {
+					used[f.Name] = true
+					visit(f.SelectionSet)
+				}
+			}
+		}
+	}
+	visit(op.SelectionSet)
+
+	res := &Document{Operations: []*Operation{op}}
+	for _, f := range d.Fragments {
+		if used[f.Name] {
+			res.Fragments = append(res.Fragments, f)
+		}
+	}
+
+	return res
+}
+
+// String prints the document in a compact form, one definition per line.
+func (d *Document) String() string {
+	var b strings.Builder
+
+	for _, op := range d.Operations {
+		if b.Len() > 0 {
+			b.WriteString("\n")
+		}
+		b.WriteString(op.Type)
+		if op.Name != "" {
+			b.WriteString(" " + op.Name)
+		}
+		if len(op.Variables) > 0 {
+			b.WriteString("(")
+			for i, v := range op.Variables {
+				if i > 0 {
+					b.WriteString(", ")
+				}
+				b.WriteString("$" + v.Name + ": " + v.Type.String())
+				if v.DefaultValue != nil {
+					b.WriteString(" = ")
+					printValue(&b, v.DefaultValue)
+				}
+			}
+			b.WriteString(")")
+		}
+		printDirectives(&b, op.Directives)
+		b.WriteString(" ")
+		printSelectionSet(&b, op.SelectionSet)
+	}
+
+	for _, f := range d.Fragments {
+		if b.Len() > 0 {
+			b.WriteString("\n")
+		}
+		b.WriteString("fragment " + f.Name + " on " + f.TypeCondition)
+		printDirectives(&b, f.Directives)
+		b.WriteString(" ")
+		printSelectionSet(&b, f.SelectionSet)
+	}
+
+	return b.String()
+}
+
+func printSelectionSet(b *strings.Builder, selections []Selection) {
+	b.WriteString("{")
+	for i, selection := range selections {
+		if i > 0 {
+			b.WriteString(" ")
+		}
+
+		switch sel := selection.(type) {
+		case *Field:
+			if sel.Alias != "" {
+				b.WriteString(sel.Alias + ": ")
+			}
+			b.WriteString(sel.Name)
+			printArguments(b, sel.Arguments)
+			printDirectives(b, sel.Directives)
+			if len(sel.SelectionSet) > 0 {
+				b.WriteString(" ")
+				printSelectionSet(b, sel.SelectionSet)
+			}
+		case *FragmentSpread:
+			b.WriteString("..." + sel.Name)
+			printDirectives(b, sel.Directives)
+		case *InlineFragment:
+			b.WriteString("...")
+			if sel.TypeCondition != "" {
+				b.WriteString(" on " + sel.TypeCondition)
+			}
+			printDirectives(b, sel.Directives)
+			b.WriteString(" ")
+			printSelectionSet(b, sel.SelectionSet)
+		}
+	}
+	b.WriteString("}")
+}
+
+func printArguments(b *strings.Builder, args []*Argument) {
+	if len(args) == 0 {
+		return
+	}
+
+	b.WriteString("(")
+	for i, arg := range args {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteString(arg.Name + ": ")
+		printValue(b, arg.Value)
+	}
+	b.WriteString(")")
+}
+
+func printDirectives(b *strings.Builder, directives []*Directive) {
+	for _, d := range directives {
+		b.WriteString(" @" + d.Name)
+		printArguments(b, d.Arguments)
+	}
+}
+
+func printValue(b *strings.Builder, v *Value) {
+	switch v.Kind {
+	case ValueVariable:
+		b.WriteString("$" + v.Raw)
+	case ValueString:
+		quoted, _ := json.Marshal(v.Raw)
+		b.Write(quoted)
+	case ValueList:
+		b.WriteString("[")
+		for i, item := range v.List {
+			if i > 0 {
+				b.WriteString(", ")
+			}
+			printValue(b, item)
+		}
+		b.WriteString("]")
+	case ValueObject:
+		b.WriteString("{")
+		for i, f := range v.Fields {
+			if i > 0 {
+				b.WriteString(", ")
+			}
+			b.WriteString(f.Name + ": ")
+			printValue(b, f.Value)
+		}
+		b.WriteString("}")
+	default:
+		b.WriteString(v.Raw)
+	}
+}
@@ -0,0 +1,455 @@
+package graphql
+
+import (
+	"fmt"
+)
+
+// ParseSchema parses a schema in the GraphQL schema definition language, as returned by
+// CloudCmsSession.GraphQLSchema. Type extensions are merged into the types they extend.
+func ParseSchema(sdl string) (*Schema, error) {
+	p, err := newParser(sdl)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Schema{
+		Types:      map[string]*TypeDefinition{},
+		Directives: map[string]*DirectiveDefinition{},
+	}
+	for _, name := range builtinScalars {
+		s.Types[name] = &TypeDefinition{Kind: KindScalar, Name: name}
+	}
+
+	explicitRoots := false
+	for p.tok.kind != tokenEOF {
+		desc, err := p.description()
+		if err != nil {
+			return nil, err
+		}
+
+		extend := false
+		if p.peekKeyword("extend") {
+			extend = true
+			err = p.advance()
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		switch {
+		case p.peekKeyword("schema"):
+			explicitRoots = true
+			err = p.schemaDefinition(s)
+		case p.peekKeyword("directive"):
+			var d *DirectiveDefinition
+			d, err = p.directiveDefinition()
+			if err == nil {
+				d.Description = desc
+				s.Directives[d.Name] = d
+			}
+		default:
+			var t *TypeDefinition
+			t, err = p.typeDefinition()
+			if err == nil {
+				t.Description = desc
+				err = s.addType(t, extend)
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if !explicitRoots {
+		for name, root := range map[string]*string{"Query": &s.QueryType, "Mutation": &s.MutationType, "Subscription": &s.SubscriptionType} {
+			if s.Types[name] != nil {
+				*root = name
+			}
+		}
+	}
+
+	err = s.check()
+	if err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+func (s *Schema) addType(t *TypeDefinition, extend bool) error {
+	existing := s.Types[t.Name]
+
+	if !extend {
+		if existing != nil && !(existing.Kind == KindScalar && IsBuiltinScalar(t.Name)) {
+			return &SyntaxError{Message: fmt.Sprintf("type %s is defined more than once", t.Name), Location: t.Location}
+		}
+		s.Types[t.Name] = t
+		return nil
+	}
+
+	if existing == nil {
+		return &SyntaxError{Message: fmt.Sprintf("cannot extend undefined type %s", t.Name), Location: t.Location}
+	}
+	if existing.Kind != t.Kind {
+		return &SyntaxError{Message: fmt.Sprintf("cannot extend %s %s as %s", existing.Kind, t.Name, t.Kind), Location: t.Location}
+	}
+
+	existing.Fields = append(existing.Fields, t.Fields...)
+	existing.Interfaces = append(existing.Interfaces, t.Interfaces...)
+	existing.PossibleTypes = append(existing.PossibleTypes, t.PossibleTypes...)
+	existing.EnumValues = append(existing.EnumValues, t.EnumValues...)
+	existing.InputFields = append(existing.InputFields, t.InputFields...)
+	return nil
+}
+
+// check verifies that every referenced type is defined
+func (s *Schema) check() error {
+	for _, root := range []string{s.QueryType, s.MutationType, s.SubscriptionType} {
+		if root != "" && s.Types[root] == nil {
+			return fmt.Errorf("graphql: undefined root type %s", root)
+		}
+	}
+
+	for _, t := range s.Types {
+		for _, f := range t.Fields {
+			if s.Types[f.Type.NamedType()] == nil {
+				return fmt.Errorf("graphql: %s.%s has undefined type %s", t.Name, f.Name, f.Type.NamedType())
+			}
+			for _, a := range f.Arguments {
+				if s.Types[a.Type.NamedType()] == nil {
+					return fmt.Errorf("graphql: %s.%s(%s) has undefined type %s", t.Name, f.Name, a.Name, a.Type.NamedType())
+				}
+			}
+		}
+		for _, f := range t.InputFields {
+			if s.Types[f.Type.NamedType()] == nil {
+				return fmt.Errorf("graphql: %s.%s has undefined type %s", t.Name, f.Name, f.Type.NamedType())
+			}
+		}
+		for _, name := range append(append([]string{}, t.Interfaces...), t.PossibleTypes...) {
+			if s.Types[name] == nil {
+				return fmt.Errorf("graphql: %s references undefined type %s", t.Name, name)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (p *parser) schemaDefinition(s *Schema) error {
+	err := p.expectKeyword("schema")
+	if err != nil {
+		return err
+	}
+
+	_, err = p.directives(true)
+	if err != nil {
+		return err
+	}
+
+	return p.many("{", "}", func() error {
+		operation, err := p.name()
+		if err != nil {
+			return err
+		}
+		err = p.expect(":")
+		if err != nil {
+			return err
+		}
+		name, err := p.name()
+		if err != nil {
+			return err
+		}
+
+		switch operation {
+		case "query":
+			s.QueryType = name
+		case "mutation":
+			s.MutationType = name
+		case "subscription":
+			s.SubscriptionType = name
+		default:
+			return p.errorf("unknown operation type %q", operation)
+		}
+		return nil
+	})
+}
+
+func (p *parser) directiveDefinition() (*DirectiveDefinition, error) {
+	err := p.expectKeyword("directive")
+	if err != nil {
+		return nil, err
+	}
+	err = p.expect("@")
+	if err != nil {
+		return nil, err
+	}
+
+	d := &DirectiveDefinition{}
+	d.Name, err = p.name()
+	if err != nil {
+		return nil, err
+	}
+
+	d.Arguments, err = p.inputValueDefinitions("(", ")")
+	if err != nil {
+		return nil, err
+	}
+
+	if p.peekKeyword("repeatable") {
+		err = p.advance()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = p.expectKeyword("on")
+	if err != nil {
+		return nil, err
+	}
+
+	d.Locations, err = p.nameList("|")
+	return d, err
+}
+
+// nameList parses names separated by sep, allowing a leading separator
+func (p *parser) nameList(sep string) ([]string, error) {
+	_, err := p.skip(sep)
+	if err != nil {
+		return nil, err
+	}
+
+	names := []string{}
+	for {
+		name, err := p.name()
+		if err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+
+		more, err := p.skip(sep)
+		if err != nil {
+			return nil, err
+		}
+		if !more {
+			return names, nil
+		}
+	}
+}
+
+func (p *parser) typeDefinition() (*TypeDefinition, error) {
+	t := &TypeDefinition{Location: p.tok.loc}
+
+	keyword, err := p.name()
+	if err != nil {
+		return nil, err
+	}
+
+	t.Name, err = p.name()
+	if err != nil {
+		return nil, err
+	}
+
+	switch keyword {
+	case "scalar":
+		t.Kind = KindScalar
+		_, err = p.directives(true)
+
+	case "type", "interface":
+		t.Kind = KindObject
+		if keyword == "interface" {
+			t.Kind = KindInterface
+		}
+		if p.peekKeyword("implements") {
+			err = p.advance()
+			if err != nil {
+				return nil, err
+			}
+			t.Interfaces, err = p.interfaces()
+			if err != nil {
+				return nil, err
+			}
+		}
+		_, err = p.directives(true)
+		if err == nil && p.peek("{") {
+			t.Fields, err = p.fieldDefinitions()
+		}
+
+	case "union":
+		t.Kind = KindUnion
+		_, err = p.directives(true)
+		if err == nil && p.peek("=") {
+			err = p.advance()
+			if err == nil {
+				t.PossibleTypes, err = p.nameList("|")
+			}
+		}
+
+	case "enum":
+		t.Kind = KindEnum
+		_, err = p.directives(true)
+		if err == nil && p.peek("{") {
+			t.EnumValues, err = p.enumValues()
+		}
+
+	case "input":
+		t.Kind = KindInputObject
+		_, err = p.directives(true)
+		if err == nil {
+			t.InputFields, err = p.inputValueDefinitions("{", "}")
+		}
+
+	default:
+		return nil, &SyntaxError{Message: fmt.Sprintf("unexpected %q", keyword), Location: t.Location}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return t, nil
+}
+
+// interfaces parses "A & B"
+func (p *parser) interfaces() ([]string, error) {
+	_, err := p.skip("&")
+	if err != nil {
+		return nil, err
+	}
+
+	names := []string{}
+	for {
+		name, err := p.name()
+		if err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+
+		amp, err := p.skip("&")
+		if err != nil {
+			return nil, err
+		}
+		if !amp {
+			return names, nil
+		}
+	}
+}
+
+func (p *parser) fieldDefinitions() ([]*FieldDefinition, error) {
+	fields := []*FieldDefinition{}
+	err := p.many("{", "}", func() error {
+		f := &FieldDefinition{}
+		var err error
+		f.Description, err = p.description()
+		if err != nil {
+			return err
+		}
+		f.Name, err = p.name()
+		if err != nil {
+			return err
+		}
+		f.Arguments, err = p.inputValueDefinitions("(", ")")
+		if err != nil {
+			return err
+		}
+		err = p.expect(":")
+		if err != nil {
+			return err
+		}
+		f.Type, err = p.typeRef()
+		if err != nil {
+			return err
+		}
+
+		directives, err := p.directives(true)
+		f.Deprecated, f.DeprecationReason = deprecation(directives)
+		fields = append(fields, f)
+		return err
+	})
+
+	return fields, err
+}
+
+func (p *parser) inputValueDefinitions(open string, close string) ([]*InputValueDefinition, error) {
+	if !p.peek(open) {
+		return nil, nil
+	}
+
+	values := []*InputValueDefinition{}
+	err := p.many(open, close, func() error {
+		v := &InputValueDefinition{}
+		var err error
+		v.Description, err = p.description()
+		if err != nil {
+			return err
+		}
+		v.Name, err = p.name()
+		if err != nil {
+			return err
+		}
+		err = p.expect(":")
+		if err != nil {
+			return err
+		}
+		v.Type, err = p.typeRef()
+		if err != nil {
+			return err
+		}
+
+		if p.peek("=") {
+			err = p.advance()
+			if err != nil {
+				return err
+			}
+			v.DefaultValue, err = p.value(true)
+			if err != nil {
+				return err
+			}
+		}
+
+		_, err = p.directives(true)
+		values = append(values, v)
+		return err
+	})
+
+	return values, err
+}
+
+func (p *parser) enumValues() ([]*EnumValueDefinition, error) {
+	values := []*EnumValueDefinition{}
+	err := p.many("{", "}", func() error {
+		v := &EnumValueDefinition{}
+		var err error
+		v.Description, err = p.description()
+		if err != nil {
+			return err
+		}
+		v.Name, err = p.name()
+		if err != nil {
+			return err
+		}
+
+		directives, err := p.directives(true)
+		v.Deprecated, _ = deprecation(directives)
+		values = append(values, v)
+		return err
+	})
+
+	return values, err
+}
+
+func deprecation(directives []*Directive) (bool, string) {
+	for _, d := range directives {
+		if d.Name != "deprecated" {
+			continue
+		}
+
+		for _, arg := range d.Arguments {
+			if arg.Name == "reason" && arg.Value.Kind == ValueString {
+				return true, arg.Value.Raw
+			}
+		}
+		return true, "No longer supported"
+	}
+
+	return false, ""
+}
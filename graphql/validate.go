@@ -0,0 +1,351 @@
+package graphql
+
+import (
+	"fmt"
+	"strings"
+)
+
+type ValidationError struct {
+	Message  string
+	Location Location
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Location, e.Message)
+}
+
+type ValidationErrors []*ValidationError
+
+func (errs ValidationErrors) Error() string {
+	msgs := make([]string, len(errs))
+	for i, e := range errs {
+		msgs[i] = e.Error()
+	}
+
+	return "graphql: invalid document: " + strings.Join(msgs, "; ")
+}
+
+// ValidateQuery parses and validates query against the schema.
+func (s *Schema) ValidateQuery(query string) error {
+	doc, err := ParseQuery(query)
+	if err != nil {
+		return err
+	}
+
+	return s.Validate(doc)
+}
+
+// Validate checks that the operations and fragments in doc only select fields that exist,
+// pass known arguments of the right shape, supply required arguments and use declared
+// variables. It returns ValidationErrors, or nil if the document is valid.
+func (s *Schema) Validate(doc *Document) error {
+	v := &validator{schema: s, doc: doc}
+	v.validate()
+
+	if len(v.errs) > 0 {
+		return v.errs
+	}
+
+	return nil
+}
+
+type validator struct {
+	schema *Schema
+	doc    *Document
+	errs   ValidationErrors
+
+	// per operation state
+	variables     map[string]*VariableDefinition
+	usedVariables map[string]bool
+	visiting      map[string]bool
+}
+
+func (v *validator) errorf(loc Location, format string, args ...interface{}) {
+	v.errs = append(v.errs, &ValidationError{Message: fmt.Sprintf(format, args...), Location: loc})
+}
+
+func (v *validator) validate() {
+	names := map[string]bool{}
+	for _, op := range v.doc.Operations {
+		if op.Name == "" && len(v.doc.Operations) > 1 {
+			v.errorf(op.Location, "anonymous operation must be the only operation in the document")
+		}
+		if op.Name != "" && names[op.Name] {
+			v.errorf(op.Location, "operation %q is defined more than once", op.Name)
+		}
+		names[op.Name] = true
+	}
+
+	fragments := map[string]bool{}
+	for _, f := range v.doc.Fragments {
+		if fragments[f.Name] {
+			v.errorf(f.Location, "fragment %q is defined more than once", f.Name)
+		}
+		fragments[f.Name] = true
+
+		t := v.schema.Types[f.TypeCondition]
+		if t == nil {
+			v.errorf(f.Location, "fragment %q is on unknown type %s", f.Name, f.TypeCondition)
+		} else if !t.IsComposite() {
+			v.errorf(f.Location, "fragment %q cannot be on non-composite type %s", f.Name, f.TypeCondition)
+		}
+	}
+
+	for _, op := range v.doc.Operations {
+		v.operation(op)
+	}
+
+	// fragments not reached from any operation are still checked for their own selections
+	if len(v.doc.Operations) == 0 {
+		for _, f := range v.doc.Fragments {
+			if t := v.schema.Types[f.TypeCondition]; t != nil && t.IsComposite() {
+				v.variables = nil
+				v.usedVariables = map[string]bool{}
+				v.visiting = map[string]bool{f.Name: true}
+				v.selectionSet(t, f.SelectionSet)
+			}
+		}
+	}
+}
+
+func (v *validator) operation(op *Operation) {
+	root := v.schema.RootType(op.Type)
+	if root == nil {
+		v.errorf(op.Location, "schema does not support %s operations", op.Type)
+		return
+	}
+
+	v.variables = map[string]*VariableDefinition{}
+	v.usedVariables = map[string]bool{}
+	v.visiting = map[string]bool{}
+
+	for _, variable := range op.Variables {
+		if v.variables[variable.Name] != nil {
+			v.errorf(variable.Location, "variable $%s is defined more than once", variable.Name)
+		}
+		v.variables[variable.Name] = variable
+
+		t := v.schema.Types[variable.Type.NamedType()]
+		if t == nil {
+			v.errorf(variable.Location, "variable $%s has unknown type %s", variable.Name, variable.Type.NamedType())
+		} else if !t.IsInput() {
+			v.errorf(variable.Location, "variable $%s cannot be of non-input type %s", variable.Name, t.Name)
+		} else if variable.DefaultValue != nil {
+			v.value(variable.DefaultValue, variable.Type, "default value of $"+variable.Name)
+		}
+	}
+
+	v.selectionSet(root, op.SelectionSet)
+
+	for _, variable := range op.Variables {
+		if !v.usedVariables[variable.Name] {
+			v.errorf(variable.Location, "variable $%s is never used", variable.Name)
+		}
+	}
+}
+
+func (v *validator) selectionSet(parent *TypeDefinition, selections []Selection) {
+	for _, selection := range selections {
+		switch sel := selection.(type) {
+		case *Field:
+			v.field(parent, sel)
+
+		case *InlineFragment:
+			v.directives(sel.Directives)
+			t := parent
+			if sel.TypeCondition != "" {
+				t = v.schema.Types[sel.TypeCondition]
+				if t == nil {
+					v.errorf(sel.Location, "inline fragment on unknown type %s", sel.TypeCondition)
+					continue
+				}
+				if !t.IsComposite() {
+					v.errorf(sel.Location, "inline fragment cannot be on non-composite type %s", t.Name)
+					continue
+				}
+			}
+			v.selectionSet(t, sel.SelectionSet)
+
+		case *FragmentSpread:
+			v.directives(sel.Directives)
+			f := v.doc.Fragment(sel.Name)
+			if f == nil {
+				v.errorf(sel.Location, "unknown fragment %q", sel.Name)
+				continue
+			}
+			if v.visiting[f.Name] {
+				v.errorf(sel.Location, "fragment %q spreads itself", f.Name)
+				continue
+			}
+
+			t := v.schema.Types[f.TypeCondition]
+			if t == nil || !t.IsComposite() {
+				continue
+			}
+
+			v.visiting[f.Name] = true
+			v.selectionSet(t, f.SelectionSet)
+			delete(v.visiting, f.Name)
+		}
+	}
+}
+
+func (v *validator) field(parent *TypeDefinition, field *Field) {
+	v.directives(field.Directives)
+
+	if field.Name == "__typename" {
+		if len(field.SelectionSet) > 0 {
+			v.errorf(field.Location, "field __typename cannot have a selection")
+		}
+		return
+	}
+
+	// introspection fields are not validated further
+	if (field.Name == "__schema" || field.Name == "__type") && parent.Name == v.schema.QueryType {
+		return
+	}
+
+	def := parent.Field(field.Name)
+	if def == nil {
+		v.errorf(field.Location, "cannot query field %q on type %s", field.Name, parent.Name)
+		return
+	}
+
+	v.arguments(field.Location, fmt.Sprintf("%s.%s", parent.Name, field.Name), def.Arguments, field.Arguments)
+
+	t := v.schema.Types[def.Type.NamedType()]
+	if t == nil {
+		return
+	}
+
+	if t.IsComposite() {
+		if len(field.SelectionSet) == 0 {
+			v.errorf(field.Location, "field %q of type %s must have a selection of subfields", field.Name, def.Type)
+			return
+		}
+		v.selectionSet(t, field.SelectionSet)
+	} else if len(field.SelectionSet) > 0 {
+		v.errorf(field.Location, "field %q of type %s cannot have a selection", field.Name, def.Type)
+	}
+}
+
+func (v *validator) directives(directives []*Directive) {
+	for _, d := range directives {
+		switch d.Name {
+		case "include", "skip":
+			def := []*InputValueDefinition{{Name: "if", Type: &TypeRef{Name: "Boolean", NonNull: true}}}
+			v.arguments(d.Location, "@"+d.Name, def, d.Arguments)
+			continue
+		}
+
+		def := v.schema.Directives[d.Name]
+		if def == nil {
+			v.errorf(d.Location, "unknown directive @%s", d.Name)
+			continue
+		}
+		v.arguments(d.Location, "@"+d.Name, def.Arguments, d.Arguments)
+	}
+}
+
+func (v *validator) arguments(loc Location, owner string, defs []*InputValueDefinition, args []*Argument) {
+	given := map[string]bool{}
+	for _, arg := range args {
+		if given[arg.Name] {
+			v.errorf(arg.Location, "argument %q is given more than once", arg.Name)
+		}
+		given[arg.Name] = true
+
+		def := findInputValue(defs, arg.Name)
+		if def == nil {
+			v.errorf(arg.Location, "unknown argument %q on %s", arg.Name, owner)
+			continue
+		}
+		v.value(arg.Value, def.Type, fmt.Sprintf("argument %q", arg.Name))
+	}
+
+	for _, def := range defs {
+		if def.Type.NonNull && def.DefaultValue == nil && !given[def.Name] {
+			v.errorf(loc, "%s is missing required argument %q of type %s", owner, def.Name, def.Type)
+		}
+	}
+}
+
+// value checks a literal against the expected input type
+func (v *validator) value(val *Value, t *TypeRef, what string) {
+	if val.Kind == ValueVariable {
+		if v.usedVariables != nil {
+			v.usedVariables[val.Raw] = true
+		}
+		if v.variables != nil && v.variables[val.Raw] == nil {
+			v.errorf(val.Location, "variable $%s is not defined", val.Raw)
+		}
+		return
+	}
+
+	if val.Kind == ValueNull {
+		if t.NonNull {
+			v.errorf(val.Location, "%s of type %s cannot be null", what, t)
+		}
+		return
+	}
+
+	if t.Elem != nil {
+		if val.Kind == ValueList {
+			for _, item := range val.List {
+				v.value(item, t.Elem, what)
+			}
+			return
+		}
+		// a single value is coerced into a list
+		v.value(val, t.Elem, what)
+		return
+	}
+
+	def := v.schema.Types[t.Name]
+	if def == nil {
+		return
+	}
+
+	ok := true
+	switch def.Kind {
+	case KindEnum:
+		ok = val.Kind == ValueEnum && def.EnumValue(val.Raw) != nil
+	case KindInputObject:
+		if val.Kind != ValueObject {
+			ok = false
+			break
+		}
+		for _, f := range val.Fields {
+			fieldDef := def.InputField(f.Name)
+			if fieldDef == nil {
+				v.errorf(f.Value.Location, "unknown field %q on input type %s", f.Name, def.Name)
+				continue
+			}
+			v.value(f.Value, fieldDef.Type, fmt.Sprintf("field %q", f.Name))
+		}
+		for _, fieldDef := range def.InputFields {
+			if fieldDef.Type.NonNull && fieldDef.DefaultValue == nil && val.Field(fieldDef.Name) == nil {
+				v.errorf(val.Location, "%s is missing required field %q", what, fieldDef.Name)
+			}
+		}
+	case KindScalar:
+		switch def.Name {
+		case "Int":
+			ok = val.Kind == ValueInt
+		case "Float":
+			ok = val.Kind == ValueInt || val.Kind == ValueFloat
+		case "String":
+			ok = val.Kind == ValueString
+		case "Boolean":
+			ok = val.Kind == ValueBoolean
+		case "ID":
+			ok = val.Kind == ValueString || val.Kind == ValueInt
+		}
+	default:
+		ok = false
+	}
+
+	if !ok {
+		v.errorf(val.Location, "%s expects type %s", what, t)
+	}
+}
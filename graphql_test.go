@@ -1,10 +1,71 @@
 package cloudcms
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
+	"net/http"
 	"testing"
 )
 
+func TestGraphQLPost(t *testing.T) {
+	var requests []GraphQLRequest
+	session := newTestSession(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" || r.URL.Path != "/repositories/repo/branches/master/graphql" {
+			t.Errorf("unexpected request: %s %s", r.Method, r.URL)
+			http.Error(w, "unexpected request", http.StatusNotFound)
+			return
+		}
+		if r.URL.Query().Has("full") {
+			t.Errorf("graphql requests should not carry full/metadata params")
+		}
+
+		var req GraphQLRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		requests = append(requests, req)
+
+		if req.Query == "" {
+			w.Write([]byte(`{"errors": [{"message": "PersistedQueryNotFound"}]}`))
+			return
+		}
+
+		w.Write([]byte(`{
+			"data": {"custom_books": [{"title": "hello", "author": null}]},
+			"errors": [{"message": "author not found", "path": ["custom_books", 0, "author"], "locations": [{"line": 1, "column": 30}]}]
+		}`))
+	}))
+
+	var data struct {
+		Books []struct {
+			Title string `json:"title"`
+		} `json:"custom_books"`
+	}
+
+	err := session.GraphQL("repo", "master", &GraphQLRequest{
+		Query:         "query Books { custom_books { title author } }",
+		OperationName: "Books",
+		Persisted:     true,
+	}, &data)
+
+	var errs GraphQLErrors
+	if !errors.As(err, &errs) || len(errs) != 1 {
+		t.Fatalf("expected graphql errors, got %v", err)
+	}
+	if errs.Error() != "graphql: author not found at custom_books.0.author (line 1, column 30)" {
+		t.Fatalf("unexpected error message: %s", errs.Error())
+	}
+	if len(data.Books) != 1 || data.Books[0].Title != "hello" {
+		t.Fatal("partial data should be decoded")
+	}
+
+	if len(requests) != 2 {
+		t.Fatal("persisted query should be retried with the full query")
+	}
+	if requests[0].Extensions["persistedQuery"] == nil || requests[1].OperationName != "Books" {
+		t.Fatal("persisted query hash or operation name missing")
+	}
+}
+
 func TestGraphQL(t *testing.T) {
 	session, err := ConnectDefault()
 	if err != nil {
@@ -55,6 +116,14 @@ func TestGraphQL(t *testing.T) {
 
 	fmt.Println(schema)
 
+	parsed, err := session.ReadGraphQLSchema(repositoryId, branchId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsed.QueryableTypes()["custom_books"] == nil {
+		t.Fatal("custom_books not queryable")
+	}
+
 	_, err = session.CreateNode(repositoryId, branchId, JsonObject{
 		"title":       "hello",
 		"description": "this is a book about salutations",
@@ -84,6 +153,11 @@ func TestGraphQL(t *testing.T) {
 		}
 	`
 
+	err = parsed.ValidateQuery(query)
+	if err != nil {
+		t.Fatal(err)
+	}
+
 	result, err := session.GraphQLQuery(repositoryId, branchId, query, "", nil)
 	if err != nil {
 		t.Fatal(err)
@@ -108,4 +182,21 @@ func TestGraphQL(t *testing.T) {
 	if len(booksArr) != 2 {
 		t.Fatal("wrong number of books in response data")
 	}
+
+	var books struct {
+		Books []struct {
+			Title  string `json:"title"`
+			Author string `json:"author"`
+		} `json:"custom_books"`
+	}
+	err = session.GraphQL(repositoryId, branchId, &GraphQLRequest{
+		Query:         `query Books { custom_books { title author } }`,
+		OperationName: "Books",
+	}, &books)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(books.Books) != 2 || books.Books[0].Author == "" {
+		t.Fatal("wrong typed graphql response")
+	}
 }
@@ -0,0 +1,166 @@
+package cloudcms
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Instrumentation is told about every API request, e.g. to record metrics or traces. See the
+// metrics package for adapters.
+type Instrumentation interface {
+	// OnRequestStart is called before the request is sent. The context it returns is used for
+	// the request, and headers set on info.Header are sent with it.
+	OnRequestStart(ctx context.Context, info *RequestInfo) context.Context
+
+	// OnRequestEnd is called with the context returned by OnRequestStart once the response
+	// body is closed or the request failed.
+	OnRequestEnd(ctx context.Context, info *RequestInfo)
+}
+
+// RequestInfo describes an API request.
+type RequestInfo struct {
+	// Operation is the session method that sent the request, e.g. "ReadNode", or the HTTP
+	// method if it was sent directly.
+	Operation string
+	Method    string
+
+	// Route is the path with its ids replaced, e.g.
+	// "/repositories/{repositoryId}/branches/{branchId}/nodes/{nodeId}".
+	Route  string
+	Header http.Header
+
+	// Set when the request ends. Status is 0 if no response was received, and Err is only
+	// set in that case. Duration runs until the response body is closed.
+	Status        int
+	Duration      time.Duration
+	RequestBytes  int64
+	ResponseBytes int64
+	Err           error
+}
+
+// routeParams names the id following a collection in a path
+var routeParams = map[string]string{
+	"repositories": "{repositoryId}",
+	"branches":     "{branchId}",
+	"nodes":        "{nodeId}",
+	"attachments":  "{attachmentId}",
+	"features":     "{featureId}",
+	"versions":     "{changesetId}",
+	"changesets":   "{changesetId}",
+	"definitions":  "{qname}",
+	"projects":     "{projectId}",
+	"releases":     "{releaseId}",
+	"jobs":         "{jobId}",
+	"vaults":       "{vaultId}",
+	"archives":     "{archiveId}",
+}
+
+// routeActions are the path segments following a collection that are not ids
+var routeActions = map[string]bool{
+	"query":  true,
+	"find":   true,
+	"search": true,
+	"delete": true,
+	"start":  true,
+}
+
+// routeTemplate replaces the ids in a path with their names
+func routeTemplate(path string) string {
+	parts := strings.Split(path, "/")
+	for i := 1; i < len(parts); i++ {
+		param, ok := routeParams[parts[i-1]]
+		if ok && parts[i] != "" && !routeActions[parts[i]] {
+			parts[i] = param
+		}
+	}
+
+	return strings.Join(parts, "/")
+}
+
+type operationKey struct{}
+
+// withOperation names the operation of the requests sent with ctx
+func withOperation(ctx context.Context, operation string) context.Context {
+	return context.WithValue(ctx, operationKey{}, operation)
+}
+
+func operationOf(ctx context.Context) string {
+	res, _ := ctx.Value(operationKey{}).(string)
+	return res
+}
+
+// named returns a copy of the session whose requests are named after the session method
+// sending them. Each method names itself on entry, so a method calling another one, e.g.
+// QueryOneNode calling QueryNodes, reports the innermost.
+func (session *CloudCmsSession) named(operation string) *CloudCmsSession {
+	res := *session
+	res.operation = operation
+	return &res
+}
+
+// instrument starts the instrumentation of a request. The returned function ends it.
+func (session *CloudCmsSession) instrument(req *http.Request) (*http.Request, func(status int, responseBytes int64, err error)) {
+	info := &RequestInfo{
+		Operation:    operationOf(req.Context()),
+		Method:       req.Method,
+		Route:        routeTemplate(strings.TrimPrefix(req.URL.Path, session.basePath())),
+		Header:       req.Header,
+		RequestBytes: req.ContentLength,
+	}
+	if info.Operation == "" {
+		info.Operation = req.Method
+	}
+	if info.RequestBytes < 0 {
+		info.RequestBytes = 0
+	}
+
+	start := time.Now()
+	ctx := session.instrumentation.OnRequestStart(req.Context(), info)
+	if ctx != nil && ctx != req.Context() {
+		req = req.WithContext(ctx)
+	}
+
+	return req, func(status int, responseBytes int64, err error) {
+		info.Status = status
+		info.ResponseBytes = responseBytes
+		info.Err = err
+		info.Duration = time.Since(start)
+		session.instrumentation.OnRequestEnd(req.Context(), info)
+	}
+}
+
+// basePath returns the path of the base URL, which is not part of the route
+func (session *CloudCmsSession) basePath() string {
+	u, err := url.Parse(session.config.BaseURL)
+	if err != nil {
+		return ""
+	}
+
+	return strings.TrimSuffix(u.Path, "/")
+}
+
+// instrumentedBody counts the bytes read and ends the instrumentation when closed
+type instrumentedBody struct {
+	io.ReadCloser
+	status int
+	bytes  int64
+	once   sync.Once
+	end    func(status int, responseBytes int64, err error)
+}
+
+func (b *instrumentedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.bytes += int64(n)
+	return n, err
+}
+
+func (b *instrumentedBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(func() { b.end(b.status, b.bytes, nil) })
+	return err
+}
@@ -0,0 +1,110 @@
+package cloudcms
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"sync"
+	"testing"
+)
+
+type recordingInstrumentation struct {
+	mu    sync.Mutex
+	ended []RequestInfo
+}
+
+type spanKey struct{}
+
+func (r *recordingInstrumentation) OnRequestStart(ctx context.Context, info *RequestInfo) context.Context {
+	info.Header.Set("Traceparent", "00-trace-span-01")
+	return context.WithValue(ctx, spanKey{}, info.Operation)
+}
+
+func (r *recordingInstrumentation) OnRequestEnd(ctx context.Context, info *RequestInfo) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if ctx.Value(spanKey{}) != info.Operation {
+		panic("context from OnRequestStart not passed to OnRequestEnd")
+	}
+	r.ended = append(r.ended, *info)
+}
+
+func TestInstrumentation(t *testing.T) {
+	session := newTestSession(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Traceparent") == "" {
+			t.Error("header from OnRequestStart not sent")
+		}
+		switch r.URL.Path {
+		case "/repositories/r/branches/b/nodes/missing":
+			http.Error(w, "not found", http.StatusNotFound)
+		case "/repositories/r/branches/b/nodes/n1/attachments/default":
+			w.Write([]byte("hello"))
+		default:
+			w.Write([]byte(`{"rows": [], "size": 0, "total_rows": 0, "offset": 0}`))
+		}
+	}))
+	recorder := &recordingInstrumentation{}
+	session.instrumentation = recorder
+
+	session.ReadNode("r", "b", "missing")
+	session.QueryOneNode("r", "b", JsonObject{"title": "a"})
+
+	body, err := session.DownloadAttachment("r", "b", "n1", "default")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(recorder.ended) != 2 {
+		t.Fatal("a download should end when its body is closed")
+	}
+	io.ReadAll(body)
+	body.Close()
+
+	session.Get("/repositories/r/changesets/query", nil)
+
+	expected := []RequestInfo{
+		{Operation: "ReadNode", Method: "GET", Route: "/repositories/{repositoryId}/branches/{branchId}/nodes/{nodeId}", Status: 404},
+		{Operation: "QueryNodes", Method: "POST", Route: "/repositories/{repositoryId}/branches/{branchId}/nodes/query", Status: 200},
+		{Operation: "DownloadAttachment", Method: "GET", Route: "/repositories/{repositoryId}/branches/{branchId}/nodes/{nodeId}/attachments/{attachmentId}", Status: 200, ResponseBytes: 5},
+		{Operation: "GET", Method: "GET", Route: "/repositories/{repositoryId}/changesets/query", Status: 200},
+	}
+	if len(recorder.ended) != len(expected) {
+		t.Fatalf("expected %d requests, got %d", len(expected), len(recorder.ended))
+	}
+	for i, e := range expected {
+		a := recorder.ended[i]
+		if a.Operation != e.Operation || a.Method != e.Method || a.Route != e.Route || a.Status != e.Status {
+			t.Errorf("request %d: expected %s %s %s %d, got %s %s %s %d", i, e.Operation, e.Method, e.Route, e.Status, a.Operation, a.Method, a.Route, a.Status)
+		}
+		if e.ResponseBytes != 0 && a.ResponseBytes != e.ResponseBytes {
+			t.Errorf("request %d: expected %d response bytes, got %d", i, e.ResponseBytes, a.ResponseBytes)
+		}
+		if a.Duration <= 0 {
+			t.Errorf("request %d: missing duration", i)
+		}
+	}
+	if recorder.ended[1].RequestBytes != int64(len(`{"title":"a"}`)) {
+		t.Errorf("unexpected request bytes %d", recorder.ended[1].RequestBytes)
+	}
+}
+
+func TestInstrumentationTransportError(t *testing.T) {
+	session := &CloudCmsSession{
+		oauthClient:     &http.Client{},
+		config:          &CloudcmsConfig{BaseURL: "http://127.0.0.1:1/api"},
+		instrumentation: &recordingInstrumentation{},
+	}
+
+	_, err := session.ReadNode("r", "b", "n1")
+	if err == nil {
+		t.Fatal("expected a connection error")
+	}
+
+	ended := session.instrumentation.(*recordingInstrumentation).ended
+	if len(ended) != 1 || ended[0].Status != 0 || ended[0].Err == nil {
+		t.Fatalf("expected the failed request to be recorded, got %+v", ended)
+	}
+	if ended[0].Route != "/repositories/{repositoryId}/branches/{branchId}/nodes/{nodeId}" {
+		t.Fatalf("base path should not be part of the route: %s", ended[0].Route)
+	}
+}
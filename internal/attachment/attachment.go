@@ -0,0 +1,72 @@
+// Package attachment holds the attachment helpers shared by the bulk and fssync packages.
+package attachment
+
+import (
+	"io"
+	"mime"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	cloudcms "github.com/gitana/cloudcms-go-driver"
+)
+
+// Downloader is the subset of *cloudcms.CloudCmsSession used to download attachments.
+type Downloader interface {
+	DownloadAttachment(repositoryId string, branchId string, nodeId string, attachmentId string) (io.ReadCloser, error)
+}
+
+var _ Downloader = (*cloudcms.CloudCmsSession)(nil)
+
+// Ext picks a file extension for a downloaded attachment from the filename or content type
+// of its row in ListAttachments.
+func Ext(row cloudcms.JsonObject) string {
+	ext := path.Ext(row.GetString("filename"))
+	if ext != "" && !strings.ContainsAny(ext, "/\\") {
+		return ext
+	}
+
+	contentType := row.GetString("contentType")
+	if contentType != "" {
+		exts, _ := mime.ExtensionsByType(contentType)
+		if len(exts) > 0 {
+			return exts[0]
+		}
+	}
+
+	return ""
+}
+
+// Download writes an attachment to target, creating its directory. It writes to a temporary
+// file first so that a failed download does not leave a partial file behind.
+func Download(session Downloader, repositoryId string, branchId string, nodeId string, attachmentId string, target string) error {
+	reader, err := session.DownloadAttachment(repositoryId, branchId, nodeId, attachmentId)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	err = os.MkdirAll(filepath.Dir(target), 0755)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(target), ".download-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = io.Copy(tmp, reader)
+	if err == nil {
+		err = tmp.Close()
+	} else {
+		tmp.Close()
+	}
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), target)
+}
@@ -0,0 +1,70 @@
+package attachment
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	cloudcms "github.com/gitana/cloudcms-go-driver"
+)
+
+func TestExt(t *testing.T) {
+	for _, test := range []struct {
+		row      cloudcms.JsonObject
+		expected string
+	}{
+		{cloudcms.JsonObject{"filename": "cover.png", "contentType": "image/jpeg"}, ".png"},
+		{cloudcms.JsonObject{"contentType": "application/pdf"}, ".pdf"},
+		{cloudcms.JsonObject{"filename": "README"}, ""},
+	} {
+		if actual := Ext(test.row); actual != test.expected {
+			t.Errorf("%v: expected %q, got %q", test.row, test.expected, actual)
+		}
+	}
+}
+
+type fakeDownloader map[string]string
+
+func (f fakeDownloader) DownloadAttachment(repositoryId string, branchId string, nodeId string, attachmentId string) (io.ReadCloser, error) {
+	body, ok := f[attachmentId]
+	if !ok {
+		return nil, errors.New("404: not found")
+	}
+	if attachmentId == "broken" {
+		// the connection drops half way
+		return io.NopCloser(io.MultiReader(strings.NewReader(body), iotest.ErrReader(errors.New("reset")))), nil
+	}
+
+	return io.NopCloser(strings.NewReader(body)), nil
+}
+
+func TestDownload(t *testing.T) {
+	dir := t.TempDir()
+	target := filepath.Join(dir, "n1", "default.txt")
+
+	err := Download(fakeDownloader{"default": "hello"}, "r", "b", "n1", "default", target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(target)
+	if err != nil || string(data) != "hello" {
+		t.Fatalf("expected hello, got %q %v", data, err)
+	}
+
+	err = Download(fakeDownloader{}, "r", "b", "n1", "missing", filepath.Join(dir, "n1", "missing"))
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	err = Download(fakeDownloader{"broken": "hal"}, "r", "b", "n1", "broken", filepath.Join(dir, "n1", "broken"))
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	entries, _ := os.ReadDir(filepath.Join(dir, "n1"))
+	if len(entries) != 1 {
+		t.Fatalf("a failed download should leave no file, got %v", entries)
+	}
+}
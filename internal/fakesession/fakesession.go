@@ -0,0 +1,540 @@
+// Package fakesession is an in-memory stand-in for *cloudcms.CloudCmsSession, shared by the
+// tests of the bulk, migrate, fssync and watch packages.
+//
+// Nodes are kept by id. Queries match top-level and dotted properties, with $and, $or and
+// the comparison operators the packages send, and are sorted and paged like the server does.
+package fakesession
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+
+	cloudcms "github.com/gitana/cloudcms-go-driver"
+)
+
+type Attachment struct {
+	Data        []byte
+	ContentType string
+	Filename    string
+
+	// ObjectId changes on every upload, like the id of the stored binary.
+	ObjectId string
+}
+
+// Session is safe for concurrent use through its methods. Tests read and set its fields
+// directly while no call is running.
+type Session struct {
+	mu     sync.Mutex
+	nextId int
+
+	Nodes map[string]cloudcms.JsonObject
+
+	// Paths are the ids of the nodes created with a filePath option, by path.
+	Paths map[string]string
+
+	Attachments map[string]map[string]*Attachment
+	Definitions map[string]*cloudcms.Definition
+
+	// Patched are the ids given to PatchNode, which leaves the nodes as they are.
+	Patched []string
+
+	// FailTitle, if set, makes CreateNode fail for the nodes with this title.
+	FailTitle string
+
+	// Tip is the tip changeset of every branch, Changesets the history of the branch and
+	// Written the nodes written by each changeset.
+	Tip        string
+	Changesets []*cloudcms.Changeset
+	Written    map[string][]cloudcms.JsonObject
+
+	// Queried, if set, is called after each query, e.g. to change the nodes between pages.
+	Queried func()
+}
+
+func New() *Session {
+	return &Session{
+		Nodes:       map[string]cloudcms.JsonObject{},
+		Paths:       map[string]string{},
+		Attachments: map[string]map[string]*Attachment{},
+		Definitions: map[string]*cloudcms.Definition{},
+		Written:     map[string][]cloudcms.JsonObject{},
+	}
+}
+
+// Add stores a node under its _doc.
+func (s *Session) Add(node cloudcms.JsonObject) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.Nodes[node.GetString("_doc")] = node
+}
+
+func (s *Session) ReadNode(repositoryId string, branchId string, nodeId string) (cloudcms.JsonObject, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	node, ok := s.Nodes[nodeId]
+	if !ok {
+		return nil, fmt.Errorf("404: node not found: %s", nodeId)
+	}
+
+	return clone(node), nil
+}
+
+func (s *Session) QueryNodes(repositoryId string, branchId string, query cloudcms.JsonObject, pagination cloudcms.JsonObject) (*cloudcms.ResultMap, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rows := []cloudcms.JsonObject{}
+	for _, node := range s.Nodes {
+		if matches(node, query) {
+			rows = append(rows, node)
+		}
+	}
+
+	keys, err := sortKeys(pagination["sort"])
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		for _, key := range keys {
+			a, _ := getPath(rows[i], key.field)
+			b, _ := getPath(rows[j], key.field)
+			if c := compare(a, b); c != 0 {
+				return c*key.direction < 0
+			}
+		}
+		return rows[i].GetString("_doc") < rows[j].GetString("_doc")
+	})
+
+	res := page(rows, pagination)
+	if s.Queried != nil {
+		s.Queried()
+	}
+
+	return res, nil
+}
+
+func (s *Session) FindNodes(repositoryId string, branchId string, config cloudcms.JsonObject, pagination cloudcms.JsonObject) (*cloudcms.ResultMap, error) {
+	return s.QueryNodes(repositoryId, branchId, config.GetObject("query"), pagination)
+}
+
+func (s *Session) QueryOneNode(repositoryId string, branchId string, query cloudcms.JsonObject) (cloudcms.JsonObject, error) {
+	res, err := s.QueryNodes(repositoryId, branchId, query, cloudcms.JsonObject{"limit": 1})
+	if err != nil || len(res.Rows()) == 0 {
+		return nil, err
+	}
+
+	return res.Rows()[0], nil
+}
+
+func (s *Session) CreateNode(repositoryId string, branchId string, obj cloudcms.JsonObject, opts map[string]string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.FailTitle != "" && obj["title"] == s.FailTitle {
+		return "", fmt.Errorf("400: invalid node")
+	}
+
+	s.nextId++
+	id := fmt.Sprintf("node%d", s.nextId)
+	obj["_doc"] = id
+	obj["_system"] = map[string]interface{}{"changeset": "1:a"}
+	s.Nodes[id] = obj
+	if opts["filePath"] != "" {
+		s.Paths[opts["filePath"]] = id
+	}
+
+	return id, nil
+}
+
+func (s *Session) UpdateNode(repositoryId string, branchId string, node cloudcms.JsonObject) (cloudcms.JsonObject, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.Nodes[cloudcms.ExtractId(&node)] = node
+	return node, nil
+}
+
+func (s *Session) PatchNode(repositoryId string, branchId string, nodeId string, patchObj cloudcms.JsonObject) (cloudcms.JsonObject, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.Patched = append(s.Patched, nodeId)
+	return s.Nodes[nodeId], nil
+}
+
+func (s *Session) DeleteNode(repositoryId string, branchId string, nodeId string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.Nodes, nodeId)
+	delete(s.Attachments, nodeId)
+	return nil
+}
+
+func (s *Session) AddNodeFeature(repositoryId string, branchId string, nodeId string, featureId string, config cloudcms.JsonObject) error {
+	return nil
+}
+
+func (s *Session) RemoveNodeFeature(repositoryId string, branchId string, nodeId string, featureId string) error {
+	return nil
+}
+
+func (s *Session) UploadAttachment(repositoryId string, branchId string, nodeId string, attachmentId string, file io.Reader, mimeType string, filename string) error {
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.Attachments[nodeId] == nil {
+		s.Attachments[nodeId] = map[string]*Attachment{}
+	}
+	s.nextId++
+	s.Attachments[nodeId][attachmentId] = &Attachment{
+		Data:        data,
+		ContentType: mimeType,
+		Filename:    filename,
+		ObjectId:    fmt.Sprintf("object%d", s.nextId),
+	}
+	return nil
+}
+
+func (s *Session) DownloadAttachment(repositoryId string, branchId string, nodeId string, attachmentId string) (io.ReadCloser, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	att, ok := s.Attachments[nodeId][attachmentId]
+	if !ok {
+		return nil, fmt.Errorf("404: attachment not found: %s", attachmentId)
+	}
+
+	return io.NopCloser(bytes.NewReader(att.Data)), nil
+}
+
+func (s *Session) ListAttachments(repositoryId string, branchId string, nodeId string) (*cloudcms.ResultMap, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rows := []cloudcms.JsonObject{}
+	for id, att := range s.Attachments[nodeId] {
+		rows = append(rows, cloudcms.JsonObject{
+			"attachmentId": id,
+			"length":       float64(len(att.Data)),
+			"contentType":  att.ContentType,
+			"filename":     att.Filename,
+			"objectId":     att.ObjectId,
+		})
+	}
+	sort.Slice(rows, func(i, j int) bool { return rows[i].GetString("attachmentId") < rows[j].GetString("attachmentId") })
+
+	return cloudcms.NewResultMap(rows, 0, len(rows)), nil
+}
+
+func (s *Session) DeleteAttachment(repositoryId string, branchId string, nodeId string, attachmentId string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.Attachments[nodeId], attachmentId)
+	return nil
+}
+
+func (s *Session) ReadDefinition(repositoryId string, branchId string, qname string) (*cloudcms.Definition, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	def, ok := s.Definitions[qname]
+	if !ok {
+		return nil, fmt.Errorf("404: definition not found: %s", qname)
+	}
+
+	copied := *def
+	copied.Properties = map[string]*cloudcms.Schema{}
+	for name, schema := range def.Properties {
+		copied.Properties[name] = schema
+	}
+	return &copied, nil
+}
+
+func (s *Session) CreateDefinition(repositoryId string, branchId string, def *cloudcms.Definition) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	def.Id = def.QName
+	s.Definitions[def.QName] = def
+	return def.Id, nil
+}
+
+func (s *Session) UpdateDefinition(repositoryId string, branchId string, def *cloudcms.Definition) (*cloudcms.Definition, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.Definitions[def.QName] = def
+	return def, nil
+}
+
+func (s *Session) DeleteDefinition(repositoryId string, branchId string, def *cloudcms.Definition) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.Definitions, def.QName)
+	return nil
+}
+
+func (s *Session) ReadBranch(repositoryId string, branchId string) (cloudcms.JsonObject, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return cloudcms.JsonObject{"_doc": branchId, "tip": s.Tip}, nil
+}
+
+// ReadChangesetHistory returns the changesets with a revision after fromChangesetId.
+func (s *Session) ReadChangesetHistory(repositoryId string, branchId string, fromChangesetId string, toChangesetId string) ([]*cloudcms.Changeset, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	res := []*cloudcms.Changeset{}
+	for _, changeset := range s.Changesets {
+		if changeset.Revision > cloudcms.ChangesetRevision(fromChangesetId) {
+			res = append(res, changeset)
+		}
+	}
+
+	return res, nil
+}
+
+func (s *Session) ListChangesetNodes(repositoryId string, changesetId string, pagination cloudcms.JsonObject) (*cloudcms.ResultMap, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return page(s.Written[changesetId], pagination), nil
+}
+
+// page returns the rows selected by the skip and limit of pagination, as copies
+func page(rows []cloudcms.JsonObject, pagination cloudcms.JsonObject) *cloudcms.ResultMap {
+	skip, _ := pagination["skip"].(int)
+	limit, ok := pagination["limit"].(int)
+	if !ok {
+		limit = len(rows)
+	}
+	total := len(rows)
+	if skip > len(rows) {
+		skip = len(rows)
+	}
+	rows = rows[skip:]
+	if len(rows) > limit {
+		rows = rows[:limit]
+	}
+
+	res := make([]cloudcms.JsonObject, len(rows))
+	for i, row := range rows {
+		res[i] = clone(row)
+	}
+
+	return cloudcms.NewResultMap(res, skip, total)
+}
+
+func clone(node cloudcms.JsonObject) cloudcms.JsonObject {
+	res := cloudcms.JsonObject{}
+	for key, val := range node {
+		res[key] = val
+	}
+
+	return res
+}
+
+// matches evaluates a query: properties and dotted paths equal to a value or matching
+// $eq, $ne, $gt, $gte, $lt, $lte, $in and $nin, combined with $and and $or
+func matches(node cloudcms.JsonObject, query map[string]interface{}) bool {
+	for key, cond := range query {
+		switch key {
+		case "$and", "$or":
+			any := false
+			for _, q := range asList(cond) {
+				ok := matches(node, asObject(q))
+				if key == "$and" && !ok {
+					return false
+				}
+				any = any || ok
+			}
+			if key == "$or" && !any {
+				return false
+			}
+			continue
+		}
+
+		val, _ := getPath(node, key)
+		ops := asObject(cond)
+		if ops == nil || !isOperators(ops) {
+			ops = map[string]interface{}{"$eq": cond}
+		}
+		for op, arg := range ops {
+			if !matchOp(val, op, arg) {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+func matchOp(val interface{}, op string, arg interface{}) bool {
+	switch op {
+	case "$eq":
+		return compare(val, arg) == 0
+	case "$ne":
+		return compare(val, arg) != 0
+	case "$gt":
+		return val != nil && compare(val, arg) > 0
+	case "$gte":
+		return val != nil && compare(val, arg) >= 0
+	case "$lt":
+		return val != nil && compare(val, arg) < 0
+	case "$lte":
+		return val != nil && compare(val, arg) <= 0
+	case "$in", "$nin":
+		found := false
+		for _, item := range asList(arg) {
+			found = found || compare(val, item) == 0
+		}
+		return found == (op == "$in")
+	}
+
+	panic("fakesession: unsupported operator " + op)
+}
+
+func isOperators(obj map[string]interface{}) bool {
+	for key := range obj {
+		if !strings.HasPrefix(key, "$") {
+			return false
+		}
+	}
+
+	return len(obj) > 0
+}
+
+// compare orders numbers of any type by value and other values by their text
+func compare(a interface{}, b interface{}) int {
+	x, aNum := number(a)
+	y, bNum := number(b)
+	if aNum && bNum {
+		switch {
+		case x < y:
+			return -1
+		case x > y:
+			return 1
+		}
+		return 0
+	}
+
+	return strings.Compare(fmt.Sprint(a), fmt.Sprint(b))
+}
+
+func number(val interface{}) (float64, bool) {
+	switch v := val.(type) {
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case float64:
+		return v, true
+	}
+
+	return 0, false
+}
+
+func getPath(node cloudcms.JsonObject, field string) (interface{}, bool) {
+	var val interface{} = map[string]interface{}(node)
+	for _, key := range strings.Split(field, ".") {
+		obj := asObject(val)
+		if obj == nil {
+			return nil, false
+		}
+		var ok bool
+		val, ok = obj[key]
+		if !ok {
+			return nil, false
+		}
+	}
+
+	return val, true
+}
+
+func asObject(val interface{}) map[string]interface{} {
+	switch v := val.(type) {
+	case map[string]interface{}:
+		return v
+	case cloudcms.JsonObject:
+		return v
+	}
+
+	return nil
+}
+
+func asList(val interface{}) []interface{} {
+	switch v := val.(type) {
+	case []interface{}:
+		return v
+	case []string:
+		res := make([]interface{}, len(v))
+		for i, s := range v {
+			res[i] = s
+		}
+		return res
+	}
+
+	return nil
+}
+
+type sortKey struct {
+	field     string
+	direction int
+}
+
+// sortKeys reads a sort given as an object, or as raw JSON keeping the order of its fields
+func sortKeys(spec interface{}) ([]sortKey, error) {
+	if spec == nil {
+		return nil, nil
+	}
+
+	raw, ok := spec.(json.RawMessage)
+	if !ok {
+		obj := asObject(spec)
+		keys := []sortKey{}
+		for field, dir := range obj {
+			d, _ := number(dir)
+			keys = append(keys, sortKey{field: field, direction: int(d)})
+		}
+		sort.Slice(keys, func(i, j int) bool { return keys[i].field < keys[j].field })
+		return keys, nil
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	_, err := decoder.Token()
+	if err != nil {
+		return nil, err
+	}
+	keys := []sortKey{}
+	for decoder.More() {
+		field, err := decoder.Token()
+		if err != nil {
+			return nil, err
+		}
+		var dir int
+		err = decoder.Decode(&dir)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, sortKey{field: field.(string), direction: dir})
+	}
+
+	return keys, nil
+}
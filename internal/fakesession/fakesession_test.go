@@ -0,0 +1,40 @@
+package fakesession
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	cloudcms "github.com/gitana/cloudcms-go-driver"
+)
+
+func TestQueryNodes(t *testing.T) {
+	session := New()
+	session.Add(cloudcms.JsonObject{"_doc": "a", "title": "x", "meta": map[string]interface{}{"rank": float64(2)}})
+	session.Add(cloudcms.JsonObject{"_doc": "b", "title": "y", "meta": map[string]interface{}{"rank": float64(1)}})
+	session.Add(cloudcms.JsonObject{"_doc": "c", "title": "x", "meta": map[string]interface{}{"rank": float64(3)}})
+
+	for _, test := range []struct {
+		query      cloudcms.JsonObject
+		pagination cloudcms.JsonObject
+		expected   string
+	}{
+		{cloudcms.JsonObject{"title": "x"}, nil, "a c"},
+		{cloudcms.JsonObject{"meta.rank": cloudcms.JsonObject{"$gte": int64(2)}}, nil, "a c"},
+		{cloudcms.JsonObject{"$or": []interface{}{cloudcms.JsonObject{"title": "y"}, cloudcms.JsonObject{"_doc": cloudcms.JsonObject{"$nin": []string{"a", "b"}}}}}, nil, "b c"},
+		{cloudcms.JsonObject{}, cloudcms.JsonObject{"sort": cloudcms.JsonObject{"meta.rank": -1}}, "c a b"},
+		{cloudcms.JsonObject{}, cloudcms.JsonObject{"sort": json.RawMessage(`{"title":-1,"meta.rank":-1}`), "skip": 1, "limit": 1}, "c"},
+	} {
+		res, err := session.QueryNodes("r", "b", test.query, test.pagination)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids := []string{}
+		for _, row := range res.Rows() {
+			ids = append(ids, row.GetString("_doc"))
+		}
+		if strings.Join(ids, " ") != test.expected {
+			t.Errorf("%v %v: expected %s, got %v", test.query, test.pagination, test.expected, ids)
+		}
+	}
+}
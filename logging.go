@@ -0,0 +1,220 @@
+package cloudcms
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const redacted = "[REDACTED]"
+
+// LoggingRoundTripper logs every request with its method, URL, status, latency and sizes
+// to slog.Default(). Successful requests are logged at info, or the level given to
+// NewLoggingRoundTripper; 429 responses and 5xx responses at warn and error levels, and
+// transport errors at error level. When the logger is enabled for debug, the request
+// headers and form bodies are logged too. Passwords, secrets, tokens and credentials are
+// redacted everywhere.
+type LoggingRoundTripper struct {
+	Proxied http.RoundTripper
+}
+
+func (lrt LoggingRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	return loggingRoundTripper{proxied: lrt.Proxied}.RoundTrip(req)
+}
+
+// LoggingOption configures the round tripper returned by NewLoggingRoundTripper.
+type LoggingOption func(*loggingRoundTripper)
+
+// WithLogger logs to logger instead of slog.Default().
+func WithLogger(logger *slog.Logger) LoggingOption {
+	return func(lrt *loggingRoundTripper) {
+		lrt.logger = logger
+	}
+}
+
+// WithLogLevel logs successful requests at level.
+func WithLogLevel(level slog.Level) LoggingOption {
+	return func(lrt *loggingRoundTripper) {
+		lrt.level = level
+	}
+}
+
+// NewLoggingRoundTripper returns a round tripper that logs like LoggingRoundTripper, with
+// the logger and level set by opts.
+func NewLoggingRoundTripper(proxied http.RoundTripper, opts ...LoggingOption) http.RoundTripper {
+	lrt := loggingRoundTripper{proxied: proxied}
+	for _, opt := range opts {
+		opt(&lrt)
+	}
+
+	return lrt
+}
+
+type loggingRoundTripper struct {
+	proxied http.RoundTripper
+	logger  *slog.Logger
+	level   slog.Level
+}
+
+func (lrt loggingRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	logger := lrt.logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+	ctx := req.Context()
+
+	attrs := []slog.Attr{
+		slog.String("method", req.Method),
+		slog.String("url", redactURL(req.URL)),
+		slog.Int64("requestSize", req.ContentLength),
+	}
+	if logger.Enabled(ctx, slog.LevelDebug) {
+		attrs = append(attrs, slog.Any("headers", redactHeader(req.Header)))
+		form, sent, err := readForm(req)
+		if err != nil {
+			return nil, err
+		}
+		req = sent
+		if form != nil {
+			attrs = append(attrs, slog.String("form", redactValues(form).Encode()))
+		}
+	}
+
+	start := time.Now()
+	res, err := lrt.proxied.RoundTrip(req)
+	attrs = append(attrs, slog.Duration("latency", time.Since(start)))
+
+	if err != nil {
+		attrs = append(attrs, slog.String("error", err.Error()))
+		logger.LogAttrs(ctx, slog.LevelError, "cloudcms request failed", attrs...)
+		return res, err
+	}
+
+	attrs = append(attrs, slog.Int("status", res.StatusCode), slog.Int64("responseSize", res.ContentLength))
+
+	level := lrt.level
+	if res.StatusCode >= 500 {
+		level = slog.LevelError
+	} else if res.StatusCode == http.StatusTooManyRequests && level < slog.LevelWarn {
+		level = slog.LevelWarn
+	}
+	logger.LogAttrs(ctx, level, "cloudcms request", attrs...)
+
+	return res, nil
+}
+
+// readForm returns the body of a form request, such as a token request, and the request to
+// send in its place. The body is read from GetBody if it can be, and otherwise the request
+// is cloned with a copy of the body, so the caller's request is never changed.
+func readForm(req *http.Request) (url.Values, *http.Request, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, req, nil
+	}
+	mediaType, _, _ := mime.ParseMediaType(req.Header.Get("Content-Type"))
+	if mediaType != "application/x-www-form-urlencoded" {
+		return nil, req, nil
+	}
+
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, req, err
+		}
+		defer body.Close()
+		data, err := io.ReadAll(body)
+		if err != nil {
+			return nil, req, err
+		}
+		form, err := url.ParseQuery(string(data))
+		return form, req, err
+	}
+
+	data, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, req, err
+	}
+	clone := req.Clone(req.Context())
+	clone.Body = io.NopCloser(bytes.NewReader(data))
+
+	form, err := url.ParseQuery(string(data))
+	return form, clone, err
+}
+
+// sensitive reports whether a parameter or header holds a credential
+func sensitive(name string) bool {
+	name = strings.ToLower(name)
+	for _, s := range []string{"password", "secret", "token", "authorization", "cookie", "apikey", "api_key"} {
+		if strings.Contains(name, s) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func redactValues(values url.Values) url.Values {
+	res := url.Values{}
+	for key, vals := range values {
+		if sensitive(key) {
+			res[key] = []string{redacted}
+		} else {
+			res[key] = vals
+		}
+	}
+
+	return res
+}
+
+func redactURL(u *url.URL) string {
+	res := *u
+	res.User = nil
+	if res.RawQuery != "" {
+		res.RawQuery = redactValues(res.Query()).Encode()
+	}
+
+	return res.String()
+}
+
+func redactHeader(header http.Header) map[string]string {
+	res := map[string]string{}
+	for key, vals := range header {
+		if sensitive(key) {
+			res[key] = redacted
+		} else {
+			res[key] = strings.Join(vals, ", ")
+		}
+	}
+
+	return res
+}
+
+// parseLogLevel reads "debug", "info", "warn" or "error", and defaults to info, which
+// slog.Default() logs
+func parseLogLevel(s string) slog.Level {
+	var level slog.Level
+	err := level.UnmarshalText([]byte(s))
+	if err != nil {
+		return slog.LevelInfo
+	}
+
+	return level
+}
+
+// logAttrs logs a message of the driver itself, such as a retry, if the session has a logger
+func (session *CloudCmsSession) logAttrs(ctx context.Context, level slog.Level, msg string, attrs ...slog.Attr) {
+	if session.logger != nil {
+		session.logger.LogAttrs(ctx, level, msg, attrs...)
+	}
+}
+
+// Logger returns the logger of the session, or nil if logging is off.
+func (session *CloudCmsSession) Logger() *slog.Logger {
+	return session.logger
+}
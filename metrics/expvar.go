@@ -0,0 +1,80 @@
+// Package metrics records the API requests of a session, through the cloudcms.Instrumentation
+// hook, for expvar or for a Prometheus scrape. It only depends on the standard library.
+package metrics
+
+import (
+	"context"
+	"expvar"
+	"sync"
+
+	cloudcms "github.com/gitana/cloudcms-go-driver"
+)
+
+// OperationStats are the totals of the requests of one operation. Errors counts the requests
+// that failed or were answered with a status of 400 or more.
+type OperationStats struct {
+	Requests        int64   `json:"requests"`
+	Errors          int64   `json:"errors"`
+	DurationSeconds float64 `json:"durationSeconds"`
+	RequestBytes    int64   `json:"requestBytes"`
+	ResponseBytes   int64   `json:"responseBytes"`
+}
+
+// Expvar publishes the stats of each operation as an expvar variable.
+type Expvar struct {
+	mu    sync.Mutex
+	stats map[string]*OperationStats
+}
+
+var _ cloudcms.Instrumentation = (*Expvar)(nil)
+
+// NewExpvar publishes the stats under name, e.g. "cloudcms", which must not already be in
+// use. They are served by expvar.Handler as {"ReadNode": {"requests": 2, ...}, ...}.
+func NewExpvar(name string) *Expvar {
+	e := &Expvar{stats: map[string]*OperationStats{}}
+	expvar.Publish(name, expvar.Func(func() interface{} {
+		return e.Stats()
+	}))
+
+	return e
+}
+
+func (e *Expvar) OnRequestStart(ctx context.Context, info *cloudcms.RequestInfo) context.Context {
+	return ctx
+}
+
+func (e *Expvar) OnRequestEnd(ctx context.Context, info *cloudcms.RequestInfo) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	stats := e.stats[info.Operation]
+	if stats == nil {
+		stats = &OperationStats{}
+		e.stats[info.Operation] = stats
+	}
+
+	stats.Requests++
+	if failed(info) {
+		stats.Errors++
+	}
+	stats.DurationSeconds += info.Duration.Seconds()
+	stats.RequestBytes += info.RequestBytes
+	stats.ResponseBytes += info.ResponseBytes
+}
+
+// Stats returns a copy of the stats by operation.
+func (e *Expvar) Stats() map[string]OperationStats {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	res := map[string]OperationStats{}
+	for op, stats := range e.stats {
+		res[op] = *stats
+	}
+
+	return res
+}
+
+func failed(info *cloudcms.RequestInfo) bool {
+	return info.Err != nil || info.Status == 0 || info.Status >= 400
+}
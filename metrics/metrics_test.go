@@ -0,0 +1,108 @@
+package metrics
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"expvar"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	cloudcms "github.com/gitana/cloudcms-go-driver"
+)
+
+var requests = []cloudcms.RequestInfo{
+	{Operation: "ReadNode", Method: "GET", Route: "/repositories/{repositoryId}/branches/{branchId}/nodes/{nodeId}", Status: 200, Duration: 20 * time.Millisecond, ResponseBytes: 100},
+	{Operation: "ReadNode", Method: "GET", Route: "/repositories/{repositoryId}/branches/{branchId}/nodes/{nodeId}", Status: 404, Duration: 3 * time.Second, ResponseBytes: 10},
+	{Operation: "CreateNode", Method: "POST", Route: "/repositories/{repositoryId}/branches/{branchId}/nodes", Err: errors.New("connection refused"), Duration: time.Millisecond, RequestBytes: 42},
+}
+
+func record(instrumentation cloudcms.Instrumentation) {
+	for _, info := range requests {
+		info := info
+		ctx := instrumentation.OnRequestStart(context.Background(), &info)
+		instrumentation.OnRequestEnd(ctx, &info)
+	}
+}
+
+func TestPrometheus(t *testing.T) {
+	p := NewPrometheus()
+	p.Buckets = []float64{0.01, 0.1, 5}
+	record(p)
+
+	server := httptest.NewServer(p)
+	defer server.Close()
+
+	res, err := server.Client().Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	if !strings.HasPrefix(res.Header.Get("Content-Type"), "text/plain; version=0.0.4") {
+		t.Fatalf("unexpected content type %s", res.Header.Get("Content-Type"))
+	}
+
+	var buf strings.Builder
+	_, err = p.WriteTo(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+
+	read := `operation="ReadNode",method="GET",route="/repositories/{repositoryId}/branches/{branchId}/nodes/{nodeId}"`
+	create := `operation="CreateNode",method="POST",route="/repositories/{repositoryId}/branches/{branchId}/nodes"`
+	for _, line := range []string{
+		"# TYPE cloudcms_requests_total counter",
+		"cloudcms_requests_total{" + read + `,status="200"} 1`,
+		"cloudcms_requests_total{" + read + `,status="404"} 1`,
+		"cloudcms_requests_total{" + create + `,status="error"} 1`,
+		"# TYPE cloudcms_request_duration_seconds histogram",
+		"cloudcms_request_duration_seconds_bucket{" + read + `,le="0.01"} 0`,
+		"cloudcms_request_duration_seconds_bucket{" + read + `,le="0.1"} 1`,
+		"cloudcms_request_duration_seconds_bucket{" + read + `,le="5"} 2`,
+		"cloudcms_request_duration_seconds_bucket{" + read + `,le="+Inf"} 2`,
+		"cloudcms_request_duration_seconds_sum{" + read + "} 3.02",
+		"cloudcms_request_duration_seconds_count{" + read + "} 2",
+		"cloudcms_request_bytes_total{" + create + "} 42",
+		"cloudcms_response_bytes_total{" + read + "} 110",
+	} {
+		if !strings.Contains(out, line+"\n") {
+			t.Errorf("missing line %s in:\n%s", line, out)
+		}
+	}
+
+	if strings.Index(out, `operation="CreateNode"`) > strings.Index(out, `operation="ReadNode"`) {
+		t.Error("series should be sorted")
+	}
+}
+
+func TestQuote(t *testing.T) {
+	if actual := quote("a\"b\\c\nd"); actual != `"a\"b\\c\nd"` {
+		t.Fatalf("unexpected escaping %s", actual)
+	}
+}
+
+func TestExpvar(t *testing.T) {
+	e := NewExpvar("cloudcms_test")
+	record(e)
+
+	stats := e.Stats()
+	read := stats["ReadNode"]
+	if read.Requests != 2 || read.Errors != 1 || read.ResponseBytes != 110 || read.DurationSeconds != 3.02 {
+		t.Fatalf("unexpected ReadNode stats %+v", read)
+	}
+	if stats["CreateNode"].Errors != 1 || stats["CreateNode"].RequestBytes != 42 {
+		t.Fatalf("unexpected CreateNode stats %+v", stats["CreateNode"])
+	}
+
+	var published map[string]OperationStats
+	err := json.Unmarshal([]byte(expvar.Get("cloudcms_test").String()), &published)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if published["ReadNode"].Requests != 2 {
+		t.Fatalf("unexpected published stats %v", published)
+	}
+}
@@ -0,0 +1,214 @@
+package metrics
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	cloudcms "github.com/gitana/cloudcms-go-driver"
+)
+
+// DefaultBuckets are the upper bounds, in seconds, of the request duration histogram.
+var DefaultBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// Prometheus keeps counters and a duration histogram by operation, method and route, and
+// writes them in the Prometheus text exposition format:
+//
+//	cloudcms_requests_total{operation, method, route, status}
+//	cloudcms_request_duration_seconds{operation, method, route}
+//	cloudcms_request_bytes_total{operation, method, route}
+//	cloudcms_response_bytes_total{operation, method, route}
+//
+// The status is "error" for requests that received no response.
+type Prometheus struct {
+	// Namespace prefixes the metric names, "cloudcms" by default.
+	Namespace string
+
+	// Buckets are the histogram bounds, DefaultBuckets by default. They must not be changed
+	// once requests are recorded.
+	Buckets []float64
+
+	mu        sync.Mutex
+	requests  map[requestKey]float64
+	durations map[routeKey]*histogram
+	reqBytes  map[routeKey]float64
+	respBytes map[routeKey]float64
+}
+
+var _ cloudcms.Instrumentation = (*Prometheus)(nil)
+
+type routeKey struct {
+	operation string
+	method    string
+	route     string
+}
+
+type requestKey struct {
+	routeKey
+	status string
+}
+
+type histogram struct {
+	counts []uint64
+	count  uint64
+	sum    float64
+}
+
+func NewPrometheus() *Prometheus {
+	return &Prometheus{
+		Namespace: "cloudcms",
+		Buckets:   DefaultBuckets,
+		requests:  map[requestKey]float64{},
+		durations: map[routeKey]*histogram{},
+		reqBytes:  map[routeKey]float64{},
+		respBytes: map[routeKey]float64{},
+	}
+}
+
+func (p *Prometheus) OnRequestStart(ctx context.Context, info *cloudcms.RequestInfo) context.Context {
+	return ctx
+}
+
+func (p *Prometheus) OnRequestEnd(ctx context.Context, info *cloudcms.RequestInfo) {
+	key := routeKey{operation: info.Operation, method: info.Method, route: info.Route}
+	status := strconv.Itoa(info.Status)
+	if info.Status == 0 {
+		status = "error"
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.requests[requestKey{routeKey: key, status: status}]++
+	p.reqBytes[key] += float64(info.RequestBytes)
+	p.respBytes[key] += float64(info.ResponseBytes)
+
+	h := p.durations[key]
+	if h == nil {
+		h = &histogram{counts: make([]uint64, len(p.Buckets))}
+		p.durations[key] = h
+	}
+	seconds := info.Duration.Seconds()
+	for i, bound := range p.Buckets {
+		if seconds <= bound {
+			h.counts[i]++
+		}
+	}
+	h.count++
+	h.sum += seconds
+}
+
+// ServeHTTP serves the metrics for a Prometheus scrape.
+func (p *Prometheus) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	p.WriteTo(w)
+}
+
+// WriteTo writes the metrics in the text exposition format.
+func (p *Prometheus) WriteTo(w io.Writer) (int64, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	cw := &countingWriter{w: w}
+	bw := bufio.NewWriter(cw)
+	name := p.Namespace
+	if name == "" {
+		name = "cloudcms"
+	}
+
+	fmt.Fprintf(bw, "# HELP %s_requests_total Cloud CMS API requests.\n", name)
+	fmt.Fprintf(bw, "# TYPE %s_requests_total counter\n", name)
+	requestKeys := make([]requestKey, 0, len(p.requests))
+	for key := range p.requests {
+		requestKeys = append(requestKeys, key)
+	}
+	sort.Slice(requestKeys, func(i, j int) bool {
+		a, b := requestKeys[i], requestKeys[j]
+		if a.routeKey != b.routeKey {
+			return lessRoute(a.routeKey, b.routeKey)
+		}
+		return a.status < b.status
+	})
+	for _, key := range requestKeys {
+		fmt.Fprintf(bw, "%s_requests_total{%s,status=%s} %s\n", name, key.labels(), quote(key.status), formatFloat(p.requests[key]))
+	}
+
+	routeKeys := make([]routeKey, 0, len(p.durations))
+	for key := range p.durations {
+		routeKeys = append(routeKeys, key)
+	}
+	sort.Slice(routeKeys, func(i, j int) bool {
+		return lessRoute(routeKeys[i], routeKeys[j])
+	})
+
+	fmt.Fprintf(bw, "# HELP %s_request_duration_seconds Cloud CMS API request duration.\n", name)
+	fmt.Fprintf(bw, "# TYPE %s_request_duration_seconds histogram\n", name)
+	for _, key := range routeKeys {
+		h := p.durations[key]
+		for i, bound := range p.Buckets {
+			fmt.Fprintf(bw, "%s_request_duration_seconds_bucket{%s,le=%s} %d\n", name, key.labels(), quote(formatFloat(bound)), h.counts[i])
+		}
+		fmt.Fprintf(bw, "%s_request_duration_seconds_bucket{%s,le=\"+Inf\"} %d\n", name, key.labels(), h.count)
+		fmt.Fprintf(bw, "%s_request_duration_seconds_sum{%s} %s\n", name, key.labels(), formatFloat(h.sum))
+		fmt.Fprintf(bw, "%s_request_duration_seconds_count{%s} %d\n", name, key.labels(), h.count)
+	}
+
+	for _, metric := range []struct {
+		name   string
+		help   string
+		values map[routeKey]float64
+	}{
+		{"request_bytes_total", "Cloud CMS API request body bytes.", p.reqBytes},
+		{"response_bytes_total", "Cloud CMS API response body bytes.", p.respBytes},
+	} {
+		fmt.Fprintf(bw, "# HELP %s_%s %s\n", name, metric.name, metric.help)
+		fmt.Fprintf(bw, "# TYPE %s_%s counter\n", name, metric.name)
+		for _, key := range routeKeys {
+			fmt.Fprintf(bw, "%s_%s{%s} %s\n", name, metric.name, key.labels(), formatFloat(metric.values[key]))
+		}
+	}
+
+	err := bw.Flush()
+	return cw.n, err
+}
+
+func (k routeKey) labels() string {
+	return fmt.Sprintf("operation=%s,method=%s,route=%s", quote(k.operation), quote(k.method), quote(k.route))
+}
+
+func lessRoute(a routeKey, b routeKey) bool {
+	if a.operation != b.operation {
+		return a.operation < b.operation
+	}
+	if a.method != b.method {
+		return a.method < b.method
+	}
+	return a.route < b.route
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func quote(s string) string {
+	return `"` + labelEscaper.Replace(s) + `"`
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,64 @@
+package cloudcms
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+)
+
+// Middleware wraps the transport of a session, e.g. to add headers or record metrics. It
+// wraps the oauth2 transport, so it sees each request before the access token is added.
+type Middleware func(http.RoundTripper) http.RoundTripper
+
+// RoundTripperFunc adapts a function to http.RoundTripper.
+type RoundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f RoundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// chainMiddlewares wraps transport so that the first middleware sees each request first
+func chainMiddlewares(transport http.RoundTripper, middlewares []Middleware) http.RoundTripper {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		transport = middlewares[i](transport)
+	}
+
+	return transport
+}
+
+// HeaderMiddleware sets the headers on every request that does not already have them.
+func HeaderMiddleware(header http.Header) Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			req = req.Clone(req.Context())
+			for key, values := range header {
+				if req.Header.Get(key) == "" {
+					req.Header[http.CanonicalHeaderKey(key)] = values
+				}
+			}
+
+			return next.RoundTrip(req)
+		})
+	}
+}
+
+// RequestIDMiddleware gives every request a random id in the named header, e.g.
+// "X-Request-Id", unless it already has one.
+func RequestIDMiddleware(headerName string) Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			if req.Header.Get(headerName) == "" {
+				req = req.Clone(req.Context())
+				req.Header.Set(headerName, newRequestId())
+			}
+
+			return next.RoundTrip(req)
+		})
+	}
+}
+
+func newRequestId() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,100 @@
+package cloudcms
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestConnectMiddlewares(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/oauth/token" {
+			json.NewEncoder(w).Encode(JsonObject{"access_token": "tok", "token_type": "bearer", "expires_in": 3600})
+			return
+		}
+
+		if r.Header.Get("Authorization") != "Bearer tok" || r.Header.Get("X-Tenant") != "acme" || r.Header.Get("X-Request-Id") == "" {
+			t.Errorf("headers missing: %v", r.Header)
+		}
+		json.NewEncoder(w).Encode(JsonObject{"_doc": "n1"})
+	}))
+	defer server.Close()
+
+	var mu sync.Mutex
+	calls := []string{}
+	record := func(s string) {
+		mu.Lock()
+		calls = append(calls, s)
+		mu.Unlock()
+	}
+
+	tracing := func(name string) Middleware {
+		return func(next http.RoundTripper) http.RoundTripper {
+			return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+				record(name + " " + req.URL.Path)
+				return next.RoundTrip(req)
+			})
+		}
+	}
+
+	config := &CloudcmsConfig{
+		BaseURL: server.URL,
+		Transport: RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			record("transport " + req.URL.Path)
+			return http.DefaultTransport.RoundTrip(req)
+		}),
+		Middlewares: []Middleware{
+			tracing("outer"),
+			HeaderMiddleware(http.Header{"X-Tenant": []string{"acme"}}),
+			RequestIDMiddleware("X-Request-Id"),
+			tracing("inner"),
+		},
+	}
+
+	session, err := Connect(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = session.ReadNode("r", "b", "n1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{
+		"transport /oauth/token",
+		"outer /repositories/r/branches/b/nodes/n1",
+		"inner /repositories/r/branches/b/nodes/n1",
+		"transport /repositories/r/branches/b/nodes/n1",
+	}
+	if strings.Join(calls, "\n") != strings.Join(expected, "\n") {
+		t.Fatalf("expected calls:\n%s\ngot:\n%s", strings.Join(expected, "\n"), strings.Join(calls, "\n"))
+	}
+}
+
+func TestHeaderMiddlewareKeepsRequestHeaders(t *testing.T) {
+	var seen http.Header
+	transport := HeaderMiddleware(http.Header{"X-Tenant": []string{"acme"}, "Accept": []string{"text/plain"}})(
+		RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			seen = req.Header
+			return &http.Response{StatusCode: 200, Body: http.NoBody}, nil
+		}))
+
+	req, _ := http.NewRequest("GET", "http://example.com", nil)
+	req.Header.Set("Accept", "application/json")
+	_, err := transport.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if seen.Get("Accept") != "application/json" || seen.Get("X-Tenant") != "acme" {
+		t.Fatalf("unexpected headers %v", seen)
+	}
+	if req.Header.Get("X-Tenant") != "" {
+		t.Fatal("the original request should not be changed")
+	}
+}
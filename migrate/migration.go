@@ -0,0 +1,165 @@
+// Package migrate applies ordered, versioned content model changes to a branch.
+package migrate
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	cloudcms "github.com/gitana/cloudcms-go-driver"
+)
+
+const (
+	OpCreateDefinition = "createDefinition"
+	OpUpdateDefinition = "updateDefinition"
+	OpDeleteDefinition = "deleteDefinition"
+	OpAddFeature       = "addFeature"
+	OpRemoveFeature    = "removeFeature"
+	OpPatchNodes       = "patchNodes"
+)
+
+// Step is a single declarative change.
+//
+// createDefinition and updateDefinition use Definition. updateDefinition merges the given
+// properties into the existing definition and drops the ones named in RemoveProperties.
+// deleteDefinition uses QName. addFeature, removeFeature and patchNodes apply to every
+// node matching Query, with Feature and Config, or Patch passed to PatchNode.
+type Step struct {
+	Op               string               `json:"op"`
+	Definition       *cloudcms.Definition `json:"definition,omitempty"`
+	RemoveProperties []string             `json:"removeProperties,omitempty"`
+	QName            string               `json:"qname,omitempty"`
+	Query            cloudcms.JsonObject  `json:"query,omitempty"`
+	Feature          string               `json:"feature,omitempty"`
+	Config           cloudcms.JsonObject  `json:"config,omitempty"`
+	Patch            cloudcms.JsonObject  `json:"patch,omitempty"`
+}
+
+type Migration struct {
+	Version int    `json:"version"`
+	Name    string `json:"name"`
+	Up      []Step `json:"up"`
+	Down    []Step `json:"down,omitempty"`
+}
+
+func (step *Step) Validate() error {
+	switch step.Op {
+	case OpCreateDefinition, OpUpdateDefinition:
+		if step.Definition == nil || step.Definition.QName == "" {
+			return fmt.Errorf("%s requires a definition with a qname", step.Op)
+		}
+	case OpDeleteDefinition:
+		if step.QName == "" {
+			return fmt.Errorf("%s requires a qname", step.Op)
+		}
+	case OpAddFeature, OpRemoveFeature:
+		if step.Query == nil || step.Feature == "" {
+			return fmt.Errorf("%s requires a query and a feature", step.Op)
+		}
+	case OpPatchNodes:
+		if step.Query == nil || step.Patch == nil {
+			return fmt.Errorf("%s requires a query and a patch", step.Op)
+		}
+	default:
+		return fmt.Errorf("unknown op: %q", step.Op)
+	}
+
+	return nil
+}
+
+func (step *Step) String() string {
+	switch step.Op {
+	case OpCreateDefinition, OpUpdateDefinition:
+		return fmt.Sprintf("%s %s", step.Op, step.Definition.QName)
+	case OpDeleteDefinition:
+		return fmt.Sprintf("%s %s", step.Op, step.QName)
+	case OpAddFeature, OpRemoveFeature:
+		q, _ := json.Marshal(step.Query)
+		return fmt.Sprintf("%s %s where %s", step.Op, step.Feature, q)
+	case OpPatchNodes:
+		q, _ := json.Marshal(step.Query)
+		return fmt.Sprintf("%s where %s", step.Op, q)
+	}
+
+	return step.Op
+}
+
+// Validate checks every step and rejects duplicate versions. It leaves migrations in the
+// order given.
+func Validate(migrations []Migration) error {
+	_, err := sortedMigrations(migrations)
+	return err
+}
+
+// sortedMigrations validates migrations and returns a copy of them sorted by version
+func sortedMigrations(migrations []Migration) ([]Migration, error) {
+	sorted := make([]Migration, len(migrations))
+	copy(sorted, migrations)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Version < sorted[j].Version
+	})
+
+	for i, m := range sorted {
+		if m.Version <= 0 {
+			return nil, fmt.Errorf("migration %q: version must be positive", m.Name)
+		}
+		if i > 0 && sorted[i-1].Version == m.Version {
+			return nil, fmt.Errorf("duplicate migration version: %d", m.Version)
+		}
+		for j := range m.Up {
+			if err := m.Up[j].Validate(); err != nil {
+				return nil, fmt.Errorf("migration %d up step %d: %v", m.Version, j, err)
+			}
+		}
+		for j := range m.Down {
+			if err := m.Down[j].Validate(); err != nil {
+				return nil, fmt.Errorf("migration %d down step %d: %v", m.Version, j, err)
+			}
+		}
+	}
+
+	return sorted, nil
+}
+
+// LoadDir reads every *.json migration in dir. A migration without a version takes it from
+// a numeric file name prefix, e.g. 0003_add_author.json.
+func LoadDir(dir string) ([]Migration, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	migrations := []Migration{}
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		var m Migration
+		err = json.Unmarshal(data, &m)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", path, err)
+		}
+
+		base := strings.TrimSuffix(filepath.Base(path), ".json")
+		prefix, name, _ := strings.Cut(base, "_")
+		if m.Version == 0 {
+			m.Version, err = strconv.Atoi(prefix)
+			if err != nil {
+				return nil, fmt.Errorf("%s: missing version", path)
+			}
+		}
+		if m.Name == "" {
+			m.Name = name
+		}
+
+		migrations = append(migrations, m)
+	}
+
+	return sortedMigrations(migrations)
+}
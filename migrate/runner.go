@@ -0,0 +1,353 @@
+package migrate
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	cloudcms "github.com/gitana/cloudcms-go-driver"
+)
+
+// TrackingQName identifies the node that records applied migrations on a branch.
+const TrackingQName = "migrate:history"
+
+const queryPageSize = 100
+
+// Session is the subset of *cloudcms.CloudCmsSession used by the runner.
+type Session interface {
+	QueryNodes(repositoryId string, branchId string, query cloudcms.JsonObject, pagination cloudcms.JsonObject) (*cloudcms.ResultMap, error)
+	CreateNode(repositoryId string, branchId string, obj cloudcms.JsonObject, opts map[string]string) (string, error)
+	UpdateNode(repositoryId string, branchId string, node cloudcms.JsonObject) (cloudcms.JsonObject, error)
+	PatchNode(repositoryId string, branchId string, nodeId string, patchObj cloudcms.JsonObject) (cloudcms.JsonObject, error)
+	AddNodeFeature(repositoryId string, branchId string, nodeId string, featureId string, config cloudcms.JsonObject) error
+	RemoveNodeFeature(repositoryId string, branchId string, nodeId string, featureId string) error
+	ReadDefinition(repositoryId string, branchId string, qname string) (*cloudcms.Definition, error)
+	CreateDefinition(repositoryId string, branchId string, def *cloudcms.Definition) (string, error)
+	UpdateDefinition(repositoryId string, branchId string, def *cloudcms.Definition) (*cloudcms.Definition, error)
+	DeleteDefinition(repositoryId string, branchId string, def *cloudcms.Definition) error
+}
+
+var _ Session = (*cloudcms.CloudCmsSession)(nil)
+
+type AppliedMigration struct {
+	Version   int    `json:"version"`
+	Name      string `json:"name"`
+	AppliedOn int64  `json:"appliedOn"`
+}
+
+type Runner struct {
+	session      Session
+	repositoryId string
+	branchId     string
+
+	// DryRun logs the steps that would run, and the number of nodes they match, without writing.
+	DryRun bool
+
+	// Out receives progress messages.
+	Out io.Writer
+}
+
+func NewRunner(session Session, repositoryId string, branchId string) *Runner {
+	return &Runner{
+		session:      session,
+		repositoryId: repositoryId,
+		branchId:     branchId,
+		Out:          io.Discard,
+	}
+}
+
+func (r *Runner) logf(format string, args ...interface{}) {
+	fmt.Fprintf(r.Out, format+"\n", args...)
+}
+
+func (r *Runner) trackingNode() (cloudcms.JsonObject, error) {
+	res, err := r.session.QueryNodes(r.repositoryId, r.branchId, cloudcms.JsonObject{"_qname": TrackingQName}, cloudcms.JsonObject{"limit": 1})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(res.Rows()) == 0 {
+		return nil, nil
+	}
+
+	return res.Rows()[0], nil
+}
+
+// Applied returns the migrations recorded on the branch, in version order.
+func (r *Runner) Applied() ([]AppliedMigration, error) {
+	node, err := r.trackingNode()
+	if err != nil || node == nil {
+		return nil, err
+	}
+
+	var tracking struct {
+		Applied []AppliedMigration `json:"applied"`
+	}
+	err = cloudcms.DecodeJsonObject(node, &tracking)
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(tracking.Applied, func(i, j int) bool {
+		return tracking.Applied[i].Version < tracking.Applied[j].Version
+	})
+
+	return tracking.Applied, nil
+}
+
+func (r *Runner) saveApplied(applied []AppliedMigration) error {
+	node, err := r.trackingNode()
+	if err != nil {
+		return err
+	}
+
+	entries := make([]interface{}, len(applied))
+	for i, a := range applied {
+		entries[i] = map[string]interface{}{"version": a.Version, "name": a.Name, "appliedOn": a.AppliedOn}
+	}
+
+	if node == nil {
+		_, err = r.session.CreateNode(r.repositoryId, r.branchId, cloudcms.JsonObject{
+			"_qname":  TrackingQName,
+			"title":   "Content model migrations",
+			"applied": entries,
+		}, nil)
+		return err
+	}
+
+	node["applied"] = entries
+	_, err = r.session.UpdateNode(r.repositoryId, r.branchId, node)
+	return err
+}
+
+// Pending returns the migrations that have not been applied to the branch.
+func (r *Runner) Pending(migrations []Migration) ([]Migration, error) {
+	migrations, err := sortedMigrations(migrations)
+	if err != nil {
+		return nil, err
+	}
+
+	applied, err := r.Applied()
+	if err != nil {
+		return nil, err
+	}
+
+	done := map[int]bool{}
+	for _, a := range applied {
+		done[a.Version] = true
+	}
+
+	pending := []Migration{}
+	for _, m := range migrations {
+		if !done[m.Version] {
+			pending = append(pending, m)
+		}
+	}
+
+	return pending, nil
+}
+
+// Up applies every pending migration in version order, recording each one as it completes.
+// It returns the migrations that were applied.
+func (r *Runner) Up(migrations []Migration) ([]Migration, error) {
+	pending, err := r.Pending(migrations)
+	if err != nil {
+		return nil, err
+	}
+
+	applied, err := r.Applied()
+	if err != nil {
+		return nil, err
+	}
+
+	done := []Migration{}
+	for _, m := range pending {
+		r.logf("applying %d %s", m.Version, m.Name)
+		err = r.runSteps(m.Up)
+		if err != nil {
+			return done, fmt.Errorf("migration %d %s: %v", m.Version, m.Name, err)
+		}
+
+		if !r.DryRun {
+			applied = append(applied, AppliedMigration{Version: m.Version, Name: m.Name, AppliedOn: time.Now().UnixMilli()})
+			err = r.saveApplied(applied)
+			if err != nil {
+				return done, err
+			}
+		}
+		done = append(done, m)
+	}
+
+	return done, nil
+}
+
+// Down rolls back every applied migration with a version greater than target, newest first,
+// by running its Down steps. It returns the migrations that were rolled back.
+func (r *Runner) Down(migrations []Migration, target int) ([]Migration, error) {
+	migrations, err := sortedMigrations(migrations)
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[int]Migration{}
+	for _, m := range migrations {
+		byVersion[m.Version] = m
+	}
+
+	applied, err := r.Applied()
+	if err != nil {
+		return nil, err
+	}
+
+	done := []Migration{}
+	for len(applied) > 0 && applied[len(applied)-1].Version > target {
+		last := applied[len(applied)-1]
+		m, ok := byVersion[last.Version]
+		if !ok {
+			return done, fmt.Errorf("migration %d %s is applied but was not provided", last.Version, last.Name)
+		}
+		if len(m.Down) == 0 {
+			return done, fmt.Errorf("migration %d %s has no down steps", m.Version, m.Name)
+		}
+
+		r.logf("rolling back %d %s", m.Version, m.Name)
+		err = r.runSteps(m.Down)
+		if err != nil {
+			return done, fmt.Errorf("rollback %d %s: %v", m.Version, m.Name, err)
+		}
+
+		applied = applied[:len(applied)-1]
+		if !r.DryRun {
+			err = r.saveApplied(applied)
+			if err != nil {
+				return done, err
+			}
+		}
+		done = append(done, m)
+	}
+
+	return done, nil
+}
+
+func (r *Runner) runSteps(steps []Step) error {
+	for i := range steps {
+		step := &steps[i]
+		r.logf("  %s", step)
+
+		err := r.runStep(step)
+		if err != nil {
+			return fmt.Errorf("%s: %v", step, err)
+		}
+	}
+
+	return nil
+}
+
+func (r *Runner) runStep(step *Step) error {
+	switch step.Op {
+	case OpCreateDefinition:
+		if r.DryRun {
+			return nil
+		}
+		def := *step.Definition
+		_, err := r.session.CreateDefinition(r.repositoryId, r.branchId, &def)
+		return err
+
+	case OpUpdateDefinition:
+		if r.DryRun {
+			return nil
+		}
+		existing, err := r.session.ReadDefinition(r.repositoryId, r.branchId, step.Definition.QName)
+		if err != nil {
+			return err
+		}
+		mergeDefinition(existing, step.Definition, step.RemoveProperties)
+		_, err = r.session.UpdateDefinition(r.repositoryId, r.branchId, existing)
+		return err
+
+	case OpDeleteDefinition:
+		if r.DryRun {
+			return nil
+		}
+		existing, err := r.session.ReadDefinition(r.repositoryId, r.branchId, step.QName)
+		if err != nil {
+			return err
+		}
+		return r.session.DeleteDefinition(r.repositoryId, r.branchId, existing)
+	}
+
+	nodeIds, err := r.matchingNodes(step.Query)
+	if err != nil {
+		return err
+	}
+	r.logf("    %d matching nodes", len(nodeIds))
+	if r.DryRun {
+		return nil
+	}
+
+	for _, nodeId := range nodeIds {
+		switch step.Op {
+		case OpAddFeature:
+			err = r.session.AddNodeFeature(r.repositoryId, r.branchId, nodeId, step.Feature, step.Config)
+		case OpRemoveFeature:
+			err = r.session.RemoveNodeFeature(r.repositoryId, r.branchId, nodeId, step.Feature)
+		case OpPatchNodes:
+			_, err = r.session.PatchNode(r.repositoryId, r.branchId, nodeId, step.Patch)
+		}
+		if err != nil {
+			return fmt.Errorf("node %s: %v", nodeId, err)
+		}
+	}
+
+	return nil
+}
+
+// matchingNodes collects all matching ids before any are modified, so that changes made by
+// the step cannot shift the pages still to be read.
+func (r *Runner) matchingNodes(query cloudcms.JsonObject) ([]string, error) {
+	nodeIds := []string{}
+	for skip := 0; ; skip += queryPageSize {
+		res, err := r.session.QueryNodes(r.repositoryId, r.branchId, query, cloudcms.JsonObject{"limit": queryPageSize, "skip": skip, "sort": cloudcms.JsonObject{"_doc": 1}})
+		if err != nil {
+			return nil, err
+		}
+
+		for _, row := range res.Rows() {
+			nodeIds = append(nodeIds, cloudcms.ExtractId(&row))
+		}
+
+		if len(res.Rows()) < queryPageSize {
+			return nodeIds, nil
+		}
+	}
+}
+
+func mergeDefinition(existing *cloudcms.Definition, changes *cloudcms.Definition, remove []string) {
+	if changes.Title != "" {
+		existing.Title = changes.Title
+	}
+	if changes.Description != "" {
+		existing.Description = changes.Description
+	}
+	if changes.Parent != "" {
+		existing.Parent = changes.Parent
+	}
+
+	if existing.Properties == nil {
+		existing.Properties = map[string]*cloudcms.Schema{}
+	}
+	for name, schema := range changes.Properties {
+		existing.Properties[name] = schema
+	}
+	for _, name := range remove {
+		delete(existing.Properties, name)
+	}
+
+	if len(changes.MandatoryFeatures) > 0 && existing.MandatoryFeatures == nil {
+		existing.MandatoryFeatures = map[string]cloudcms.JsonObject{}
+	}
+	for name, config := range changes.MandatoryFeatures {
+		existing.MandatoryFeatures[name] = config
+	}
+}
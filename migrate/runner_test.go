@@ -0,0 +1,173 @@
+package migrate
+
+import (
+	"testing"
+
+	cloudcms "github.com/gitana/cloudcms-go-driver"
+	"github.com/gitana/cloudcms-go-driver/internal/fakesession"
+)
+
+func TestLoadDir(t *testing.T) {
+	migrations, err := LoadDir("testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(migrations) != 2 {
+		t.Fatal("wrong number of migrations")
+	}
+	if migrations[0].Version != 1 || migrations[0].Name != "book" {
+		t.Fatal("version and name should come from the file name")
+	}
+	if migrations[1].Version != 2 || migrations[1].Name != "add book author" {
+		t.Fatal("explicit name should be kept")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	err := Validate([]Migration{
+		{Version: 1, Up: []Step{{Op: OpDeleteDefinition, QName: "store:book"}}},
+		{Version: 1, Up: []Step{{Op: OpDeleteDefinition, QName: "store:author"}}},
+	})
+	if err == nil {
+		t.Fatal("duplicate versions should fail")
+	}
+
+	err = Validate([]Migration{{Version: 1, Up: []Step{{Op: OpPatchNodes}}}})
+	if err == nil {
+		t.Fatal("patch without query should fail")
+	}
+
+	migrations := []Migration{{Version: 2}, {Version: 1}}
+	err = Validate(migrations)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if migrations[0].Version != 2 {
+		t.Fatal("Validate should not reorder the caller's migrations")
+	}
+}
+
+// sortRecorder records the sort of every query
+type sortRecorder struct {
+	*fakesession.Session
+	sorts []interface{}
+}
+
+func (s *sortRecorder) QueryNodes(repositoryId string, branchId string, query cloudcms.JsonObject, pagination cloudcms.JsonObject) (*cloudcms.ResultMap, error) {
+	s.sorts = append(s.sorts, pagination["sort"])
+	return s.Session.QueryNodes(repositoryId, branchId, query, pagination)
+}
+
+func TestMatchingNodesSorted(t *testing.T) {
+	session := &sortRecorder{Session: fakesession.New()}
+	session.Nodes["book1"] = cloudcms.JsonObject{"_doc": "book1", "_type": "store:book"}
+
+	runner := NewRunner(session, "repo", "master")
+	nodeIds, err := runner.matchingNodes(cloudcms.JsonObject{"_type": "store:book"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(nodeIds) != 1 || nodeIds[0] != "book1" {
+		t.Fatalf("unexpected nodes %v", nodeIds)
+	}
+	for _, sort := range session.sorts {
+		if sort, ok := sort.(cloudcms.JsonObject); !ok || sort["_doc"] != 1 {
+			t.Fatalf("queries should be paged in a stable order, got sort %v", sort)
+		}
+	}
+}
+
+func TestUpAndDown(t *testing.T) {
+	migrations, err := LoadDir("testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	session := fakesession.New()
+	session.Nodes["book1"] = cloudcms.JsonObject{"_doc": "book1", "_type": "store:book"}
+	session.Nodes["book2"] = cloudcms.JsonObject{"_doc": "book2", "_type": "store:book"}
+
+	runner := NewRunner(session, "repo", "master")
+
+	done, err := runner.Up(migrations)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(done) != 2 {
+		t.Fatal("both migrations should apply")
+	}
+	if session.Definitions["store:book"].Properties["author"] == nil {
+		t.Fatal("author property should be added")
+	}
+	if len(session.Patched) != 2 {
+		t.Fatal("both books should be patched")
+	}
+
+	applied, err := runner.Applied()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(applied) != 2 || applied[1].Version != 2 {
+		t.Fatal("migrations should be recorded")
+	}
+
+	done, err = runner.Up(migrations)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(done) != 0 {
+		t.Fatal("applied migrations should not run again")
+	}
+
+	done, err = runner.Down(migrations, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(done) != 1 || done[0].Version != 2 {
+		t.Fatal("only migration 2 should roll back")
+	}
+	if session.Definitions["store:book"].Properties["author"] != nil {
+		t.Fatal("author property should be removed")
+	}
+
+	_, err = runner.Down(migrations, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if session.Definitions["store:book"] != nil {
+		t.Fatal("definition should be deleted")
+	}
+
+	applied, _ = runner.Applied()
+	if len(applied) != 0 {
+		t.Fatal("all migrations should be rolled back")
+	}
+}
+
+func TestDryRun(t *testing.T) {
+	migrations, err := LoadDir("testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	session := fakesession.New()
+	session.Nodes["book1"] = cloudcms.JsonObject{"_doc": "book1", "_type": "store:book"}
+
+	runner := NewRunner(session, "repo", "master")
+	runner.DryRun = true
+
+	done, err := runner.Up(migrations)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(done) != 2 {
+		t.Fatal("dry run should report both migrations")
+	}
+	if len(session.Definitions) != 0 || len(session.Patched) != 0 {
+		t.Fatal("dry run should not write")
+	}
+	if len(session.Nodes) != 1 {
+		t.Fatal("dry run should not create the tracking node")
+	}
+}
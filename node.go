@@ -11,10 +11,12 @@ import (
 )
 
 func (session *CloudCmsSession) ReadNode(repositoryId string, branchId string, nodeId string) (JsonObject, error) {
+	session = session.named("ReadNode")
 	return session.Get(fmt.Sprintf("/repositories/%s/branches/%s/nodes/%s", repositoryId, branchId, nodeId), nil)
 }
 
 func (session *CloudCmsSession) QueryNodes(repositoryId string, branchId string, query JsonObject, pagination JsonObject) (*ResultMap, error) {
+	session = session.named("QueryNodes")
 	res, err := session.Post(fmt.Sprintf("/repositories/%s/branches/%s/nodes/query", repositoryId, branchId), ToParams(pagination), MapToReader(query))
 	if err != nil {
 		return nil, err
@@ -24,6 +26,7 @@ func (session *CloudCmsSession) QueryNodes(repositoryId string, branchId string,
 }
 
 func (session *CloudCmsSession) QueryOneNode(repositoryId string, branchId string, query JsonObject) (JsonObject, error) {
+	session = session.named("QueryOneNode")
 
 	res, err := session.QueryNodes(repositoryId, branchId, query, JsonObject{"limit": 1})
 	if err != nil {
@@ -38,6 +41,7 @@ func (session *CloudCmsSession) QueryOneNode(repositoryId string, branchId strin
 }
 
 func (session *CloudCmsSession) SearchNodes(repositoryId string, branchId string, text string, pagination JsonObject) (*ResultMap, error) {
+	session = session.named("SearchNodes")
 	uri := fmt.Sprintf("/repositories/%s/branches/%s/nodes/search", repositoryId, branchId)
 	params := ToParams(pagination)
 	params.Add("text", text)
@@ -51,6 +55,7 @@ func (session *CloudCmsSession) SearchNodes(repositoryId string, branchId string
 }
 
 func (session *CloudCmsSession) FindNodes(repositoryId string, branchId string, config JsonObject, pagination JsonObject) (*ResultMap, error) {
+	session = session.named("FindNodes")
 	uri := fmt.Sprintf("/repositories/%s/branches/%s/nodes/find", repositoryId, branchId)
 	params := ToParams(pagination)
 
@@ -63,6 +68,7 @@ func (session *CloudCmsSession) FindNodes(repositoryId string, branchId string,
 }
 
 func (session *CloudCmsSession) CreateNode(repositoryId string, branchId string, obj JsonObject, opts map[string]string) (string, error) {
+	session = session.named("CreateNode")
 	params := url.Values{}
 	for key, val := range opts {
 		switch key {
@@ -94,6 +100,7 @@ func (session *CloudCmsSession) CreateNode(repositoryId string, branchId string,
 }
 
 func (session *CloudCmsSession) QueryNodeRelatives(repositoryId string, branchId string, nodeId string, associationTypeQName string, associationDirection string, query JsonObject, pagination JsonObject) (*ResultMap, error) {
+	session = session.named("QueryNodeRelatives")
 	params := ToParams(pagination)
 	params.Add("type", associationTypeQName)
 	params.Add("direction", associationDirection)
@@ -107,10 +114,12 @@ func (session *CloudCmsSession) QueryNodeRelatives(repositoryId string, branchId
 }
 
 func (session *CloudCmsSession) QueryNodeChildren(repositoryId string, branchId string, nodeId string, query JsonObject, pagination JsonObject) (*ResultMap, error) {
+	session = session.named("QueryNodeChildren")
 	return session.QueryNodeRelatives(repositoryId, branchId, nodeId, "a:child", "OUTGOING", query, pagination)
 }
 
 func (session *CloudCmsSession) ListNodeAssociations(repositoryId string, branchId string, nodeId string, associationTypeQName string, associationDirection string, pagination JsonObject) (*ResultMap, error) {
+	session = session.named("ListNodeAssociations")
 	params := ToParams(pagination)
 
 	if associationTypeQName != "" {
@@ -129,14 +138,17 @@ func (session *CloudCmsSession) ListNodeAssociations(repositoryId string, branch
 }
 
 func (session *CloudCmsSession) ListOutgoingAssociations(repositoryId string, branchId string, nodeId string, associationTypeQName string, pagination JsonObject) (*ResultMap, error) {
+	session = session.named("ListOutgoingAssociations")
 	return session.ListNodeAssociations(repositoryId, branchId, nodeId, associationTypeQName, "OUTGOING", pagination)
 }
 
 func (session *CloudCmsSession) ListIncomingAssociations(repositoryId string, branchId string, nodeId string, associationTypeQName string, pagination JsonObject) (*ResultMap, error) {
+	session = session.named("ListIncomingAssociations")
 	return session.ListNodeAssociations(repositoryId, branchId, nodeId, associationTypeQName, "INCOMING", pagination)
 }
 
 func (session *CloudCmsSession) Associate(repositoryId string, branchId string, nodeId string, otherNodeId string, associationTypeQName string, associationDirection string, obj JsonObject) (JsonObject, error) {
+	session = session.named("Associate")
 	params := url.Values{"node": []string{otherNodeId}}
 	if associationTypeQName != "" {
 		params.Add("type", associationTypeQName)
@@ -149,6 +161,7 @@ func (session *CloudCmsSession) Associate(repositoryId string, branchId string,
 }
 
 func (session *CloudCmsSession) Unassociate(repositoryId string, branchId string, nodeId string, otherNodeId string, associationTypeQName string, associationDirection string) error {
+	session = session.named("Unassociate")
 	params := url.Values{"node": []string{otherNodeId}}
 	if associationTypeQName != "" {
 		params.Add("type", associationTypeQName)
@@ -162,20 +175,24 @@ func (session *CloudCmsSession) Unassociate(repositoryId string, branchId string
 }
 
 func (session *CloudCmsSession) AssociateChild(repositoryId string, branchId string, nodeId string, otherNodeId string, obj JsonObject) (JsonObject, error) {
+	session = session.named("AssociateChild")
 	return session.Associate(repositoryId, branchId, nodeId, otherNodeId, "a:child", "DIRECTED", obj)
 }
 
 func (session *CloudCmsSession) UnassociateChild(repositoryId string, branchId string, nodeId string, otherNodeId string) error {
+	session = session.named("UnassociateChild")
 	return session.Unassociate(repositoryId, branchId, nodeId, otherNodeId, "a:child", "DIRECTED")
 }
 
 func (session *CloudCmsSession) DeleteNode(repositoryId string, branchId string, nodeId string) error {
+	session = session.named("DeleteNode")
 	uri := fmt.Sprintf("/repositories/%s/branches/%s/nodes/%s", repositoryId, branchId, nodeId)
 	_, err := session.Delete(uri, nil)
 	return err
 }
 
 func (session *CloudCmsSession) DeleteNodes(repositoryId string, branchId string, nodeIds []string) ([]string, error) {
+	session = session.named("DeleteNodes")
 	uri := fmt.Sprintf("/repositories/%s/branches/%s/nodes/delete", repositoryId, branchId)
 	body := JsonObject{
 		"_docs": nodeIds,
@@ -186,10 +203,12 @@ func (session *CloudCmsSession) DeleteNodes(repositoryId string, branchId string
 		return nil, err
 	}
 
-	return res["_docs"].([]string), nil
+	// the ids decode as []interface{}
+	return toStrings(res.GetArray("_docs")), nil
 }
 
 func (session *CloudCmsSession) UpdateNode(repositoryId string, branchId string, node JsonObject) (JsonObject, error) {
+	session = session.named("UpdateNode")
 
 	doc := node["_doc"]
 
@@ -204,21 +223,25 @@ func (session *CloudCmsSession) UpdateNode(repositoryId string, branchId string,
 }
 
 func (session *CloudCmsSession) PatchNode(repositoryId string, branchId string, nodeId string, patchObj JsonObject) (JsonObject, error) {
+	session = session.named("PatchNode")
 
 	return session.Patch(fmt.Sprintf("/repositories/%s/branches/%s/nodes/%s", repositoryId, branchId, nodeId), url.Values{}, MapToReader(patchObj))
 }
 
 func (session *CloudCmsSession) AddNodeFeature(repositoryId string, branchId string, nodeId string, featureId string, config JsonObject) error {
+	session = session.named("AddNodeFeature")
 	_, err := session.Post(fmt.Sprintf("/repositories/%s/branches/%s/nodes/%s/features/%s", repositoryId, branchId, nodeId, featureId), url.Values{}, MapToReader(config))
 	return err
 }
 
 func (session *CloudCmsSession) RemoveNodeFeature(repositoryId string, branchId string, nodeId string, featureId string) error {
+	session = session.named("RemoveNodeFeature")
 	_, err := session.Delete(fmt.Sprintf("/repositories/%s/branches/%s/nodes/%s/features/%s", repositoryId, branchId, nodeId, featureId), url.Values{})
 	return err
 }
 
 func (session *CloudCmsSession) RefreshNode(repositoryId string, branchId string, node JsonObject) error {
+	session = session.named("RefreshNode")
 	doc := node["_doc"]
 
 	// Ensure node id is a string
@@ -234,12 +257,14 @@ func (session *CloudCmsSession) RefreshNode(repositoryId string, branchId string
 }
 
 func (session *CloudCmsSession) ChangeNodeQName(repositoryId string, branchId string, nodeId string, newQName string) error {
+	session = session.named("ChangeNodeQName")
 	params := url.Values{"qname": []string{newQName}}
 	_, err := session.Post(fmt.Sprintf("/repositories/%s/branches/%s/nodes/%s/change_qname", repositoryId, branchId, nodeId), params, nil)
 	return err
 }
 
 func (session *CloudCmsSession) NodeTree(repositoryId string, branchId string, nodeId string, config JsonObject) (JsonObject, error) {
+	session = session.named("NodeTree")
 	params := url.Values{}
 	for key, val := range config {
 		if valStr, ok := val.(string); ok {
@@ -287,6 +312,7 @@ func (session *CloudCmsSession) NodeTree(repositoryId string, branchId string, n
 }
 
 func (session *CloudCmsSession) ResolveNodePath(repositoryId string, branchId string, nodeId string) (string, error) {
+	session = session.named("ResolveNodePath")
 	res, err := session.Get(fmt.Sprintf("/repositories/%s/branches/%s/nodes/%s/path", repositoryId, branchId, nodeId), nil)
 	if err != nil {
 		return "", err
@@ -296,6 +322,7 @@ func (session *CloudCmsSession) ResolveNodePath(repositoryId string, branchId st
 }
 
 func (session *CloudCmsSession) ResolveNodePaths(repositoryId string, branchId string, nodeId string) (map[string]string, error) {
+	session = session.named("ResolveNodePaths")
 	res, err := session.Get(fmt.Sprintf("/repositories/%s/branches/%s/nodes/%s/paths", repositoryId, branchId, nodeId), nil)
 	if err != nil {
 		return nil, err
@@ -311,9 +338,11 @@ func (session *CloudCmsSession) ResolveNodePaths(repositoryId string, branchId s
 }
 
 func (session *CloudCmsSession) TraverseNode(repositoryId string, branchId string, nodeId string, config JsonObject) (JsonObject, error) {
+	session = session.named("TraverseNode")
 	return session.Post(fmt.Sprintf("/repositories/%s/branches/%s/nodes/%s/traverse", repositoryId, branchId, nodeId), nil, MapToReader(JsonObject{"traverse": config}))
 }
 func (session *CloudCmsSession) UploadAttachment(repositoryId string, branchId string, nodeId string, attachmentId string, file io.Reader, mimeType string, filename string) error {
+	session = session.named("UploadAttachment")
 	uri := fmt.Sprintf("/repositories/%s/branches/%s/nodes/%s/attachments/%s", repositoryId, branchId, nodeId, attachmentId)
 
 	if attachmentId == "" {
@@ -350,10 +379,12 @@ func (session *CloudCmsSession) UploadAttachment(repositoryId string, branchId s
 }
 
 func (session *CloudCmsSession) DownloadAttachment(repositoryId string, branchId string, nodeId string, attachmentId string) (io.ReadCloser, error) {
+	session = session.named("DownloadAttachment")
 	return session.Download(fmt.Sprintf("/repositories/%s/branches/%s/nodes/%s/attachments/%s", repositoryId, branchId, nodeId, attachmentId), nil)
 }
 
 func (session *CloudCmsSession) ListAttachments(repositoryId string, branchId string, nodeId string) (*ResultMap, error) {
+	session = session.named("ListAttachments")
 	res, err := session.Get(fmt.Sprintf("/repositories/%s/branches/%s/nodes/%s/attachments", repositoryId, branchId, nodeId), nil)
 	if err != nil {
 		return nil, err
@@ -363,6 +394,7 @@ func (session *CloudCmsSession) ListAttachments(repositoryId string, branchId st
 }
 
 func (session *CloudCmsSession) DeleteAttachment(repositoryId string, branchId string, nodeId string, attachmentId string) error {
+	session = session.named("DeleteAttachment")
 	if attachmentId == "" {
 		attachmentId = "default"
 	}
@@ -372,6 +404,7 @@ func (session *CloudCmsSession) DeleteAttachment(repositoryId string, branchId s
 }
 
 func (session *CloudCmsSession) ListVersions(repositoryId string, branchId string, nodeId string, options JsonObject, pagination JsonObject) (*ResultMap, error) {
+	session = session.named("ListVersions")
 	params := ToParams(options, pagination)
 
 	res, err := session.Get(fmt.Sprintf("/repositories/%s/branches/%s/nodes/%s/versions", repositoryId, branchId, nodeId), params)
@@ -383,10 +416,12 @@ func (session *CloudCmsSession) ListVersions(repositoryId string, branchId strin
 }
 
 func (session *CloudCmsSession) ReadVersion(repositoryId string, branchId string, nodeId string, changesetId string, options JsonObject) (JsonObject, error) {
+	session = session.named("ReadVersion")
 	params := ToParams(options)
 	return session.Get(fmt.Sprintf("/repositories/%s/branches/%s/nodes/%s/versions/%s", repositoryId, branchId, nodeId, changesetId), params)
 }
 
 func (session *CloudCmsSession) RestoreVersion(repositoryId string, branchId string, nodeId string, changesetId string) (JsonObject, error) {
+	session = session.named("RestoreVersion")
 	return session.Post(fmt.Sprintf("/repositories/%s/branches/%s/nodes/%s/versions/%s/restore", repositoryId, branchId, nodeId, changesetId), nil, nil)
 }
@@ -6,6 +6,7 @@ import (
 )
 
 func (session *CloudCmsSession) ReadPlatform() (JsonObject, error) {
+	session = session.named("ReadPlatform")
 	return session.Get("/", nil)
 }
 
@@ -23,10 +24,12 @@ func (session *CloudCmsSession) ReadPlatform() (JsonObject, error) {
 // 		time.sleep(1)
 
 func (session *CloudCmsSession) ReadJob(jobId string) (JsonObject, error) {
+	session = session.named("ReadJob")
 	return session.Get(fmt.Sprintf("/jobs/%s", jobId), nil)
 }
 
 func (session *CloudCmsSession) WaitForJob(jobId string) error {
+	session = session.named("WaitForJob")
 	for {
 		job, err := session.ReadJob(jobId)
 		if err != nil {
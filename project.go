@@ -6,10 +6,12 @@ import "fmt"
 // createProject(obj: Object, callback?: ResultCb<StartJobResult>):  Promise<StartJobResult>
 
 func (session *CloudCmsSession) ReadProject(projectId string) (JsonObject, error) {
+	session = session.named("ReadProject")
 	return session.Get(fmt.Sprintf("/projects/%s", projectId), nil)
 }
 
 func (session *CloudCmsSession) StartCreateProject(obj JsonObject) (string, error) {
+	session = session.named("StartCreateProject")
 	res, err := session.Post("/projects/start", nil, MapToReader(obj))
 
 	if err != nil {
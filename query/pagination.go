@@ -0,0 +1,82 @@
+package query
+
+import (
+	"bytes"
+	"encoding/json"
+
+	cloudcms "github.com/gitana/cloudcms-go-driver"
+)
+
+type sortField struct {
+	field     string
+	direction int
+}
+
+type Pagination struct {
+	limit int
+	skip  int
+	sort  []sortField
+}
+
+func Paginate() *Pagination {
+	return &Pagination{limit: -1, skip: -1}
+}
+
+func (p *Pagination) Limit(limit int) *Pagination {
+	p.limit = limit
+	return p
+}
+
+func (p *Pagination) Skip(skip int) *Pagination {
+	p.skip = skip
+	return p
+}
+
+// Page selects the zero-based page of the given size.
+func (p *Pagination) Page(page int, size int) *Pagination {
+	return p.Limit(size).Skip(page * size)
+}
+
+func (p *Pagination) SortAsc(field string) *Pagination {
+	p.sort = append(p.sort, sortField{field: field, direction: 1})
+	return p
+}
+
+func (p *Pagination) SortDesc(field string) *Pagination {
+	p.sort = append(p.sort, sortField{field: field, direction: -1})
+	return p
+}
+
+// Build renders the pagination object accepted by the driver's query and list methods.
+// The sort spec is kept as raw JSON so that multi-field sorts retain their order.
+func (p *Pagination) Build() cloudcms.JsonObject {
+	res := cloudcms.JsonObject{}
+	if p.limit >= 0 {
+		res["limit"] = p.limit
+	}
+	if p.skip >= 0 {
+		res["skip"] = p.skip
+	}
+
+	if len(p.sort) > 0 {
+		var b bytes.Buffer
+		b.WriteString("{")
+		for i, s := range p.sort {
+			if i > 0 {
+				b.WriteString(",")
+			}
+			key, _ := json.Marshal(s.field)
+			b.Write(key)
+			b.WriteString(":")
+			if s.direction < 0 {
+				b.WriteString("-1")
+			} else {
+				b.WriteString("1")
+			}
+		}
+		b.WriteString("}")
+		res["sort"] = json.RawMessage(b.Bytes())
+	}
+
+	return res
+}
@@ -0,0 +1,228 @@
+// Package query provides builders for Cloud CMS Mongo-style queries and pagination.
+package query
+
+import (
+	"time"
+
+	cloudcms "github.com/gitana/cloudcms-go-driver"
+)
+
+const (
+	ModifiedOnField = "_system.modified_on"
+	CreatedOnField  = "_system.created_on"
+)
+
+type condition struct {
+	field string
+	op    string
+	value interface{}
+
+	// options go into the same operator object as op, e.g. $options with $regex
+	options map[string]interface{}
+}
+
+type Query struct {
+	conditions []condition
+	fields     []string
+}
+
+func New() *Query {
+	return &Query{}
+}
+
+func (q *Query) where(field string, op string, value interface{}) *Query {
+	q.conditions = append(q.conditions, condition{field: field, op: op, value: value})
+	return q
+}
+
+func (q *Query) Eq(field string, value interface{}) *Query {
+	return q.where(field, "", value)
+}
+
+func (q *Query) Ne(field string, value interface{}) *Query {
+	return q.where(field, "$ne", value)
+}
+
+func (q *Query) Gt(field string, value interface{}) *Query {
+	return q.where(field, "$gt", value)
+}
+
+func (q *Query) Gte(field string, value interface{}) *Query {
+	return q.where(field, "$gte", value)
+}
+
+func (q *Query) Lt(field string, value interface{}) *Query {
+	return q.where(field, "$lt", value)
+}
+
+func (q *Query) Lte(field string, value interface{}) *Query {
+	return q.where(field, "$lte", value)
+}
+
+func (q *Query) In(field string, values ...interface{}) *Query {
+	return q.where(field, "$in", values)
+}
+
+func (q *Query) Nin(field string, values ...interface{}) *Query {
+	return q.where(field, "$nin", values)
+}
+
+func (q *Query) All(field string, values ...interface{}) *Query {
+	return q.where(field, "$all", values)
+}
+
+func (q *Query) Exists(field string, exists bool) *Query {
+	return q.where(field, "$exists", exists)
+}
+
+// Regex matches field against pattern. Options are the usual Mongo regex flags, e.g. "i".
+func (q *Query) Regex(field string, pattern string, options string) *Query {
+	c := condition{field: field, op: "$regex", value: pattern}
+	if options != "" {
+		c.options = map[string]interface{}{"$options": options}
+	}
+	q.conditions = append(q.conditions, c)
+
+	return q
+}
+
+func (q *Query) Type(typeQName string) *Query {
+	return q.Eq("_type", typeQName)
+}
+
+func (q *Query) QName(qname string) *Query {
+	return q.Eq("_qname", qname)
+}
+
+// After, Before and Between operate on Cloud CMS date objects such as
+// _system.modified_on, comparing against their epoch millisecond value.
+func (q *Query) After(field string, t time.Time) *Query {
+	return q.Gte(field+".ms", t.UnixMilli())
+}
+
+func (q *Query) Before(field string, t time.Time) *Query {
+	return q.Lt(field+".ms", t.UnixMilli())
+}
+
+func (q *Query) Between(field string, from time.Time, to time.Time) *Query {
+	return q.After(field, from).Before(field, to)
+}
+
+func (q *Query) ModifiedSince(t time.Time) *Query {
+	return q.After(ModifiedOnField, t)
+}
+
+func (q *Query) ModifiedBetween(from time.Time, to time.Time) *Query {
+	return q.Between(ModifiedOnField, from, to)
+}
+
+func (q *Query) CreatedBetween(from time.Time, to time.Time) *Query {
+	return q.Between(CreatedOnField, from, to)
+}
+
+func (q *Query) And(queries ...*Query) *Query {
+	return q.where("$and", "", buildAll(queries))
+}
+
+func (q *Query) Or(queries ...*Query) *Query {
+	return q.where("$or", "", buildAll(queries))
+}
+
+func (q *Query) Nor(queries ...*Query) *Query {
+	return q.where("$nor", "", buildAll(queries))
+}
+
+// Fields restricts the properties returned for each matching node.
+func (q *Query) Fields(fields ...string) *Query {
+	q.fields = append(q.fields, fields...)
+	return q
+}
+
+func And(queries ...*Query) *Query {
+	return New().And(queries...)
+}
+
+func Or(queries ...*Query) *Query {
+	return New().Or(queries...)
+}
+
+func buildAll(queries []*Query) []interface{} {
+	res := make([]interface{}, 0, len(queries))
+	for _, q := range queries {
+		if q != nil {
+			res = append(res, map[string]interface{}(q.Build()))
+		}
+	}
+
+	return res
+}
+
+// Build renders the query into the JsonObject expected by QueryNodes, QueryBranches and QueryRepositories.
+// Several operators on the same field are merged, and a field that is both matched exactly
+// and by operator is rewritten into an $and clause.
+func (q *Query) Build() cloudcms.JsonObject {
+	res := cloudcms.JsonObject{}
+	var conflicts []interface{}
+
+	for _, c := range q.conditions {
+		existing, exists := res[c.field]
+
+		if c.op == "" {
+			if exists {
+				conflicts = append(conflicts, map[string]interface{}{c.field: c.value})
+			} else {
+				res[c.field] = c.value
+			}
+			continue
+		}
+
+		ops := map[string]interface{}{c.op: c.value}
+		for op, value := range c.options {
+			ops[op] = value
+		}
+		if !exists {
+			res[c.field] = ops
+			continue
+		}
+
+		existingOps, ok := existing.(map[string]interface{})
+		dup := false
+		for op := range ops {
+			if _, found := existingOps[op]; found {
+				dup = true
+			}
+		}
+		if !ok || dup {
+			conflicts = append(conflicts, map[string]interface{}{c.field: ops})
+			continue
+		}
+
+		// the existing value may be a map given to Eq, which is the caller's
+		merged := map[string]interface{}{}
+		for op, value := range existingOps {
+			merged[op] = value
+		}
+		for op, value := range ops {
+			merged[op] = value
+		}
+		res[c.field] = merged
+	}
+
+	if len(conflicts) > 0 {
+		if and, ok := res["$and"].([]interface{}); ok {
+			res["$and"] = append(append([]interface{}{}, and...), conflicts...)
+		} else {
+			res["$and"] = conflicts
+		}
+	}
+
+	if len(q.fields) > 0 {
+		fields := map[string]interface{}{}
+		for _, f := range q.fields {
+			fields[f] = 1
+		}
+		res["_fields"] = fields
+	}
+
+	return res
+}
@@ -0,0 +1,109 @@
+package query
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	cloudcms "github.com/gitana/cloudcms-go-driver"
+)
+
+func toJson(t *testing.T, obj cloudcms.JsonObject) string {
+	b, err := json.Marshal(obj)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return string(b)
+}
+
+func TestQueryBuild(t *testing.T) {
+	q := New().
+		Type("store:book").
+		In("tags", "a", "b").
+		Regex("title", "^twelfth", "i").
+		Exists("author", true).
+		Fields("title", "author")
+
+	expected := `{"_fields":{"author":1,"title":1},"_type":"store:book","author":{"$exists":true},"tags":{"$in":["a","b"]},"title":{"$options":"i","$regex":"^twelfth"}}`
+	if actual := toJson(t, q.Build()); actual != expected {
+		t.Fatalf("unexpected query: %s", actual)
+	}
+}
+
+func TestQueryDateRange(t *testing.T) {
+	from := time.UnixMilli(1000)
+	to := time.UnixMilli(2000)
+
+	q := New().ModifiedBetween(from, to)
+
+	expected := `{"_system.modified_on.ms":{"$gte":1000,"$lt":2000}}`
+	if actual := toJson(t, q.Build()); actual != expected {
+		t.Fatalf("unexpected query: %s", actual)
+	}
+}
+
+func TestQueryAndOr(t *testing.T) {
+	q := Or(
+		New().Eq("meal", "lunch"),
+		And(New().Eq("meal", "breakfast"), New().Gt("price", 5)),
+	)
+
+	expected := `{"$or":[{"meal":"lunch"},{"$and":[{"meal":"breakfast"},{"price":{"$gt":5}}]}]}`
+	if actual := toJson(t, q.Build()); actual != expected {
+		t.Fatalf("unexpected query: %s", actual)
+	}
+}
+
+func TestQueryConflicts(t *testing.T) {
+	q := New().Eq("meal", "lunch").Ne("meal", "dinner").Gt("price", 1).Gt("price", 2)
+
+	expected := `{"$and":[{"meal":{"$ne":"dinner"}},{"price":{"$gt":2}}],"meal":"lunch","price":{"$gt":1}}`
+	if actual := toJson(t, q.Build()); actual != expected {
+		t.Fatalf("unexpected query: %s", actual)
+	}
+}
+
+func TestQueryRegexConflicts(t *testing.T) {
+	q := New().Eq("title", "Hamlet").Regex("title", "^ham", "i").Ne("sku", "x").Regex("sku", "^b", "").Regex("sku", "^c", "m")
+
+	expected := `{"$and":[{"title":{"$options":"i","$regex":"^ham"}},{"sku":{"$options":"m","$regex":"^c"}}],"sku":{"$ne":"x","$regex":"^b"},"title":"Hamlet"}`
+	if actual := toJson(t, q.Build()); actual != expected {
+		t.Fatalf("unexpected query: %s", actual)
+	}
+}
+
+func TestQueryKeepsCallerMaps(t *testing.T) {
+	price := map[string]interface{}{"$gt": 1}
+	and := []interface{}{map[string]interface{}{"a": 1}, map[string]interface{}{"b": 1}}
+	q := New().Eq("price", price).Lt("price", 10).Eq("$and", and[:1]).Eq("c", 1).Eq("c", 2)
+
+	expected := `{"$and":[{"a":1},{"c":2}],"c":1,"price":{"$gt":1,"$lt":10}}`
+	if actual := toJson(t, q.Build()); actual != expected {
+		t.Fatalf("unexpected query: %s", actual)
+	}
+	if len(price) != 1 {
+		t.Fatalf("the map given to Eq was changed: %v", price)
+	}
+	if _, ok := and[1].(map[string]interface{})["b"]; !ok {
+		t.Fatalf("the slice given to Eq was changed: %v", and)
+	}
+}
+
+func TestPagination(t *testing.T) {
+	p := Paginate().Page(2, 25).SortDesc("_system.modified_on.ms").SortAsc("title")
+
+	expected := `{"limit":25,"skip":50,"sort":{"_system.modified_on.ms":-1,"title":1}}`
+	if actual := toJson(t, p.Build()); actual != expected {
+		t.Fatalf("unexpected pagination: %s", actual)
+	}
+
+	params := cloudcms.ToParams(p.Build())
+	if params.Get("sort") != `{"_system.modified_on.ms":-1,"title":1}` {
+		t.Fatalf("unexpected sort param: %s", params.Get("sort"))
+	}
+
+	if len(Paginate().Build()) != 0 {
+		t.Fatal("empty pagination should render empty object")
+	}
+}
@@ -0,0 +1,278 @@
+package query
+
+import (
+	cloudcms "github.com/gitana/cloudcms-go-driver"
+)
+
+// Clause is an Elasticsearch query DSL clause.
+type Clause interface {
+	Build() cloudcms.JsonObject
+}
+
+type clause cloudcms.JsonObject
+
+func (c clause) Build() cloudcms.JsonObject {
+	return cloudcms.JsonObject(c)
+}
+
+func Match(field string, text string) Clause {
+	return clause{"match": map[string]interface{}{field: text}}
+}
+
+func MatchPhrase(field string, text string) Clause {
+	return clause{"match_phrase": map[string]interface{}{field: text}}
+}
+
+func MultiMatch(text string, fields ...string) Clause {
+	return clause{"multi_match": map[string]interface{}{"query": text, "fields": fields}}
+}
+
+func QueryString(text string) Clause {
+	return clause{"query_string": map[string]interface{}{"query": text}}
+}
+
+func Term(field string, value interface{}) Clause {
+	return clause{"term": map[string]interface{}{field: value}}
+}
+
+func Terms(field string, values ...interface{}) Clause {
+	return clause{"terms": map[string]interface{}{field: values}}
+}
+
+func Prefix(field string, prefix string) Clause {
+	return clause{"prefix": map[string]interface{}{field: prefix}}
+}
+
+func FieldExists(field string) Clause {
+	return clause{"exists": map[string]interface{}{"field": field}}
+}
+
+type RangeClause struct {
+	field  string
+	bounds map[string]interface{}
+}
+
+func Range(field string) *RangeClause {
+	return &RangeClause{field: field, bounds: map[string]interface{}{}}
+}
+
+func (r *RangeClause) Gt(value interface{}) *RangeClause {
+	r.bounds["gt"] = value
+	return r
+}
+
+func (r *RangeClause) Gte(value interface{}) *RangeClause {
+	r.bounds["gte"] = value
+	return r
+}
+
+func (r *RangeClause) Lt(value interface{}) *RangeClause {
+	r.bounds["lt"] = value
+	return r
+}
+
+func (r *RangeClause) Lte(value interface{}) *RangeClause {
+	r.bounds["lte"] = value
+	return r
+}
+
+func (r *RangeClause) Build() cloudcms.JsonObject {
+	return cloudcms.JsonObject{"range": map[string]interface{}{r.field: r.bounds}}
+}
+
+type BoolClause struct {
+	must               []Clause
+	should             []Clause
+	filter             []Clause
+	mustNot            []Clause
+	minimumShouldMatch int
+}
+
+func Bool() *BoolClause {
+	return &BoolClause{}
+}
+
+func (b *BoolClause) Must(clauses ...Clause) *BoolClause {
+	b.must = append(b.must, clauses...)
+	return b
+}
+
+func (b *BoolClause) Should(clauses ...Clause) *BoolClause {
+	b.should = append(b.should, clauses...)
+	return b
+}
+
+func (b *BoolClause) Filter(clauses ...Clause) *BoolClause {
+	b.filter = append(b.filter, clauses...)
+	return b
+}
+
+func (b *BoolClause) MustNot(clauses ...Clause) *BoolClause {
+	b.mustNot = append(b.mustNot, clauses...)
+	return b
+}
+
+func (b *BoolClause) MinimumShouldMatch(n int) *BoolClause {
+	b.minimumShouldMatch = n
+	return b
+}
+
+func (b *BoolClause) Build() cloudcms.JsonObject {
+	body := map[string]interface{}{}
+	addClauses(body, "must", b.must)
+	addClauses(body, "should", b.should)
+	addClauses(body, "filter", b.filter)
+	addClauses(body, "must_not", b.mustNot)
+	if b.minimumShouldMatch > 0 {
+		body["minimum_should_match"] = b.minimumShouldMatch
+	}
+
+	return cloudcms.JsonObject{"bool": body}
+}
+
+func addClauses(body map[string]interface{}, key string, clauses []Clause) {
+	if len(clauses) == 0 {
+		return
+	}
+
+	arr := make([]interface{}, len(clauses))
+	for i, c := range clauses {
+		arr[i] = map[string]interface{}(c.Build())
+	}
+	body[key] = arr
+}
+
+// Aggregation is an Elasticsearch aggregation, returned as facets in a cloudcms.FindResult.
+type Aggregation interface {
+	Build() cloudcms.JsonObject
+}
+
+type aggregation cloudcms.JsonObject
+
+func (a aggregation) Build() cloudcms.JsonObject {
+	return cloudcms.JsonObject(a)
+}
+
+func TermsAggregation(field string, size int) Aggregation {
+	terms := map[string]interface{}{"field": field}
+	if size > 0 {
+		terms["size"] = size
+	}
+
+	return aggregation{"terms": terms}
+}
+
+func DateHistogramAggregation(field string, interval string) Aggregation {
+	return aggregation{"date_histogram": map[string]interface{}{"field": field, "calendar_interval": interval}}
+}
+
+// RangeAggregation buckets field by consecutive boundaries, e.g. 0, 10, 100 yields [0,10) and [10,100).
+func RangeAggregation(field string, boundaries ...float64) Aggregation {
+	ranges := []interface{}{}
+	for i := 0; i+1 < len(boundaries); i++ {
+		ranges = append(ranges, map[string]interface{}{"from": boundaries[i], "to": boundaries[i+1]})
+	}
+
+	return aggregation{"range": map[string]interface{}{"field": field, "ranges": ranges}}
+}
+
+type Search struct {
+	query        Clause
+	highlight    []string
+	aggregations map[string]Aggregation
+}
+
+func NewSearch(q Clause) *Search {
+	return &Search{query: q}
+}
+
+func (s *Search) Highlight(fields ...string) *Search {
+	s.highlight = append(s.highlight, fields...)
+	return s
+}
+
+func (s *Search) Aggregate(name string, agg Aggregation) *Search {
+	if s.aggregations == nil {
+		s.aggregations = map[string]Aggregation{}
+	}
+	s.aggregations[name] = agg
+	return s
+}
+
+func (s *Search) Build() cloudcms.JsonObject {
+	res := cloudcms.JsonObject{}
+	if s.query != nil {
+		res["query"] = map[string]interface{}(s.query.Build())
+	}
+
+	if len(s.highlight) > 0 {
+		fields := map[string]interface{}{}
+		for _, f := range s.highlight {
+			fields[f] = map[string]interface{}{}
+		}
+		res["highlight"] = map[string]interface{}{"fields": fields}
+	}
+
+	if len(s.aggregations) > 0 {
+		aggs := map[string]interface{}{}
+		for name, agg := range s.aggregations {
+			aggs[name] = map[string]interface{}(agg.Build())
+		}
+		res["aggs"] = aggs
+	}
+
+	return res
+}
+
+// Find combines a Mongo-style query, a full-text or structured search and a traversal
+// into the config object accepted by FindNodes and Find.
+type Find struct {
+	query    *Query
+	search   *Search
+	text     string
+	traverse cloudcms.JsonObject
+}
+
+func NewFind() *Find {
+	return &Find{}
+}
+
+func (f *Find) Query(q *Query) *Find {
+	f.query = q
+	return f
+}
+
+func (f *Find) Search(s *Search) *Find {
+	f.search = s
+	return f
+}
+
+// Text performs a plain full-text search. It is ignored if a structured Search is set.
+func (f *Find) Text(text string) *Find {
+	f.text = text
+	return f
+}
+
+func (f *Find) Traverse(traverse cloudcms.JsonObject) *Find {
+	f.traverse = traverse
+	return f
+}
+
+func (f *Find) Build() cloudcms.JsonObject {
+	res := cloudcms.JsonObject{}
+	if f.query != nil {
+		res["query"] = map[string]interface{}(f.query.Build())
+	}
+
+	if f.search != nil {
+		res["search"] = map[string]interface{}(f.search.Build())
+	} else if f.text != "" {
+		res["search"] = f.text
+	}
+
+	if f.traverse != nil {
+		res["traverse"] = map[string]interface{}(f.traverse)
+	}
+
+	return res
+}
@@ -0,0 +1,37 @@
+package query
+
+import (
+	"testing"
+)
+
+func TestSearchBuild(t *testing.T) {
+	s := NewSearch(
+		Bool().
+			Must(Match("title", "burger")).
+			Filter(Term("meal", "lunch"), Range("price").Gte(5).Lt(10)).
+			MustNot(Prefix("title", "veggie")),
+	).
+		Highlight("title").
+		Aggregate("meals", TermsAggregation("meal", 10))
+
+	expected := `{"aggs":{"meals":{"terms":{"field":"meal","size":10}}},"highlight":{"fields":{"title":{}}},"query":{"bool":{"filter":[{"term":{"meal":"lunch"}},{"range":{"price":{"gte":5,"lt":10}}}],"must":[{"match":{"title":"burger"}}],"must_not":[{"prefix":{"title":"veggie"}}]}}}`
+	if actual := toJson(t, s.Build()); actual != expected {
+		t.Fatalf("unexpected search: %s", actual)
+	}
+}
+
+func TestFindBuild(t *testing.T) {
+	f := NewFind().Query(New().Type("store:book")).Text("shakespeare")
+
+	expected := `{"query":{"_type":"store:book"},"search":"shakespeare"}`
+	if actual := toJson(t, f.Build()); actual != expected {
+		t.Fatalf("unexpected find: %s", actual)
+	}
+
+	f.Search(NewSearch(MultiMatch("shakespeare", "title", "author")))
+
+	expected = `{"query":{"_type":"store:book"},"search":{"query":{"multi_match":{"fields":["title","author"],"query":"shakespeare"}}}}`
+	if actual := toJson(t, f.Build()); actual != expected {
+		t.Fatalf("unexpected find: %s", actual)
+	}
+}
@@ -0,0 +1,256 @@
+package cloudcms
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	defaultMaxRetries = 3
+	defaultRetryAfter = time.Second
+
+	// the rate never drops below this fraction of the configured rate
+	minRateFactor = 0.1
+)
+
+// limiter spaces requests with a token bucket, caps the requests in flight and backs off when
+// the server answers 429. After a 429 every request waits for the Retry-After delay and the
+// rate is halved; each successful request then restores a tenth of the configured rate.
+type limiter struct {
+	mu          sync.Mutex
+	rate        float64
+	maxRate     float64
+	burst       float64
+	tokens      float64
+	last        time.Time
+	pausedUntil time.Time
+	maxRetries  int
+
+	sem chan struct{}
+}
+
+// newLimiter returns nil if the config sets neither a rate limit nor a concurrency cap
+func newLimiter(config *CloudcmsConfig) *limiter {
+	if config == nil || (config.RateLimit <= 0 && config.MaxConcurrentRequests <= 0) {
+		return nil
+	}
+
+	l := &limiter{
+		rate:       config.RateLimit,
+		maxRate:    config.RateLimit,
+		burst:      float64(config.RateBurst),
+		maxRetries: config.MaxRetries,
+		last:       time.Now(),
+	}
+	if l.burst < 1 {
+		l.burst = 1
+	}
+	l.tokens = l.burst
+	if l.maxRetries == 0 {
+		l.maxRetries = defaultMaxRetries
+	}
+	if config.MaxConcurrentRequests > 0 {
+		l.sem = make(chan struct{}, config.MaxConcurrentRequests)
+	}
+
+	return l
+}
+
+// acquire waits for a slot and a token. The slot must be given back with release.
+func (l *limiter) acquire(ctx context.Context) error {
+	if l.sem != nil {
+		select {
+		case l.sem <- struct{}{}:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	for {
+		wait := l.reserve(time.Now())
+		if wait <= 0 {
+			return nil
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			l.release()
+			return ctx.Err()
+		}
+	}
+}
+
+// reserve takes a token and returns 0, or returns how long to wait before trying again
+func (l *limiter) reserve(now time.Time) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if now.Before(l.pausedUntil) {
+		return l.pausedUntil.Sub(now)
+	}
+	if l.rate <= 0 {
+		return 0
+	}
+
+	l.tokens += now.Sub(l.last).Seconds() * l.rate
+	if l.tokens > l.burst {
+		l.tokens = l.burst
+	}
+	l.last = now
+
+	if l.tokens >= 1 {
+		l.tokens--
+		return 0
+	}
+
+	return time.Duration((1 - l.tokens) / l.rate * float64(time.Second))
+}
+
+func (l *limiter) release() {
+	if l.sem != nil {
+		<-l.sem
+	}
+}
+
+// throttled pauses all requests for retryAfter and halves the rate
+func (l *limiter) throttled(now time.Time, retryAfter time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	until := now.Add(retryAfter)
+	if until.After(l.pausedUntil) {
+		l.pausedUntil = until
+	}
+
+	if l.rate > 0 {
+		l.rate /= 2
+		if l.rate < l.maxRate*minRateFactor {
+			l.rate = l.maxRate * minRateFactor
+		}
+		l.tokens = 0
+		l.last = until
+	}
+}
+
+func (l *limiter) succeeded() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.rate < l.maxRate {
+		l.rate += l.maxRate * minRateFactor
+		if l.rate > l.maxRate {
+			l.rate = l.maxRate
+		}
+	}
+}
+
+// limitedRequest sends the request through the limiter, retrying it after a 429 if its body
+// can be sent again. The slot is held until the response body is closed, or for a streamed
+// request until the headers arrive, since its body may be held open while other requests
+// are sent.
+func (session *CloudCmsSession) limitedRequest(req *http.Request) (*http.Response, error) {
+	l := session.limiter
+
+	for attempt := 0; ; attempt++ {
+		err := l.acquire(req.Context())
+		if err != nil {
+			return nil, err
+		}
+
+		resp, err := session.oauthClient.Do(req)
+		if err != nil {
+			l.release()
+			return nil, err
+		}
+
+		if resp.StatusCode == http.StatusTooManyRequests {
+			wait := retryAfter(resp.Header, time.Now())
+			l.throttled(time.Now(), wait)
+
+			if attempt < l.maxRetries && (req.Body == nil || req.GetBody != nil) {
+				session.logAttrs(req.Context(), slog.LevelWarn, "cloudcms request throttled, retrying",
+					slog.String("method", req.Method), slog.String("url", redactURL(req.URL)),
+					slog.Duration("retryAfter", wait), slog.Int("attempt", attempt+1))
+
+				io.Copy(io.Discard, resp.Body)
+				resp.Body.Close()
+				l.release()
+
+				if req.GetBody != nil {
+					req.Body, err = req.GetBody()
+					if err != nil {
+						return nil, err
+					}
+				}
+				continue
+			}
+		} else if (resp.StatusCode >= 200 && resp.StatusCode <= 299) || resp.StatusCode == http.StatusNotModified {
+			l.succeeded()
+		}
+
+		if isStreamed(req.Context()) {
+			l.release()
+		} else {
+			resp.Body = &releasingBody{ReadCloser: resp.Body, release: l.release}
+		}
+		return resp, nil
+	}
+}
+
+type streamedKey struct{}
+
+// streamed marks a request whose response body is handed to the caller, e.g. a download
+func streamed(req *http.Request) *http.Request {
+	return req.WithContext(context.WithValue(req.Context(), streamedKey{}, true))
+}
+
+func isStreamed(ctx context.Context) bool {
+	res, _ := ctx.Value(streamedKey{}).(bool)
+	return res
+}
+
+type releasingBody struct {
+	io.ReadCloser
+	once    sync.Once
+	release func()
+}
+
+func (b *releasingBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(b.release)
+	return err
+}
+
+// retryAfter reads a Retry-After header given in seconds or as an HTTP date
+func retryAfter(header http.Header, now time.Time) time.Duration {
+	value := header.Get("Retry-After")
+	if value == "" {
+		return defaultRetryAfter
+	}
+
+	seconds, err := strconv.Atoi(value)
+	if err == nil {
+		if seconds < 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+
+	date, err := http.ParseTime(value)
+	if err == nil {
+		if date.Before(now) {
+			return 0
+		}
+		return date.Sub(now)
+	}
+
+	return defaultRetryAfter
+}
@@ -0,0 +1,178 @@
+package cloudcms
+
+import (
+	"io"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestLimiterReserve(t *testing.T) {
+	l := newLimiter(&CloudcmsConfig{RateLimit: 10, RateBurst: 2})
+	now := l.last
+
+	if l.reserve(now) != 0 || l.reserve(now) != 0 {
+		t.Fatal("burst should be available at once")
+	}
+	if wait := l.reserve(now); wait != 100*time.Millisecond {
+		t.Fatalf("expected to wait 100ms, got %v", wait)
+	}
+	if l.reserve(now.Add(100*time.Millisecond)) != 0 {
+		t.Fatal("token should be available after 100ms")
+	}
+
+	l.throttled(now, 2*time.Second)
+	if l.rate != 5 {
+		t.Fatalf("expected the rate to be halved, got %v", l.rate)
+	}
+	if wait := l.reserve(now.Add(time.Second)); wait != time.Second {
+		t.Fatalf("expected to wait for the pause, got %v", wait)
+	}
+
+	for i := 0; i < 10; i++ {
+		l.throttled(now, 0)
+	}
+	if l.rate != 1 {
+		t.Fatalf("expected the rate to stop at a tenth, got %v", l.rate)
+	}
+	for i := 0; i < 20; i++ {
+		l.succeeded()
+	}
+	if l.rate != 10 {
+		t.Fatalf("expected the rate to recover, got %v", l.rate)
+	}
+
+	if newLimiter(&CloudcmsConfig{}) != nil {
+		t.Fatal("no limiter expected without limits")
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for value, expected := range map[string]time.Duration{
+		"":                              defaultRetryAfter,
+		"3":                             3 * time.Second,
+		"Mon, 01 Jan 2024 00:00:05 GMT": 5 * time.Second,
+		"Sun, 31 Dec 2023 00:00:00 GMT": 0,
+		"soon":                          defaultRetryAfter,
+	} {
+		header := http.Header{}
+		if value != "" {
+			header.Set("Retry-After", value)
+		}
+		if actual := retryAfter(header, now); actual != expected {
+			t.Errorf("Retry-After %q: expected %v, got %v", value, expected, actual)
+		}
+	}
+}
+
+func TestRequestRetriesThrottled(t *testing.T) {
+	var mu sync.Mutex
+	bodies := []string{}
+
+	session := newTestSession(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+
+		mu.Lock()
+		bodies = append(bodies, string(body))
+		attempt := len(bodies)
+		mu.Unlock()
+
+		if attempt == 1 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.Write([]byte(`{"_doc": "n1"}`))
+	}))
+	session.limiter = newLimiter(&CloudcmsConfig{MaxConcurrentRequests: 1})
+
+	nodeId, err := session.CreateNode("r", "b", JsonObject{"title": "a"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if nodeId != "n1" || len(bodies) != 2 || bodies[1] != bodies[0] {
+		t.Fatalf("expected the request to be sent again with its body, got %v", bodies)
+	}
+
+	session.limiter = newLimiter(&CloudcmsConfig{MaxConcurrentRequests: 1, MaxRetries: -1})
+	bodies = nil
+	_, err = session.CreateNode("r", "b", JsonObject{"title": "a"}, nil)
+	if err == nil || len(bodies) != 1 {
+		t.Fatalf("expected the 429 to be returned without retrying, got %v", err)
+	}
+
+	// the slot of the failed request was given back
+	_, err = session.CreateNode("r", "b", JsonObject{"title": "a"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRequestConcurrencyCap(t *testing.T) {
+	var mu sync.Mutex
+	active, maxActive := 0, 0
+
+	session := newTestSession(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		active++
+		if active > maxActive {
+			maxActive = active
+		}
+		mu.Unlock()
+
+		time.Sleep(10 * time.Millisecond)
+
+		mu.Lock()
+		active--
+		mu.Unlock()
+
+		w.Write([]byte(`{}`))
+	}))
+	session.limiter = newLimiter(&CloudcmsConfig{MaxConcurrentRequests: 2})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 6; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := session.ReadNode("r", "b", "n")
+			if err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if maxActive > 2 {
+		t.Fatalf("expected at most 2 requests in flight, got %d", maxActive)
+	}
+}
+
+func TestDownloadFreesConcurrencySlot(t *testing.T) {
+	session := newTestSession(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{}`))
+	}))
+	session.limiter = newLimiter(&CloudcmsConfig{MaxConcurrentRequests: 1})
+
+	body, err := session.Download("/repositories/r/branches/b/nodes/n/attachments/default", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer body.Close()
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := session.ReadNode("r", "b", "n")
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("a download held open should not block other requests")
+	}
+}
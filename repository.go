@@ -3,19 +3,23 @@ package cloudcms
 import "fmt"
 
 func (session *CloudCmsSession) CreateRepository(obj JsonObject) (JsonObject, error) {
+	session = session.named("CreateRepository")
 	return session.Post("/repositories", nil, MapToReader(obj))
 }
 
 func (session *CloudCmsSession) DeleteRepository(repositoryId string) error {
+	session = session.named("DeleteRepository")
 	_, err := session.Delete(fmt.Sprintf("/repositories/%s", repositoryId), nil)
 	return err
 }
 
 func (session *CloudCmsSession) ReadRepository(repositoryId string) (JsonObject, error) {
+	session = session.named("ReadRepository")
 	return session.Get(fmt.Sprintf("/repositories/%s", repositoryId), nil)
 }
 
 func (session *CloudCmsSession) QueryRepositories(query JsonObject, pagination JsonObject) (*ResultMap, error) {
+	session = session.named("QueryRepositories")
 	res, err := session.Post("/repositories/query", ToParams(pagination), MapToReader(query))
 	if err != nil {
 		return nil, err
@@ -0,0 +1,205 @@
+package cloudcms
+
+import (
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/url"
+	"strings"
+)
+
+// Archive identifies an archive in a vault by its group, artifact and version. Exports
+// write to it and imports read from it. If VaultId is empty, the platform's default vault
+// is used.
+type Archive struct {
+	VaultId  string
+	Group    string
+	Artifact string
+	Version  string
+}
+
+func (a Archive) params() url.Values {
+	params := url.Values{}
+	if a.VaultId != "" {
+		params.Add("vault", a.VaultId)
+	}
+	params.Add("group", a.Group)
+	params.Add("artifact", a.Artifact)
+	params.Add("version", a.Version)
+	params.Add("schedule", "ASYNCHRONOUS")
+
+	return params
+}
+
+func (a Archive) String() string {
+	return strings.Join([]string{a.Group, a.Artifact, a.Version}, ":")
+}
+
+func (a Archive) validate() error {
+	if a.Group == "" || a.Artifact == "" || a.Version == "" {
+		return fmt.Errorf("archive group, artifact and version are required")
+	}
+
+	return nil
+}
+
+// RepositoryReference, BranchReference and NodeReference name the objects to export, or
+// the target of an import.
+func RepositoryReference(platformId string, repositoryId string) string {
+	return fmt.Sprintf("repository://%s/%s", platformId, repositoryId)
+}
+
+func BranchReference(platformId string, repositoryId string, branchId string) string {
+	return fmt.Sprintf("branch://%s/%s/%s", platformId, repositoryId, branchId)
+}
+
+func NodeReference(platformId string, repositoryId string, branchId string, nodeId string) string {
+	return fmt.Sprintf("node://%s/%s/%s/%s", platformId, repositoryId, branchId, nodeId)
+}
+
+// PlatformId returns the id of the platform the session is connected to, as needed in references.
+func (session *CloudCmsSession) PlatformId() (string, error) {
+	session = session.named("PlatformId")
+	platform, err := session.ReadPlatform()
+	if err != nil {
+		return "", err
+	}
+
+	return ExtractId(&platform), nil
+}
+
+// StartExport starts a job that exports the referenced repositories, branches or nodes
+// into archive. It returns the job id.
+func (session *CloudCmsSession) StartExport(references []string, archive Archive, config JsonObject) (string, error) {
+	session = session.named("StartExport")
+	if len(references) == 0 {
+		return "", fmt.Errorf("at least one reference is required")
+	}
+	err := archive.validate()
+	if err != nil {
+		return "", err
+	}
+
+	params := archive.params()
+	for _, ref := range references {
+		params.Add("id", ref)
+	}
+
+	if config == nil {
+		config = JsonObject{}
+	}
+
+	res, err := session.Post("/transfer/export", params, MapToReader(config))
+	if err != nil {
+		return "", err
+	}
+
+	return ExtractId(&res), nil
+}
+
+// StartImport starts a job that imports archive into the referenced target, usually a
+// branch. It returns the job id.
+func (session *CloudCmsSession) StartImport(archive Archive, targetReference string, config JsonObject) (string, error) {
+	session = session.named("StartImport")
+	err := archive.validate()
+	if err != nil {
+		return "", err
+	}
+
+	params := archive.params()
+	params.Add("id", targetReference)
+
+	if config == nil {
+		config = JsonObject{}
+	}
+
+	res, err := session.Post("/transfer/import", params, MapToReader(config))
+	if err != nil {
+		return "", err
+	}
+
+	return ExtractId(&res), nil
+}
+
+func (session *CloudCmsSession) ReadArchive(vaultId string, archiveId string) (JsonObject, error) {
+	session = session.named("ReadArchive")
+	return session.Get(fmt.Sprintf("/vaults/%s/archives/%s", vaultId, archiveId), nil)
+}
+
+func (session *CloudCmsSession) QueryArchives(vaultId string, query JsonObject, pagination JsonObject) (*ResultMap, error) {
+	session = session.named("QueryArchives")
+	res, err := session.Post(fmt.Sprintf("/vaults/%s/archives/query", vaultId), ToParams(pagination), MapToReader(query))
+	if err != nil {
+		return nil, err
+	}
+
+	return ToResultMap(res), nil
+}
+
+// LookupArchive finds an archive by group, artifact and version. It returns nil if there
+// is no such archive.
+func (session *CloudCmsSession) LookupArchive(archive Archive) (JsonObject, error) {
+	session = session.named("LookupArchive")
+	if archive.VaultId == "" {
+		return nil, fmt.Errorf("archive vault id is required")
+	}
+
+	res, err := session.QueryArchives(archive.VaultId, JsonObject{
+		"group":    archive.Group,
+		"artifact": archive.Artifact,
+		"version":  archive.Version,
+	}, JsonObject{"limit": 1})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(res.rows) == 0 {
+		return nil, nil
+	}
+
+	return res.rows[0], nil
+}
+
+func (session *CloudCmsSession) DownloadArchive(vaultId string, archiveId string) (io.ReadCloser, error) {
+	session = session.named("DownloadArchive")
+	return session.Download(fmt.Sprintf("/vaults/%s/archives/%s/download", vaultId, archiveId), nil)
+}
+
+// UploadArchive uploads an archive file, such as one from DownloadArchive on another
+// tenant, so that it can be imported. The archive's group, artifact and version are read
+// from the file by the server. The file is streamed, so a throttled upload is not retried.
+func (session *CloudCmsSession) UploadArchive(vaultId string, file io.Reader, filename string) error {
+	session = session.named("UploadArchive")
+	if filename == "" {
+		filename = "archive.zip"
+	}
+
+	pr, pw := io.Pipe()
+	mw := multipart.NewWriter(pw)
+	contentType := mw.FormDataContentType()
+	go func() {
+		part, err := mw.CreateFormFile("file", filename)
+		if err == nil {
+			_, err = io.Copy(part, file)
+		}
+		if err == nil {
+			err = mw.Close()
+		}
+		pw.CloseWithError(err)
+	}()
+
+	resp, err := session.MultipartPost(fmt.Sprintf("/vaults/%s/archives", vaultId), nil, contentType, pr)
+	// stops the writer if the server answered before reading the whole file
+	pr.Close()
+	if err != nil {
+		return err
+	}
+
+	return resp.Close()
+}
+
+func (session *CloudCmsSession) DeleteArchive(vaultId string, archiveId string) error {
+	session = session.named("DeleteArchive")
+	_, err := session.Delete(fmt.Sprintf("/vaults/%s/archives/%s", vaultId, archiveId), nil)
+	return err
+}
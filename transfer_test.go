@@ -0,0 +1,162 @@
+package cloudcms
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestStartExport(t *testing.T) {
+	session := newTestSession(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/transfer/export" {
+			t.Errorf("unexpected request %s", r.URL.Path)
+		}
+
+		query := r.URL.Query()
+		expected := []string{"branch://p/r/master", "node://p/r/master/n"}
+		if !reflect.DeepEqual(query["id"], expected) {
+			t.Errorf("expected references %v, got %v", expected, query["id"])
+		}
+		if query.Get("group") != "g" || query.Get("artifact") != "a" || query.Get("version") != "1" || query.Get("vault") != "v" {
+			t.Errorf("unexpected archive params %v", query)
+		}
+		if query.Get("schedule") != "ASYNCHRONOUS" {
+			t.Error("export should be scheduled asynchronously")
+		}
+
+		json.NewEncoder(w).Encode(JsonObject{"_doc": "job1"})
+	}))
+
+	archive := Archive{VaultId: "v", Group: "g", Artifact: "a", Version: "1"}
+	jobId, err := session.StartExport([]string{BranchReference("p", "r", "master"), NodeReference("p", "r", "master", "n")}, archive, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if jobId != "job1" {
+		t.Fatalf("unexpected job id %s", jobId)
+	}
+
+	_, err = session.StartExport(nil, archive, nil)
+	if err == nil {
+		t.Fatal("expected missing reference error")
+	}
+	_, err = session.StartImport(Archive{Group: "g"}, BranchReference("p", "r", "master"), nil)
+	if err == nil {
+		t.Fatal("expected incomplete archive error")
+	}
+}
+
+func TestUploadArchive(t *testing.T) {
+	session := newTestSession(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/vaults/v/archives" {
+			t.Errorf("unexpected request %s", r.URL.Path)
+		}
+
+		file, header, err := r.FormFile("file")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		defer file.Close()
+		data, _ := io.ReadAll(file)
+		if header.Filename != "archive.zip" || string(data) != "zip data" {
+			t.Errorf("unexpected upload %s %q", header.Filename, data)
+		}
+
+		json.NewEncoder(w).Encode(JsonObject{"ok": true})
+	}))
+
+	err := session.UploadArchive("v", strings.NewReader("zip data"), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = session.UploadArchive("v", iotest.ErrReader(errors.New("disk failed")), "a.zip")
+	if err == nil || !strings.Contains(err.Error(), "disk failed") {
+		t.Fatalf("expected the read error, got %v", err)
+	}
+}
+
+func TestTransfer(t *testing.T) {
+	session, repository := setupTestRepository(t)
+
+	repositoryId := ExtractId(&repository)
+	branchId := "master"
+	defer session.DeleteRepository(repositoryId)
+
+	_, err := session.CreateNode(repositoryId, branchId, JsonObject{"title": "exported"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	platformId, err := session.PlatformId()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	vaults, err := session.Get("/vaults", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows := vaults.GetObjectArray("rows")
+	if len(rows) == 0 {
+		t.Fatal("no vaults")
+	}
+	vaultId := ExtractId(&rows[0])
+
+	archive := Archive{VaultId: vaultId, Group: "test", Artifact: repositoryId, Version: "1"}
+	jobId, err := session.StartExport([]string{BranchReference(platformId, repositoryId, branchId)}, archive, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = session.WaitForJob(jobId)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	archiveObj, err := session.LookupArchive(archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if archiveObj == nil {
+		t.Fatal("archive not found")
+	}
+	archiveId := ExtractId(&archiveObj)
+	defer session.DeleteArchive(vaultId, archiveId)
+
+	reader, err := session.DownloadArchive(vaultId, archiveId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := io.ReadAll(reader)
+	reader.Close()
+	if err != nil || len(data) == 0 {
+		t.Fatal("failed to download archive")
+	}
+
+	_, target := setupTestRepository(t)
+	targetId := ExtractId(&target)
+	defer session.DeleteRepository(targetId)
+
+	jobId, err = session.StartImport(archive, BranchReference(platformId, targetId, branchId), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = session.WaitForJob(jobId)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	node, err := session.QueryOneNode(targetId, branchId, JsonObject{"title": "exported"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if node == nil {
+		t.Fatal("imported node not found")
+	}
+}
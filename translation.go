@@ -0,0 +1,155 @@
+package cloudcms
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+const (
+	MultilingualFeature       = "f:multilingual"
+	TranslationFeature        = "f:translation"
+	HasTranslationAssociation = "a:has_translation"
+)
+
+// WithLocale returns a copy of the session that asks for content in locale, e.g. "fr_CA".
+// Every request carries an Accept-Language header listing locale and its fallbacks, so
+// ReadNode, QueryNodes and the other reads return translations where they exist.
+func (session *CloudCmsSession) WithLocale(locale string) *CloudCmsSession {
+	res := *session
+	res.locale = locale
+	return &res
+}
+
+// Locale returns the locale set with WithLocale.
+func (session *CloudCmsSession) Locale() string {
+	return session.locale
+}
+
+// LocaleFallbacks returns locale followed by the less specific locales to try after it,
+// e.g. "fr_CA" gives ["fr_CA", "fr"]. Dashes are read as underscores.
+func LocaleFallbacks(locale string) []string {
+	locale = strings.ReplaceAll(strings.TrimSpace(locale), "-", "_")
+
+	res := []string{}
+	for locale != "" {
+		res = append(res, locale)
+
+		idx := strings.LastIndex(locale, "_")
+		if idx < 0 {
+			break
+		}
+		locale = locale[:idx]
+	}
+
+	return res
+}
+
+// acceptLanguage formats the fallbacks of locale as an Accept-Language header
+func acceptLanguage(locale string) string {
+	fallbacks := LocaleFallbacks(locale)
+	for i, l := range fallbacks {
+		fallbacks[i] = strings.ReplaceAll(l, "_", "-")
+		if i > 0 {
+			fallbacks[i] += fmt.Sprintf(";q=%.1f", 1-float64(i)/10)
+		}
+	}
+
+	return strings.Join(fallbacks, ", ")
+}
+
+func translationParams(edition string, locale string) url.Values {
+	params := url.Values{}
+	if edition != "" {
+		params.Add("edition", edition)
+	}
+	if locale != "" {
+		params.Add("locale", locale)
+	}
+
+	return params
+}
+
+// CreateTranslation creates a translation of a node for locale. If edition is empty, the
+// translation belongs to the current edition of the node.
+func (session *CloudCmsSession) CreateTranslation(repositoryId string, branchId string, nodeId string, edition string, locale string, obj JsonObject) (string, error) {
+	session = session.named("CreateTranslation")
+	if locale == "" {
+		return "", fmt.Errorf("locale is required")
+	}
+
+	uri := fmt.Sprintf("/repositories/%s/branches/%s/nodes/%s/i18n", repositoryId, branchId, nodeId)
+	res, err := session.Post(uri, translationParams(edition, locale), MapToReader(obj))
+	if err != nil {
+		return "", err
+	}
+
+	return res.GetString("_doc"), nil
+}
+
+// ListEditions returns the translation editions of a node.
+func (session *CloudCmsSession) ListEditions(repositoryId string, branchId string, nodeId string) ([]string, error) {
+	session = session.named("ListEditions")
+	uri := fmt.Sprintf("/repositories/%s/branches/%s/nodes/%s/i18n/editions", repositoryId, branchId, nodeId)
+	res, err := session.Get(uri, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return toStrings(res.GetArray("editions")), nil
+}
+
+// ListTranslations returns the locales a node is translated into for edition, or for its
+// current edition if edition is empty.
+func (session *CloudCmsSession) ListTranslations(repositoryId string, branchId string, nodeId string, edition string) ([]string, error) {
+	session = session.named("ListTranslations")
+	uri := fmt.Sprintf("/repositories/%s/branches/%s/nodes/%s/i18n/locales", repositoryId, branchId, nodeId)
+	res, err := session.Get(uri, translationParams(edition, ""))
+	if err != nil {
+		return nil, err
+	}
+
+	return toStrings(res.GetArray("locales")), nil
+}
+
+// ReadTranslation reads the translation of a node that best matches locale, walking its
+// fallbacks (fr_CA, then fr) and reading the node itself if none of them is translated.
+func (session *CloudCmsSession) ReadTranslation(repositoryId string, branchId string, nodeId string, edition string, locale string) (JsonObject, error) {
+	session = session.named("ReadTranslation")
+	locales, err := session.ListTranslations(repositoryId, branchId, nodeId, edition)
+	if err != nil {
+		return nil, err
+	}
+
+	match := matchLocale(locales, locale)
+	if match == "" {
+		return session.ReadNode(repositoryId, branchId, nodeId)
+	}
+
+	uri := fmt.Sprintf("/repositories/%s/branches/%s/nodes/%s/i18n", repositoryId, branchId, nodeId)
+	return session.Get(uri, translationParams(edition, match))
+}
+
+// matchLocale returns the first fallback of locale found in available, or "" if none is
+func matchLocale(available []string, locale string) string {
+	for _, candidate := range LocaleFallbacks(locale) {
+		for _, l := range available {
+			if strings.EqualFold(strings.ReplaceAll(l, "-", "_"), candidate) {
+				return l
+			}
+		}
+	}
+
+	return ""
+}
+
+func toStrings(arr []interface{}) []string {
+	res := make([]string, 0, len(arr))
+	for _, v := range arr {
+		if s, ok := v.(string); ok {
+			res = append(res, s)
+		}
+	}
+
+	return res
+}
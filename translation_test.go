@@ -0,0 +1,115 @@
+package cloudcms
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLocaleFallbacks(t *testing.T) {
+	cases := map[string][]string{
+		"fr_CA":      {"fr_CA", "fr"},
+		"zh-Hant-TW": {"zh_Hant_TW", "zh_Hant", "zh"},
+		"en":         {"en"},
+		"":           {},
+	}
+
+	for locale, expected := range cases {
+		if actual := LocaleFallbacks(locale); !reflect.DeepEqual(actual, expected) {
+			t.Fatalf("LocaleFallbacks(%q) = %v, expected %v", locale, actual, expected)
+		}
+	}
+
+	if header := acceptLanguage("fr_CA"); header != "fr-CA, fr;q=0.9" {
+		t.Fatalf("unexpected Accept-Language %q", header)
+	}
+}
+
+func TestReadTranslationFallback(t *testing.T) {
+	session := newTestSession(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/i18n/locales"):
+			json.NewEncoder(w).Encode(JsonObject{"locales": []string{"de", "fr"}})
+		case strings.HasSuffix(r.URL.Path, "/i18n"):
+			json.NewEncoder(w).Encode(JsonObject{"_doc": "translation", "locale": r.URL.Query().Get("locale")})
+		default:
+			json.NewEncoder(w).Encode(JsonObject{"_doc": "master", "acceptLanguage": r.Header.Get("Accept-Language")})
+		}
+	}))
+
+	obj, err := session.ReadTranslation("repo", "master", "node", "", "fr_CA")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if obj.GetString("locale") != "fr" {
+		t.Fatalf("expected fr translation, got %v", obj)
+	}
+
+	obj, err = session.ReadTranslation("repo", "master", "node", "", "es_MX")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if obj.GetString("_doc") != "master" {
+		t.Fatalf("expected master node, got %v", obj)
+	}
+
+	obj, err = session.WithLocale("fr_CA").ReadNode("repo", "master", "node")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if obj.GetString("acceptLanguage") != "fr-CA, fr;q=0.9" {
+		t.Fatalf("expected Accept-Language header, got %v", obj)
+	}
+	if session.Locale() != "" {
+		t.Fatal("WithLocale should not modify the session")
+	}
+}
+
+func TestTranslations(t *testing.T) {
+	session, repository := setupTestRepository(t)
+
+	repositoryId := ExtractId(&repository)
+	branchId := "master"
+	defer session.DeleteRepository(repositoryId)
+
+	nodeId, err := session.CreateNode(repositoryId, branchId, JsonObject{"title": "hello"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = session.AddNodeFeature(repositoryId, branchId, nodeId, MultilingualFeature, JsonObject{"edition": "1.0"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = session.CreateTranslation(repositoryId, branchId, nodeId, "1.0", "fr", JsonObject{"title": "bonjour"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	locales, err := session.ListTranslations(repositoryId, branchId, nodeId, "1.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(locales) != 1 || locales[0] != "fr" {
+		t.Fatalf("expected fr translation, got %v", locales)
+	}
+
+	translation, err := session.ReadTranslation(repositoryId, branchId, nodeId, "1.0", "fr_CA")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if translation.GetString("title") != "bonjour" {
+		t.Fatal("failed to read translation")
+	}
+
+	node, err := session.ReadTranslation(repositoryId, branchId, nodeId, "1.0", "de")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if node.GetString("title") != "hello" {
+		t.Fatal("expected fallback to the master node")
+	}
+}
@@ -1,5 +1,7 @@
 package cloudcms
 
+import "encoding/json"
+
 func ExtractId(obj *JsonObject) string {
 	return obj.GetString("_doc")
 }
@@ -7,3 +9,29 @@ func ExtractId(obj *JsonObject) string {
 func ExtractTitle(obj *JsonObject) string {
 	return obj.GetString("title")
 }
+
+// ToJsonObject converts a struct with json tags into a JsonObject.
+func ToJsonObject(v interface{}) (JsonObject, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	var res JsonObject
+	err = json.Unmarshal(data, &res)
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// DecodeJsonObject decodes obj into the struct pointed to by v using its json tags.
+func DecodeJsonObject(obj JsonObject, v interface{}) error {
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(data, v)
+}
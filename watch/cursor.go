@@ -0,0 +1,103 @@
+package watch
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Cursor is the position of a watcher on its branch.
+type Cursor struct {
+	// ModifiedOn is the _system.modified_on.ms of the last node seen when watching
+	// ByModified, and Ids are the nodes seen with that time.
+	ModifiedOn int64    `json:"modifiedOn,omitempty"`
+	Ids        []string `json:"ids,omitempty"`
+
+	// Changeset is the last changeset seen when watching ByChangeset.
+	Changeset string `json:"changeset,omitempty"`
+}
+
+// IsZero reports whether the cursor has no position.
+func (c Cursor) IsZero() bool {
+	return c.ModifiedOn == 0 && len(c.Ids) == 0 && c.Changeset == ""
+}
+
+// CursorStore keeps the cursor of a watcher between runs.
+type CursorStore interface {
+	// Load returns the saved cursor, or a zero cursor if none was saved.
+	Load() (Cursor, error)
+	Save(cursor Cursor) error
+}
+
+// FileStore keeps the cursor in a JSON file.
+type FileStore struct {
+	Path string
+}
+
+var _ CursorStore = (*FileStore)(nil)
+
+func NewFileStore(path string) *FileStore {
+	return &FileStore{Path: path}
+}
+
+func (s *FileStore) Load() (Cursor, error) {
+	var cursor Cursor
+	data, err := os.ReadFile(s.Path)
+	if errors.Is(err, os.ErrNotExist) {
+		return cursor, nil
+	}
+	if err != nil {
+		return cursor, err
+	}
+
+	err = json.Unmarshal(data, &cursor)
+	return cursor, err
+}
+
+// Save replaces the file through a rename, so a crash leaves the previous cursor in place.
+func (s *FileStore) Save(cursor Cursor) error {
+	data, err := json.Marshal(cursor)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.Path), filepath.Base(s.Path)+".*")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	return os.Rename(tmp.Name(), s.Path)
+}
+
+// MemoryStore keeps the cursor for the life of the process.
+type MemoryStore struct {
+	mu     sync.Mutex
+	cursor Cursor
+}
+
+var _ CursorStore = (*MemoryStore)(nil)
+
+func (s *MemoryStore) Load() (Cursor, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.cursor, nil
+}
+
+func (s *MemoryStore) Save(cursor Cursor) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.cursor = cursor
+	return nil
+}
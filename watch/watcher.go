@@ -0,0 +1,379 @@
+// Package watch polls a branch and reports the nodes created, updated and deleted on it.
+//
+// A watcher either queries the nodes by _system.modified_on, which is cheap but cannot see
+// deletions, or follows the changeset history of the branch, which sees every write. Its
+// position is a Cursor, saved in a CursorStore after every poll so a restarted watcher
+// resumes where it stopped. Events are delivered at least once: the events of a poll that
+// was interrupted may be sent again.
+package watch
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	cloudcms "github.com/gitana/cloudcms-go-driver"
+)
+
+const (
+	defaultInterval = 10 * time.Second
+	defaultPageSize = 100
+
+	modifiedOnField = "_system.modified_on.ms"
+)
+
+// Session is the subset of *cloudcms.CloudCmsSession used by the watcher.
+type Session interface {
+	QueryNodes(repositoryId string, branchId string, query cloudcms.JsonObject, pagination cloudcms.JsonObject) (*cloudcms.ResultMap, error)
+	ReadBranch(repositoryId string, branchId string) (cloudcms.JsonObject, error)
+	ReadChangesetHistory(repositoryId string, branchId string, fromChangesetId string, toChangesetId string) ([]*cloudcms.Changeset, error)
+	ListChangesetNodes(repositoryId string, changesetId string, pagination cloudcms.JsonObject) (*cloudcms.ResultMap, error)
+}
+
+var _ Session = (*cloudcms.CloudCmsSession)(nil)
+
+type Mode string
+
+const (
+	// ByModified queries the nodes modified since the cursor. Deletions are not seen.
+	ByModified Mode = "modified"
+
+	// ByChangeset lists the nodes written by the changesets after the cursor.
+	ByChangeset Mode = "changeset"
+)
+
+type EventType string
+
+const (
+	Created EventType = "created"
+	Updated EventType = "updated"
+	Deleted EventType = "deleted"
+)
+
+// Event is a change to a node.
+type Event struct {
+	Type   EventType
+	NodeId string
+
+	// Node is the node as written, or for a deletion as the changeset recorded it.
+	Node cloudcms.JsonObject
+
+	// Changeset is the changeset that wrote the node, when watching ByChangeset.
+	Changeset string
+}
+
+type Watcher struct {
+	session      Session
+	repositoryId string
+	branchId     string
+
+	// Mode is ByModified or ByChangeset.
+	Mode Mode
+
+	// Query narrows the nodes watched ByModified, e.g. {"_type": "custom:article"}.
+	Query cloudcms.JsonObject
+
+	// Interval is the time between polls, 10 seconds by default.
+	Interval time.Duration
+
+	// PageSize is the number of nodes read at a time.
+	PageSize int
+
+	// Store keeps the cursor. Without one, every run starts from the current state of the
+	// branch.
+	Store CursorStore
+
+	// Out receives progress messages and the errors of failed polls.
+	Out io.Writer
+}
+
+func NewWatcher(session Session, repositoryId string, branchId string, mode Mode) *Watcher {
+	return &Watcher{
+		session:      session,
+		repositoryId: repositoryId,
+		branchId:     branchId,
+		Mode:         mode,
+		Interval:     defaultInterval,
+		PageSize:     defaultPageSize,
+		Out:          io.Discard,
+	}
+}
+
+func (w *Watcher) logf(format string, args ...interface{}) {
+	fmt.Fprintf(w.Out, format+"\n", args...)
+}
+
+// Watch polls the branch every Interval and sends the changes to events until ctx is done,
+// then closes events and returns nil. A failed poll is logged and tried again at the next
+// interval. Watch only returns an error if the cursor cannot be loaded or saved.
+//
+// A watcher without a saved cursor starts from the current state of the branch, so the
+// first poll sends nothing.
+func (w *Watcher) Watch(ctx context.Context, events chan<- Event) error {
+	defer close(events)
+
+	cursor := Cursor{}
+	if w.Store != nil {
+		var err error
+		cursor, err = w.Store.Load()
+		if err != nil {
+			return fmt.Errorf("failed to load cursor: %w", err)
+		}
+	}
+
+	interval := w.Interval
+	if interval <= 0 {
+		interval = defaultInterval
+	}
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-timer.C:
+		}
+
+		before := cursor
+		var err error
+		if cursor.IsZero() {
+			cursor, err = w.Start()
+		} else {
+			err = w.Poll(ctx, &cursor, events)
+		}
+		if err != nil && ctx.Err() == nil {
+			w.logf("poll of %s/%s failed: %v", w.repositoryId, w.branchId, err)
+		}
+
+		if w.Store != nil && !sameCursor(before, cursor) {
+			err = w.Store.Save(cursor)
+			if err != nil {
+				return fmt.Errorf("failed to save cursor: %w", err)
+			}
+		}
+
+		timer.Reset(interval)
+	}
+}
+
+// Start returns the cursor at the current state of the branch.
+func (w *Watcher) Start() (Cursor, error) {
+	cursor := Cursor{}
+	if w.Mode == ByChangeset {
+		branch, err := w.session.ReadBranch(w.repositoryId, w.branchId)
+		if err != nil {
+			return cursor, err
+		}
+		cursor.Changeset = branch.GetString("tip")
+		if cursor.Changeset == "" {
+			return cursor, fmt.Errorf("branch %s has no tip changeset", w.branchId)
+		}
+		return cursor, nil
+	}
+
+	res, err := w.session.QueryNodes(w.repositoryId, w.branchId, w.query(0), cloudcms.JsonObject{
+		"limit": w.pageSize(),
+		"sort":  cloudcms.JsonObject{modifiedOnField: -1},
+	})
+	if err != nil {
+		return cursor, err
+	}
+	for _, node := range res.Rows() {
+		ms := modifiedOn(node)
+		if cursor.ModifiedOn != 0 && ms != cursor.ModifiedOn {
+			break
+		}
+		cursor.ModifiedOn = ms
+		cursor.Ids = append(cursor.Ids, node.GetString("_doc"))
+	}
+	if cursor.IsZero() {
+		// an empty branch: everything from now on is new
+		cursor.ModifiedOn = 1
+	}
+
+	return cursor, nil
+}
+
+// Poll sends the changes after cursor to events and moves cursor past them. If ctx is done
+// before every change is sent, cursor stays after the last one sent.
+func (w *Watcher) Poll(ctx context.Context, cursor *Cursor, events chan<- Event) error {
+	if w.Mode == ByChangeset {
+		return w.pollChangesets(ctx, cursor, events)
+	}
+
+	return w.pollModified(ctx, cursor, events)
+}
+
+func (w *Watcher) pollModified(ctx context.Context, cursor *Cursor, events chan<- Event) error {
+	seen := map[string]bool{}
+	for _, id := range cursor.Ids {
+		seen[id] = true
+	}
+
+	// every page starts after the cursor, so the pages do not shift when nodes change
+	// while they are read
+	for {
+		res, err := w.session.QueryNodes(w.repositoryId, w.branchId, w.after(*cursor), cloudcms.JsonObject{
+			"limit": w.pageSize(),
+			"sort":  cloudcms.JsonObject{modifiedOnField: 1},
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, node := range res.Rows() {
+			id := node.GetString("_doc")
+			ms := modifiedOn(node)
+			if ms < cursor.ModifiedOn || (ms == cursor.ModifiedOn && seen[id]) {
+				continue
+			}
+
+			event := Event{Type: Updated, NodeId: id, Node: node}
+			if createdOn(node) == ms {
+				event.Type = Created
+			}
+			err = send(ctx, events, event)
+			if err != nil {
+				return err
+			}
+
+			if ms > cursor.ModifiedOn {
+				cursor.ModifiedOn = ms
+				cursor.Ids = nil
+				seen = map[string]bool{}
+			}
+			// a copy, so a saved cursor is never changed
+			cursor.Ids = append(cursor.Ids[:len(cursor.Ids):len(cursor.Ids)], id)
+			seen[id] = true
+		}
+
+		if len(res.Rows()) < w.pageSize() {
+			return nil
+		}
+	}
+}
+
+func (w *Watcher) pollChangesets(ctx context.Context, cursor *Cursor, events chan<- Event) error {
+	changesets, err := w.session.ReadChangesetHistory(w.repositoryId, w.branchId, cursor.Changeset, "")
+	if err != nil {
+		return err
+	}
+
+	for _, changeset := range changesets {
+		w.logf("reading changeset %s", changeset.Id)
+		for skip := 0; ; skip += w.pageSize() {
+			res, err := w.session.ListChangesetNodes(w.repositoryId, changeset.Id, cloudcms.JsonObject{
+				"limit": w.pageSize(),
+				"skip":  skip,
+			})
+			if err != nil {
+				return err
+			}
+
+			for _, node := range res.Rows() {
+				event := Event{Type: Updated, NodeId: node.GetString("_doc"), Node: node, Changeset: changeset.Id}
+				system := node.GetObject("_system")
+				if deleted, _ := system["deleted"].(bool); deleted {
+					event.Type = Deleted
+				} else if createdOn(node) == modifiedOn(node) {
+					event.Type = Created
+				}
+
+				err = send(ctx, events, event)
+				if err != nil {
+					return err
+				}
+			}
+
+			if len(res.Rows()) < w.pageSize() {
+				break
+			}
+		}
+
+		// the changeset is resumed from its start if the poll stops half way
+		cursor.Changeset = changeset.Id
+	}
+
+	return nil
+}
+
+func send(ctx context.Context, events chan<- Event, event Event) error {
+	select {
+	case events <- event:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// after returns Query limited to the nodes after cursor: those modified later, or at the
+// same time but not in cursor.Ids
+func (w *Watcher) after(cursor Cursor) cloudcms.JsonObject {
+	query := w.query(cursor.ModifiedOn)
+	if len(cursor.Ids) == 0 {
+		return query
+	}
+
+	delete(query, modifiedOnField)
+	and, _ := query["$and"].([]interface{})
+	query["$and"] = append(and[:len(and):len(and)], cloudcms.JsonObject{
+		"$or": []interface{}{
+			cloudcms.JsonObject{modifiedOnField: cloudcms.JsonObject{"$gt": cursor.ModifiedOn}},
+			cloudcms.JsonObject{modifiedOnField: cursor.ModifiedOn, "_doc": cloudcms.JsonObject{"$nin": cursor.Ids}},
+		},
+	})
+
+	return query
+}
+
+// query returns Query limited to the nodes modified at or after since
+func (w *Watcher) query(since int64) cloudcms.JsonObject {
+	query := cloudcms.JsonObject{}
+	for key, val := range w.Query {
+		query[key] = val
+	}
+	if since > 0 {
+		query[modifiedOnField] = cloudcms.JsonObject{"$gte": since}
+	}
+
+	return query
+}
+
+func (w *Watcher) pageSize() int {
+	if w.PageSize <= 0 {
+		return defaultPageSize
+	}
+
+	return w.PageSize
+}
+
+func sameCursor(a Cursor, b Cursor) bool {
+	if a.ModifiedOn != b.ModifiedOn || a.Changeset != b.Changeset || len(a.Ids) != len(b.Ids) {
+		return false
+	}
+	for i := range a.Ids {
+		if a.Ids[i] != b.Ids[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+func modifiedOn(node cloudcms.JsonObject) int64 {
+	return systemMillis(node, "modified_on")
+}
+
+func createdOn(node cloudcms.JsonObject) int64 {
+	return systemMillis(node, "created_on")
+}
+
+// systemMillis returns a _system timestamp, e.g. {"_system": {"modified_on": {"ms": 1}}}
+func systemMillis(node cloudcms.JsonObject, field string) int64 {
+	system := node.GetObject("_system")
+	date := system.GetObject(field)
+	ms, _ := date["ms"].(float64)
+	return int64(ms)
+}
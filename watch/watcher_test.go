@@ -0,0 +1,192 @@
+package watch
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	cloudcms "github.com/gitana/cloudcms-go-driver"
+	"github.com/gitana/cloudcms-go-driver/internal/fakesession"
+)
+
+func newSession(nodes ...cloudcms.JsonObject) *fakesession.Session {
+	session := fakesession.New()
+	for _, n := range nodes {
+		session.Add(n)
+	}
+
+	return session
+}
+
+func node(id string, createdOn int64, modifiedOn int64) cloudcms.JsonObject {
+	return cloudcms.JsonObject{
+		"_doc": id,
+		"_system": map[string]interface{}{
+			"created_on":  map[string]interface{}{"ms": float64(createdOn)},
+			"modified_on": map[string]interface{}{"ms": float64(modifiedOn)},
+		},
+	}
+}
+
+func poll(t *testing.T, w *Watcher, cursor *Cursor) []Event {
+	events := make(chan Event, 100)
+	err := w.Poll(context.Background(), cursor, events)
+	if err != nil {
+		t.Fatal(err)
+	}
+	close(events)
+
+	res := []Event{}
+	for event := range events {
+		res = append(res, event)
+	}
+	return res
+}
+
+func TestPollModified(t *testing.T) {
+	session := newSession(node("old", 1, 10), node("tie", 5, 10))
+	w := NewWatcher(session, "r", "b", ByModified)
+	w.PageSize = 2
+
+	cursor, err := w.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cursor.ModifiedOn != 10 || len(cursor.Ids) != 2 {
+		t.Fatalf("unexpected start cursor %+v", cursor)
+	}
+	if events := poll(t, w, &cursor); len(events) != 0 {
+		t.Fatalf("expected no events, got %v", events)
+	}
+
+	session.Add(node("late-tie", 3, 10))
+	session.Add(node("new", 20, 20))
+	session.Add(node("edited", 2, 30))
+	session.Add(node("edited-too", 2, 30))
+
+	events := poll(t, w, &cursor)
+	expected := []Event{{Type: Updated, NodeId: "late-tie"}, {Type: Created, NodeId: "new"}, {Type: Updated, NodeId: "edited"}, {Type: Updated, NodeId: "edited-too"}}
+	if len(events) != len(expected) {
+		t.Fatalf("expected %d events, got %v", len(expected), events)
+	}
+	for i, e := range expected {
+		if events[i].Type != e.Type || events[i].NodeId != e.NodeId || events[i].Node == nil {
+			t.Errorf("event %d: expected %s %s, got %s %s", i, e.Type, e.NodeId, events[i].Type, events[i].NodeId)
+		}
+	}
+	if cursor.ModifiedOn != 30 || len(cursor.Ids) != 2 {
+		t.Fatalf("unexpected cursor %+v", cursor)
+	}
+}
+
+func TestPollModifiedPages(t *testing.T) {
+	session := newSession(node("start", 1, 1))
+	w := NewWatcher(session, "r", "b", ByModified)
+	w.PageSize = 2
+
+	cursor, err := w.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// more ties than fit in a page, and a node of the first page edited while the
+	// next ones are read
+	for _, id := range []string{"t1", "t2", "t3", "t4", "t5"} {
+		session.Add(node(id, 5, 5))
+	}
+	session.Add(node("later", 6, 6))
+	session.Queried = func() {
+		session.Queried = nil
+		session.Nodes["t1"] = node("t1", 5, 7)
+	}
+
+	ids := []string{}
+	for _, event := range poll(t, w, &cursor) {
+		ids = append(ids, event.NodeId)
+	}
+	expected := "t1 t2 t3 t4 t5 later t1"
+	if strings.Join(ids, " ") != expected || cursor.ModifiedOn != 7 {
+		t.Fatalf("expected %s, got %v and %+v", expected, ids, cursor)
+	}
+}
+
+func TestPollChangesets(t *testing.T) {
+	deleted := node("gone", 1, 2)
+	deleted["_system"].(map[string]interface{})["deleted"] = true
+	session := fakesession.New()
+	session.Tip = "1:aaa"
+	session.Written = map[string][]cloudcms.JsonObject{
+		"2:bbb": {node("a", 5, 5), node("b", 1, 5)},
+		"3:ccc": {deleted},
+	}
+	w := NewWatcher(session, "r", "b", ByChangeset)
+
+	cursor, err := w.Start()
+	if err != nil || cursor.Changeset != "1:aaa" {
+		t.Fatalf("expected the tip, got %+v %v", cursor, err)
+	}
+
+	session.Changesets = []*cloudcms.Changeset{{Id: "1:aaa", Revision: 1}, {Id: "2:bbb", Revision: 2}, {Id: "3:ccc", Revision: 3}}
+	events := poll(t, w, &cursor)
+	if len(events) != 3 {
+		t.Fatalf("expected 3 events, got %v", events)
+	}
+	if events[0].Type != Created || events[1].Type != Updated || events[2].Type != Deleted || events[2].NodeId != "gone" || events[2].Changeset != "3:ccc" {
+		t.Fatalf("unexpected events %v", events)
+	}
+	if cursor.Changeset != "3:ccc" {
+		t.Fatalf("unexpected cursor %+v", cursor)
+	}
+}
+
+func TestWatchResumes(t *testing.T) {
+	store := NewFileStore(filepath.Join(t.TempDir(), "cursor.json"))
+	session := newSession(node("a", 1, 1))
+
+	run := func(until int) []Event {
+		w := NewWatcher(session, "r", "b", ByModified)
+		w.Interval = time.Millisecond
+		w.Store = store
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		events := make(chan Event)
+		errs := make(chan error, 1)
+		go func() { errs <- w.Watch(ctx, events) }()
+
+		res := []Event{}
+		for len(res) < until {
+			res = append(res, <-events)
+		}
+		for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(time.Millisecond) {
+			if cursor, _ := store.Load(); !cursor.IsZero() {
+				break
+			}
+		}
+		cancel()
+		for range events {
+		}
+		if err := <-errs; err != nil {
+			t.Fatal(err)
+		}
+		return res
+	}
+
+	run(0)
+	cursor, _ := store.Load()
+	if cursor.ModifiedOn != 1 {
+		t.Fatalf("the start cursor should be saved, got %+v", cursor)
+	}
+
+	session.Add(node("b", 2, 2))
+	if events := run(1); events[0].NodeId != "b" {
+		t.Fatalf("unexpected events %v", events)
+	}
+
+	session.Add(node("c", 3, 3))
+	if events := run(1); events[0].NodeId != "c" {
+		t.Fatalf("the watcher should resume after b, got %v", events)
+	}
+}
@@ -0,0 +1,314 @@
+// Package webhook receives the web hooks that Cloud CMS rules post when nodes change or
+// workflows move on.
+//
+// A Receiver is an http.Handler. It checks the shared secret or signature of each request,
+// parses the payload into an Event and calls the handlers registered for its type:
+//
+//	receiver := webhook.NewReceiver(os.Getenv("CLOUDCMS_WEBHOOK_SECRET"))
+//	receiver.On(webhook.NodeUpdated, func(ctx context.Context, event *webhook.Event) error {
+//		return rebuild(event.NodeId)
+//	})
+//	http.Handle("/hooks/cloudcms", receiver)
+package webhook
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+
+	cloudcms "github.com/gitana/cloudcms-go-driver"
+)
+
+const (
+	// DefaultSignatureHeader carries the shared secret, or the signature of the body.
+	DefaultSignatureHeader = "X-Cloudcms-Signature"
+
+	defaultMaxBodySize = 10 << 20
+)
+
+type EventType string
+
+const (
+	NodeCreated        EventType = "node.created"
+	NodeUpdated        EventType = "node.updated"
+	NodeDeleted        EventType = "node.deleted"
+	WorkflowTransition EventType = "workflow.transition"
+
+	// Unknown is the type of the events not recognized, e.g. from custom rule payloads.
+	Unknown EventType = "unknown"
+)
+
+// Event is a parsed web hook payload.
+type Event struct {
+	Type EventType
+
+	// Name is the event as named by the payload, e.g. "p:afterUpdateNode".
+	Name string
+
+	RepositoryId string
+	BranchId     string
+	NodeId       string
+
+	// Node is the node the event is about, if the payload has one.
+	Node cloudcms.JsonObject
+
+	// Workflow and Transition describe a workflow transition.
+	Workflow   cloudcms.JsonObject
+	Transition string
+
+	// Payload is the whole payload.
+	Payload cloudcms.JsonObject
+}
+
+// Handler handles an event. An error answers the web hook with a 500 so it can be retried,
+// and is passed to the receiver's OnError.
+type Handler func(ctx context.Context, event *Event) error
+
+type Receiver struct {
+	// Secret, if set, must be sent in SignatureHeader, either as is or as the hex encoded
+	// HMAC-SHA256 of the body, optionally prefixed with "sha256=".
+	Secret string
+
+	// SignatureHeader is DefaultSignatureHeader unless set.
+	SignatureHeader string
+
+	// MaxBodySize caps the payload size, 10 MB by default.
+	MaxBodySize int64
+
+	// OnError, if set, is called with the error of a failed handler. The web hook itself is
+	// only answered "handler failed".
+	OnError func(ctx context.Context, event *Event, err error)
+
+	mu       sync.RWMutex
+	handlers map[EventType][]Handler
+	any      []Handler
+}
+
+func NewReceiver(secret string) *Receiver {
+	return &Receiver{
+		Secret:          secret,
+		SignatureHeader: DefaultSignatureHeader,
+		MaxBodySize:     defaultMaxBodySize,
+		handlers:        map[EventType][]Handler{},
+	}
+}
+
+// On registers a handler for the events of a type. Handlers run in the order registered.
+func (r *Receiver) On(eventType EventType, handler Handler) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.handlers == nil {
+		r.handlers = map[EventType][]Handler{}
+	}
+	r.handlers[eventType] = append(r.handlers[eventType], handler)
+}
+
+// OnAny registers a handler for every event, called after the handlers of its type.
+func (r *Receiver) OnAny(handler Handler) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.any = append(r.any, handler)
+}
+
+func (r *Receiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	maxBodySize := r.MaxBodySize
+	if maxBodySize <= 0 {
+		maxBodySize = defaultMaxBodySize
+	}
+	body, err := io.ReadAll(http.MaxBytesReader(w, req.Body, maxBodySize))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			http.Error(w, "payload too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, "failed to read payload", http.StatusBadRequest)
+		return
+	}
+
+	if !r.Verify(body, req.Header) {
+		http.Error(w, "invalid signature", http.StatusUnauthorized)
+		return
+	}
+
+	event, err := Parse(body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	err = r.dispatch(req.Context(), event)
+	if err != nil {
+		if r.OnError != nil {
+			r.OnError(req.Context(), event, err)
+		}
+		http.Error(w, "handler failed", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (r *Receiver) dispatch(ctx context.Context, event *Event) error {
+	r.mu.RLock()
+	handlers := append(append([]Handler{}, r.handlers[event.Type]...), r.any...)
+	r.mu.RUnlock()
+
+	for _, handler := range handlers {
+		err := handler(ctx, event)
+		if err != nil {
+			return fmt.Errorf("%s handler failed: %w", event.Type, err)
+		}
+	}
+
+	return nil
+}
+
+// Verify reports whether the header of a request carries the secret or the signature of
+// body. Every request is accepted if Secret is empty.
+func (r *Receiver) Verify(body []byte, header http.Header) bool {
+	if r.Secret == "" {
+		return true
+	}
+
+	name := r.SignatureHeader
+	if name == "" {
+		name = DefaultSignatureHeader
+	}
+	value := header.Get(name)
+	if value == "" {
+		return false
+	}
+
+	if subtle.ConstantTimeCompare([]byte(value), []byte(r.Secret)) == 1 {
+		return true
+	}
+
+	signature, err := hex.DecodeString(strings.TrimPrefix(value, "sha256="))
+	if err != nil {
+		return false
+	}
+	return hmac.Equal(signature, mac(r.Secret, body))
+}
+
+// Sign returns the signature of body, as sent in the signature header.
+func Sign(secret string, body []byte) string {
+	return "sha256=" + hex.EncodeToString(mac(secret, body))
+}
+
+func mac(secret string, body []byte) []byte {
+	h := hmac.New(sha256.New, []byte(secret))
+	h.Write(body)
+	return h.Sum(nil)
+}
+
+// Parse reads a web hook payload. The event name is read from "event" (a string, or an
+// object with a "type" or "name"), "type", "eventType" or "policy", and the node from
+// "node" or "object".
+func Parse(body []byte) (*Event, error) {
+	payload := cloudcms.JsonObject{}
+	err := json.Unmarshal(body, &payload)
+	if err != nil {
+		return nil, fmt.Errorf("invalid payload: %v", err)
+	}
+
+	event := &Event{Payload: payload}
+	info := payload.GetObject("event")
+	if name, ok := payload["event"].(string); ok {
+		event.Name = name
+	} else {
+		event.Name = firstString(info, "type", "name")
+	}
+	if event.Name == "" {
+		event.Name = firstString(payload, "type", "eventType", "policy")
+	}
+
+	event.Node = payload.GetObject("node")
+	if event.Node == nil {
+		event.Node = payload.GetObject("object")
+	}
+	event.Workflow = payload.GetObject("workflow")
+	event.Transition = firstString(payload, "transition")
+	if event.Transition == "" {
+		event.Transition = firstString(event.Workflow, "transition")
+	}
+
+	event.RepositoryId = firstString(payload, "repositoryId", "repository")
+	if event.RepositoryId == "" {
+		event.RepositoryId = firstString(info, "repositoryId", "repository")
+	}
+	if event.RepositoryId == "" {
+		event.RepositoryId = firstString(event.Node, "repositoryId", "repository")
+	}
+	event.BranchId = firstString(payload, "branchId", "branch")
+	if event.BranchId == "" {
+		event.BranchId = firstString(info, "branchId", "branch")
+	}
+	if event.BranchId == "" {
+		event.BranchId = firstString(event.Node, "branchId", "branch")
+	}
+	event.NodeId = firstString(payload, "nodeId")
+	if event.NodeId == "" {
+		event.NodeId = firstString(event.Node, "_doc")
+	}
+
+	event.Type = eventType(event.Name, event.Workflow != nil)
+	return event, nil
+}
+
+// nodePolicies maps the names of the node policies to their event types. Other policies,
+// e.g. "p:afterCreateAssociation", are Unknown.
+var nodePolicies = map[string]EventType{
+	"p:beforeCreateNode": NodeCreated,
+	"p:afterCreateNode":  NodeCreated,
+	"p:beforeUpdateNode": NodeUpdated,
+	"p:afterUpdateNode":  NodeUpdated,
+	"p:beforeDeleteNode": NodeDeleted,
+	"p:afterDeleteNode":  NodeDeleted,
+}
+
+// eventType recognizes the names of the node policies, of workflow events and of the
+// driver's own event types
+func eventType(name string, hasWorkflow bool) EventType {
+	if t, ok := nodePolicies[name]; ok {
+		return t
+	}
+
+	lower := strings.ToLower(name)
+	switch {
+	case EventType(name) == NodeCreated, EventType(name) == NodeUpdated, EventType(name) == NodeDeleted, EventType(name) == WorkflowTransition:
+		return EventType(name)
+	case strings.Contains(lower, "workflow") || strings.Contains(lower, "transition") || (name == "" && hasWorkflow):
+		return WorkflowTransition
+	}
+
+	return Unknown
+}
+
+// firstString returns the first of keys holding a string in obj
+func firstString(obj cloudcms.JsonObject, keys ...string) string {
+	for _, key := range keys {
+		if s, ok := obj[key].(string); ok && s != "" {
+			return s
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,140 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func post(t *testing.T, handler http.Handler, body string, header http.Header) *http.Response {
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	req, _ := http.NewRequest("POST", server.URL, strings.NewReader(body))
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	res, err := server.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	return res
+}
+
+func TestReceiver(t *testing.T) {
+	receiver := NewReceiver("s3cret")
+	received := []*Event{}
+	receiver.On(NodeUpdated, func(ctx context.Context, event *Event) error {
+		received = append(received, event)
+		return nil
+	})
+	all := 0
+	receiver.OnAny(func(ctx context.Context, event *Event) error {
+		all++
+		return nil
+	})
+
+	body := `{"event": {"type": "p:afterUpdateNode", "repository": "r1"}, "branchId": "b1", "node": {"_doc": "n1", "title": "hello"}}`
+
+	for _, test := range []struct {
+		name   string
+		header http.Header
+		status int
+	}{
+		{"missing", nil, http.StatusUnauthorized},
+		{"wrong", http.Header{DefaultSignatureHeader: {"nope"}}, http.StatusUnauthorized},
+		{"wrong signature", http.Header{DefaultSignatureHeader: {Sign("other", []byte(body))}}, http.StatusUnauthorized},
+		{"secret", http.Header{DefaultSignatureHeader: {"s3cret"}}, http.StatusNoContent},
+		{"signature", http.Header{DefaultSignatureHeader: {Sign("s3cret", []byte(body))}}, http.StatusNoContent},
+	} {
+		res := post(t, receiver, body, test.header)
+		if res.StatusCode != test.status {
+			t.Errorf("%s: expected %d, got %d", test.name, test.status, res.StatusCode)
+		}
+	}
+
+	if len(received) != 2 || all != 2 {
+		t.Fatalf("expected 2 events, got %d and %d", len(received), all)
+	}
+	event := received[0]
+	if event.Name != "p:afterUpdateNode" || event.RepositoryId != "r1" || event.BranchId != "b1" || event.NodeId != "n1" || event.Node.GetString("title") != "hello" {
+		t.Fatalf("unexpected event %+v", event)
+	}
+}
+
+func TestReceiverErrors(t *testing.T) {
+	receiver := NewReceiver("")
+	receiver.MaxBodySize = 64
+	receiver.On(NodeDeleted, func(ctx context.Context, event *Event) error {
+		return errors.New("index unavailable")
+	})
+	var reported error
+	receiver.OnError = func(ctx context.Context, event *Event, err error) {
+		reported = err
+	}
+
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+	res, err := server.Client().Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("expected 405 for a GET, got %d", res.StatusCode)
+	}
+
+	tooLarge := `{"node": "` + strings.Repeat("x", 64) + `"}`
+	for body, status := range map[string]int{
+		`not json`:                      http.StatusBadRequest,
+		tooLarge:                        http.StatusRequestEntityTooLarge,
+		`{"type": "p:afterDeleteNode"}`: http.StatusInternalServerError,
+		`{"type": "custom"}`:            http.StatusNoContent,
+	} {
+		res := post(t, receiver, body, nil)
+		if res.StatusCode != status {
+			t.Errorf("%s: expected %d, got %d", body, status, res.StatusCode)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	receiver.ServeHTTP(rec, httptest.NewRequest("POST", "/", strings.NewReader(`{"type": "p:afterDeleteNode"}`)))
+	if strings.Contains(rec.Body.String(), "index unavailable") {
+		t.Errorf("the handler error should not be sent to the caller, got %q", rec.Body.String())
+	}
+	if reported == nil || !strings.Contains(reported.Error(), "index unavailable") {
+		t.Errorf("the handler error should be passed to OnError, got %v", reported)
+	}
+}
+
+func TestParse(t *testing.T) {
+	for body, expected := range map[string]EventType{
+		`{"event": "p:afterCreateNode", "node": {"_doc": "n1"}}`:                        NodeCreated,
+		`{"eventType": "node.deleted", "nodeId": "n1"}`:                                 NodeDeleted,
+		`{"policy": "p:beforeDeleteNode", "object": {"_doc": "n1"}}`:                    NodeDeleted,
+		`{"workflow": {"_doc": "w1", "transition": "approve"}, "node": {"_doc": "n1"}}`: WorkflowTransition,
+		`{"event": {"name": "custom"}, "node": {"_doc": "n1"}}`:                         Unknown,
+		`{"policy": "p:afterCreateAssociation", "node": {"_doc": "n1"}}`:                Unknown,
+		`{"event": "p:afterUpdateNodeTemplate", "node": {"_doc": "n1"}}`:                Unknown,
+	} {
+		event, err := Parse([]byte(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if event.Type != expected {
+			t.Errorf("%s: expected %s, got %s", body, expected, event.Type)
+		}
+		if expected != Unknown && event.NodeId != "n1" {
+			t.Errorf("%s: expected node n1, got %q", body, event.NodeId)
+		}
+	}
+
+	event, _ := Parse([]byte(`{"workflow": {"_doc": "w1", "transition": "approve"}}`))
+	if event.Transition != "approve" || event.Workflow.GetString("_doc") != "w1" {
+		t.Fatalf("unexpected workflow event %+v", event)
+	}
+}